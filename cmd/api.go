@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,6 +10,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/psds-microservice/search-service/internal/application"
 	"github.com/psds-microservice/search-service/internal/config"
+	"github.com/psds-microservice/search-service/internal/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +20,15 @@ var apiCmd = &cobra.Command{
 	RunE:  runAPI,
 }
 
+// apiStorage — значение флага --storage (пусто — из конфига STORAGE).
+var apiStorage string
+
+func init() {
+	for _, c := range []*cobra.Command{rootCmd, apiCmd} {
+		c.Flags().StringVar(&apiStorage, "storage", "", "index storage: elasticsearch | memory | bleve (overrides STORAGE)")
+	}
+}
+
 func runAPI(cmd *cobra.Command, args []string) error {
 	_ = godotenv.Load(".env")
 	_ = godotenv.Load("../.env")
@@ -27,9 +36,25 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("config: %w", err)
 	}
+	if apiStorage != "" {
+		cfg.Storage = apiStorage
+	}
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("config: %w", err)
 	}
+	if _, err := logger.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return fmt.Errorf("logger: %w", err)
+	}
+	log := logger.Component("api")
+	shutdownTracing, err := setupTracing(context.Background(), cfg, "search-service")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("tracing shutdown", logger.Err(err))
+		}
+	}()
 	app, err := application.NewAPI(cfg)
 	if err != nil {
 		return err
@@ -39,6 +64,6 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	if err := app.Run(ctx); err != nil {
 		return err
 	}
-	log.Println("bye")
+	log.Info("bye")
 	return nil
 }
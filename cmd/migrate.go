@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joho/godotenv"
+	"github.com/psds-microservice/search-service/internal/config"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate Elasticsearch index mappings",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Update index mappings, reindexing indices whose field types changed. Stop api and worker first.",
+	Args:  cobra.NoArgs,
+	RunE:  runMigrateUp,
+}
+
+// migrateTenants — значение флага --tenant: арендаторы, чьи индексы переносятся в TENANCY_MODE=index.
+var migrateTenants []string
+
+func init() {
+	migrateUpCmd.Flags().StringSliceVar(&migrateTenants, "tenant", nil, "tenants to migrate (TENANCY_MODE=index)")
+	migrateCmd.AddCommand(migrateUpCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrateUp(cmd *cobra.Command, args []string) error {
+	_ = godotenv.Load(".env")
+	_ = godotenv.Load("../.env")
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Storage != config.StorageElasticsearch {
+		return fmt.Errorf("migrate requires STORAGE=elasticsearch, got %s", cfg.Storage)
+	}
+	if _, err := logger.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return fmt.Errorf("logger: %w", err)
+	}
+	log := logger.Component("migrate")
+
+	flavor, err := elasticsearch.ParseFlavor(cfg.Elasticsearch.Flavor)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	es := elasticsearch.NewClient(cfg.Elasticsearch.URL, cfg.Elasticsearch.InsecureSkipVerify,
+		cfg.Elasticsearch.Username, cfg.Elasticsearch.Password, elasticsearch.WithFlavor(flavor))
+	if err := service.MigrateIndices(context.Background(), es, cfg.Tenancy.Mode, migrateTenants); err != nil {
+		return err
+	}
+	log.Info("index mappings are up to date")
+	return nil
+}
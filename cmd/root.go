@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/psds-microservice/search-service/internal/config"
+	"github.com/psds-microservice/search-service/internal/tracing"
 	"github.com/spf13/cobra"
 )
 
@@ -18,3 +23,17 @@ func init() {
 	rootCmd.AddCommand(apiCmd)
 	rootCmd.AddCommand(workerCmd)
 }
+
+// setupTracing настраивает OpenTelemetry по конфигу; возвращённую функцию вызывать при остановке.
+func setupTracing(ctx context.Context, cfg *config.Config, serviceName string) (func(context.Context) error, error) {
+	shutdown, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	}, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	return shutdown, nil
+}
@@ -3,15 +3,23 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/psds-microservice/helpy/paths"
+	"github.com/psds-microservice/search-service/internal/application"
 	"github.com/psds-microservice/search-service/internal/config"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/handler"
 	"github.com/psds-microservice/search-service/internal/kafka"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/metrics"
 	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/psds-microservice/search-service/internal/sessionstate"
 	"github.com/spf13/cobra"
 )
 
@@ -38,8 +46,43 @@ func runWorker(cmd *cobra.Command, args []string) error {
 	if len(cfg.KafkaBrokers) == 0 || len(cfg.KafkaTopics) == 0 {
 		return fmt.Errorf("worker requires KAFKA_BROKERS and KAFKA_TOPICS")
 	}
+	if cfg.Storage != config.StorageElasticsearch {
+		return fmt.Errorf("worker requires STORAGE=elasticsearch: %s storage is local to one process (with bleve the api consumes Kafka itself)", cfg.Storage)
+	}
+	router, err := application.NewRouter(cfg)
+	if err != nil {
+		return err
+	}
 
-	searchSvc, err := service.NewSearchService(cfg.Elasticsearch.URL, cfg.Elasticsearch.InsecureSkipVerify, cfg.Elasticsearch.Username, cfg.Elasticsearch.Password)
+	if _, err := logger.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return fmt.Errorf("logger: %w", err)
+	}
+	log := logger.Component("worker")
+	shutdownTracing, err := setupTracing(context.Background(), cfg, "search-service-worker")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("tracing shutdown", logger.Err(err))
+		}
+	}()
+
+	flavor, err := elasticsearch.ParseFlavor(cfg.Elasticsearch.Flavor)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	es := elasticsearch.NewClient(cfg.Elasticsearch.URL, cfg.Elasticsearch.InsecureSkipVerify,
+		cfg.Elasticsearch.Username, cfg.Elasticsearch.Password, elasticsearch.WithFlavor(flavor))
+	opts := []service.Option{service.WithTenancy(cfg.Tenancy.Mode)}
+	if cfg.SessionStatesFile != "" {
+		m, err := sessionstate.Load(cfg.SessionStatesFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, service.WithSessionStates(m))
+	}
+	searchSvc, err := service.NewSearchService(es, opts...)
 	if err != nil {
 		return fmt.Errorf("search service: %w", err)
 	}
@@ -47,8 +90,34 @@ func runWorker(cmd *cobra.Command, args []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	log.Printf("worker: starting Kafka consumer (group=%s, topics=%v)", cfg.KafkaGroupID, cfg.KafkaTopics)
-	kafka.RunConsumer(ctx, cfg.KafkaBrokers, cfg.KafkaGroupID, cfg.KafkaTopics, searchSvc)
-	log.Println("worker: bye")
+	httpSrv := newWorkerHTTPServer(cfg)
+	go func() {
+		log.Info("HTTP server listening (/metrics, /health)", "addr", httpSrv.Addr)
+		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("http server failed", logger.Err(err))
+		}
+	}()
+
+	log.Info("starting Kafka consumer", "group", cfg.KafkaGroupID, "topics", cfg.KafkaTopics)
+	kafka.RunConsumer(ctx, cfg.KafkaBrokers, cfg.KafkaGroupID, cfg.KafkaTopics, cfg.KafkaDLQTopic, application.NewDecoder(cfg), router, searchSvc)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		log.Error("http shutdown", logger.Err(err))
+	}
+	log.Info("bye")
 	return nil
 }
+
+// newWorkerHTTPServer — служебный HTTP-сервер worker'а: метрики Prometheus и health.
+func newWorkerHTTPServer(cfg *config.Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(paths.PathHealth, handler.Health)
+	mux.Handle("/metrics", metrics.Handler())
+	return &http.Server{
+		Addr:              cfg.AppHost + ":" + cfg.WorkerHTTPPort,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
@@ -3,23 +3,41 @@ package application
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/psds-microservice/helpy/paths"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/blevestore"
+	"github.com/psds-microservice/search-service/internal/cache"
 	"github.com/psds-microservice/search-service/internal/config"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
 	grpcserver "github.com/psds-microservice/search-service/internal/grpc"
 	"github.com/psds-microservice/search-service/internal/handler"
+	"github.com/psds-microservice/search-service/internal/kafka"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/metrics"
+	"github.com/psds-microservice/search-service/internal/policy"
+	"github.com/psds-microservice/search-service/internal/ratelimit"
+	"github.com/psds-microservice/search-service/internal/schemaregistry"
 	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/psds-microservice/search-service/internal/sessionstate"
+	"github.com/psds-microservice/search-service/internal/tenant"
 	"github.com/psds-microservice/search-service/internal/validator"
 	"github.com/psds-microservice/search-service/pkg/gen/search_service"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -53,11 +71,16 @@ func serveOpenAPISpec() http.HandlerFunc {
 
 // API приложение: HTTP + gRPC серверы (режим api).
 type API struct {
-	cfg       *config.Config
-	httpSrv   *http.Server
-	grpcSrv   *grpc.Server
-	lis       net.Listener
-	searchSvc service.SearchServicer
+	cfg         *config.Config
+	httpSrv     *http.Server
+	grpcSrv     *grpc.Server
+	lis         net.Listener
+	gatewayConn *grpc.ClientConn
+	searchSvc   service.SearchServicer
+	storage     io.Closer     // встроенное хранилище (bleve); nil для внешнего Elasticsearch
+	router      *kafka.Router // маршруты consumer'а; nil — события Kafka api не читает
+	decoder     *kafka.Decoder
+	log         *slog.Logger
 }
 
 // NewAPI создаёт приложение для режима api.
@@ -66,7 +89,39 @@ func NewAPI(cfg *config.Config) (*API, error) {
 		return nil, fmt.Errorf("config: %w", err)
 	}
 
-	searchSvc, err := service.NewSearchService(cfg.Elasticsearch.URL, cfg.Elasticsearch.InsecureSkipVerify, cfg.Elasticsearch.Username, cfg.Elasticsearch.Password)
+	opts := []service.Option{
+		service.WithTenancy(cfg.Tenancy.Mode),
+		service.WithSlowQueryLog(cfg.Elasticsearch.SlowQuery),
+	}
+	if cfg.Cache.Enabled {
+		opts = append(opts, service.WithCache(cache.NewLRU(cfg.Cache.Size), cfg.Cache.TTL))
+	}
+	if cfg.Auth.PolicyFile != "" {
+		p, err := policy.Load(cfg.Auth.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("policy: %w", err)
+		}
+		opts = append(opts, service.WithPolicy(p))
+	}
+	if cfg.SessionStatesFile != "" {
+		m, err := sessionstate.Load(cfg.SessionStatesFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, service.WithSessionStates(m))
+	}
+
+	// со встроенным хранилищем события Kafka читает сам api (см. Run)
+	var router *kafka.Router
+	if cfg.Storage == config.StorageBleve && len(cfg.KafkaBrokers) > 0 {
+		r, err := NewRouter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		router = r
+	}
+
+	searchSvc, storage, err := newSearchService(cfg, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("search service: %w", err)
 	}
@@ -76,10 +131,41 @@ func NewAPI(cfg *config.Config) (*API, error) {
 	if err != nil {
 		return nil, fmt.Errorf("grpc listen %s: %w (порт занят — остановите другой процесс или задайте GRPC_PORT в .env)", grpcAddr, err)
 	}
-	grpcSrv := grpc.NewServer()
+	grpcLog := logger.Component("grpc")
+	interceptors := []grpc.UnaryServerInterceptor{
+		grpcserver.LoggingUnaryInterceptor(grpcLog),
+		grpcserver.MetricsUnaryInterceptor(),
+	}
+	if cfg.Auth.Enabled {
+		authInterceptor, err := newAuthInterceptor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		interceptors = append(interceptors, authInterceptor)
+	}
+	if cfg.RateLimit.Enabled {
+		limiter, err := newRateLimiter(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit: %w", err)
+		}
+		interceptors = append(interceptors, grpcserver.RateLimitUnaryInterceptor(limiter))
+	}
+	if cfg.Tenancy.Mode != tenant.ModeNone {
+		tenantClaim := "" // без аутентификации арендатор — из заголовка x-tenant-id
+		if cfg.Auth.Enabled {
+			tenantClaim = cfg.Tenancy.Claim
+		}
+		interceptors = append(interceptors, grpcserver.TenantUnaryInterceptor(tenantClaim))
+	}
+	grpcSrv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors...),
+	)
 	grpcImpl := grpcserver.NewServer(grpcserver.Deps{
-		SearchSvc: searchSvc,
-		Validator: validator.New(),
+		SearchSvc:      searchSvc,
+		Validator:      validator.New(),
+		Logger:         grpcLog,
+		ExplainEnabled: cfg.ExplainEnabled,
 	})
 	search_service.RegisterSearchServiceServer(grpcSrv, grpcImpl)
 	reflection.Register(grpcSrv)
@@ -91,14 +177,26 @@ func NewAPI(cfg *config.Config) (*API, error) {
 				EmitUnpopulated: true,
 			},
 		}),
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
 	)
-	if err := search_service.RegisterSearchServiceHandlerServer(context.Background(), gatewayMux, grpcImpl); err != nil {
+	// Gateway ходит в собственный gRPC-сервер через loopback, чтобы HTTP-запросы проходили те же interceptors (метрики и т.д.).
+	// Trace context из входящего HTTP-запроса (otelhttp) передаётся дальше в gRPC через client stats handler.
+	gatewayConn, err := grpc.NewClient(loopbackAddr(lis.Addr()),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("grpc-gateway dial: %w", err)
+	}
+	if err := search_service.RegisterSearchServiceHandler(context.Background(), gatewayMux, gatewayConn); err != nil {
 		return nil, fmt.Errorf("register grpc-gateway: %w", err)
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc(paths.PathHealth, handler.Health)
 	mux.HandleFunc(paths.PathReady, handler.Ready)
+	mux.Handle("/metrics", metrics.Handler())
 	mux.HandleFunc(paths.PathSwagger+"/openapi.json", serveOpenAPISpec())
 	mux.Handle(paths.PathSwagger+"/", httpSwagger.Handler(
 		httpSwagger.URL("openapi.json"),
@@ -110,7 +208,7 @@ func NewAPI(cfg *config.Config) (*API, error) {
 	httpAddr := cfg.AppHost + ":" + cfg.HTTPPort
 	httpSrv := &http.Server{
 		Addr:              httpAddr,
-		Handler:           mux,
+		Handler:           otelhttp.NewHandler(mux, "search-service.http"),
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       15 * time.Second,
 		WriteTimeout:      30 * time.Second,
@@ -118,14 +216,152 @@ func NewAPI(cfg *config.Config) (*API, error) {
 	}
 
 	return &API{
-		cfg:       cfg,
-		httpSrv:   httpSrv,
-		grpcSrv:   grpcSrv,
-		lis:       lis,
-		searchSvc: searchSvc,
+		cfg:         cfg,
+		httpSrv:     httpSrv,
+		grpcSrv:     grpcSrv,
+		lis:         lis,
+		gatewayConn: gatewayConn,
+		searchSvc:   searchSvc,
+		storage:     storage,
+		router:      router,
+		decoder:     NewDecoder(cfg),
+		log:         logger.Component("api"),
 	}, nil
 }
 
+// newSearchService создаёт сервис поиска поверх хранилища из конфига. Для встроенного хранилища
+// возвращает его Closer (закрыть при остановке).
+func newSearchService(cfg *config.Config, opts ...service.Option) (*service.SearchService, io.Closer, error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		logger.Component("api").Warn("using in-memory storage: indexed data is lost on restart")
+		svc, err := service.NewSearchServiceWithIndexer(elasticsearch.NewInstrumented(elasticsearch.NewMemory()), opts...)
+		return svc, nil, err
+	case config.StorageBleve:
+		store, err := blevestore.New(cfg.BlevePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		svc, err := service.NewSearchServiceWithIndexer(elasticsearch.NewInstrumented(store), opts...)
+		if err != nil {
+			store.Close()
+			return nil, nil, err
+		}
+		return svc, store, nil
+	}
+	es, err := newElasticsearchClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	svc, err := service.NewSearchService(es, opts...)
+	return svc, nil, err
+}
+
+// newElasticsearchClient создаёт клиент Elasticsearch/OpenSearch; вид бэкенда (ELASTICSEARCH_FLAVOR=auto)
+// определяется при первом обращении, здесь — только для лога.
+func newElasticsearchClient(cfg *config.Config) (*elasticsearch.Client, error) {
+	flavor, err := elasticsearch.ParseFlavor(cfg.Elasticsearch.Flavor)
+	if err != nil {
+		return nil, err
+	}
+	es := elasticsearch.NewClient(cfg.Elasticsearch.URL, cfg.Elasticsearch.InsecureSkipVerify,
+		cfg.Elasticsearch.Username, cfg.Elasticsearch.Password, elasticsearch.WithFlavor(flavor))
+	log := logger.Component("elasticsearch")
+	if detected, err := es.Flavor(context.Background()); err != nil {
+		log.Warn("backend flavor not detected yet", logger.Err(err))
+	} else {
+		log.Info("search backend", "flavor", detected)
+	}
+	return es, nil
+}
+
+// newAuthInterceptor проверяет JWT по JWKS; Index* RPC доступны только токенам с сервисной ролью,
+// Explain (если включён EXPLAIN_ENABLED) — только администраторам.
+func newAuthInterceptor(cfg *config.Config) (grpc.UnaryServerInterceptor, error) {
+	keys, err := auth.NewKeySet(context.Background(), cfg.Auth.JWKSFile, cfg.Auth.JWKSURL, cfg.Auth.JWKSRefresh)
+	if err != nil {
+		return nil, err
+	}
+	verifier := auth.NewVerifier(keys, auth.VerifierConfig{
+		Issuer:    cfg.Auth.Issuer,
+		Audience:  cfg.Auth.Audience,
+		RoleClaim: cfg.Auth.RoleClaim,
+	})
+	serviceOnly := []string{cfg.Auth.ServiceRole}
+	return auth.UnaryServerInterceptor(verifier, map[string][]string{
+		search_service.SearchService_IndexTicket_FullMethodName:   serviceOnly,
+		search_service.SearchService_IndexSession_FullMethodName:  serviceOnly,
+		search_service.SearchService_IndexOperator_FullMethodName: serviceOnly,
+		search_service.SearchService_Explain_FullMethodName:       {cfg.Auth.AdminRole},
+	}), nil
+}
+
+func newRateLimiter(cfg *config.Config) (*ratelimit.Limiter, error) {
+	search, err := ratelimit.ParseBudget(cfg.RateLimit.Search)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_SEARCH: %w", err)
+	}
+	index, err := ratelimit.ParseBudget(cfg.RateLimit.Index)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_INDEX: %w", err)
+	}
+	routes, err := ratelimit.ParseRoutes(cfg.RateLimit.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err)
+	}
+	return ratelimit.New(search, index, routes), nil
+}
+
+// NewRouter разбирает маршруты consumer'а и проверяет, что они покрывают все топики подписки.
+func NewRouter(cfg *config.Config) (*kafka.Router, error) {
+	router, err := kafka.ParseRoutes(cfg.KafkaRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("KAFKA_ROUTES: %w", err)
+	}
+	if err := router.Validate(cfg.KafkaTopics); err != nil {
+		return nil, fmt.Errorf("KAFKA_ROUTES: %w", err)
+	}
+	return router, nil
+}
+
+// NewDecoder создаёт декодер protobuf/Avro-сообщений по реестру или каталогу схем (nil — только JSON).
+func NewDecoder(cfg *config.Config) *kafka.Decoder {
+	switch {
+	case cfg.SchemaRegistry.URL != "":
+		return kafka.NewDecoder(schemaregistry.NewClient(cfg.SchemaRegistry.URL, cfg.SchemaRegistry.Username, cfg.SchemaRegistry.Password))
+	case cfg.SchemaRegistry.Dir != "":
+		return kafka.NewDecoder(schemaregistry.NewDir(cfg.SchemaRegistry.Dir))
+	}
+	return nil
+}
+
+// incomingHeaderMatcher пробрасывает в gRPC metadata, помимо стандартных, заголовки сервиса (X-Request-Id, X-Tenant-Id).
+func incomingHeaderMatcher(key string) (string, bool) {
+	for _, h := range []string{grpcserver.RequestIDHeader, tenant.Header} {
+		if strings.EqualFold(key, h) {
+			return h, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher отдаёт заголовки сервиса из gRPC metadata как есть (без префикса Grpc-Metadata-).
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == grpcserver.RequestIDHeader || key == grpcserver.RetryAfterHeader {
+		return http.CanonicalHeaderKey(key), true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
+
+// loopbackAddr возвращает адрес для подключения к локальному listener'у (0.0.0.0/:: → localhost).
+func loopbackAddr(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return net.JoinHostPort("localhost", strconv.Itoa(tcp.Port))
+}
+
 // Run запускает HTTP и gRPC серверы, блокируется до отмены ctx.
 func (a *API) Run(ctx context.Context) error {
 	httpAddr := a.httpSrv.Addr
@@ -135,27 +371,40 @@ func (a *API) Run(ctx context.Context) error {
 		host = "localhost"
 	}
 	base := "http://" + host + ":" + a.cfg.HTTPPort
-	log.Printf("HTTP server listening on %s", httpAddr)
-	log.Printf("  Swagger UI:    %s/swagger", base)
-	log.Printf("  Swagger spec:  %s/swagger/openapi.json", base)
-	log.Printf("  Health:        %s/health", base)
-	log.Printf("  Ready:         %s/ready", base)
-	log.Printf("  Search:        %s/search", base)
-	log.Printf("gRPC server listening on %s", grpcAddr)
-	log.Printf("  gRPC endpoint: %s (reflection enabled)", grpcAddr)
+	a.log.Info("HTTP server listening",
+		"addr", httpAddr,
+		"swagger_ui", base+"/swagger",
+		"swagger_spec", base+"/swagger/openapi.json",
+		"health", base+"/health",
+		"ready", base+"/ready",
+		"metrics", base+"/metrics",
+		"search", base+"/search",
+	)
+	a.log.Info("gRPC server listening (reflection enabled)", "addr", grpcAddr)
 
 	go func() {
 		if err := a.httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("http: %v", err)
+			a.log.Error("http server failed", logger.Err(err))
 		}
 	}()
 
 	go func() {
 		if err := a.grpcSrv.Serve(a.lis); err != nil {
-			log.Printf("grpc: %v", err)
+			a.log.Error("grpc server failed", logger.Err(err))
 		}
 	}()
 
+	// Встроенное хранилище открыто только этим процессом, поэтому события Kafka индексирует он сам.
+	consumerDone := make(chan struct{})
+	if a.router != nil {
+		go func() {
+			defer close(consumerDone)
+			kafka.RunConsumer(ctx, a.cfg.KafkaBrokers, a.cfg.KafkaGroupID, a.cfg.KafkaTopics, a.cfg.KafkaDLQTopic, a.decoder, a.router, a.searchSvc)
+		}()
+	} else {
+		close(consumerDone)
+	}
+
 	<-ctx.Done()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -163,5 +412,14 @@ func (a *API) Run(ctx context.Context) error {
 		return fmt.Errorf("http shutdown: %w", err)
 	}
 	a.grpcSrv.GracefulStop()
+	if err := a.gatewayConn.Close(); err != nil {
+		return fmt.Errorf("grpc-gateway close: %w", err)
+	}
+	<-consumerDone
+	if a.storage != nil {
+		if err := a.storage.Close(); err != nil {
+			return fmt.Errorf("storage close: %w", err)
+		}
+	}
 	return nil
 }
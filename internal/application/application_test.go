@@ -0,0 +1,100 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans подменяет глобальные TracerProvider и propagator на время теста и возвращает записанные спаны.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+		tp.Shutdown(context.Background())
+	})
+	return rec
+}
+
+// newTestAPI создаёт api на хранилище в памяти и запускает его gRPC-сервер на свободном порту.
+func newTestAPI(t *testing.T) *API {
+	t.Helper()
+	t.Setenv("STORAGE", config.StorageMemory)
+	t.Setenv("APP_HOST", "127.0.0.1")
+	t.Setenv("GRPC_PORT", "0")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, err := NewAPI(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go api.grpcSrv.Serve(api.lis)
+	t.Cleanup(func() {
+		api.gatewayConn.Close()
+		api.grpcSrv.Stop()
+	})
+	return api
+}
+
+func TestGatewayContinuesHTTPTrace(t *testing.T) {
+	rec := recordSpans(t)
+	api := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/search/tickets?status=open", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	api.httpSrv.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /search/tickets = %d %s", w.Code, w.Body)
+	}
+
+	// серверный gRPC-спан завершается после ответа клиенту
+	const rpc = "search_service.SearchService/SearchTickets"
+	var httpSpan, clientSpan, serverSpan sdktrace.ReadOnlySpan
+	for deadline := time.Now().Add(5 * time.Second); serverSpan == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, s := range rec.Ended() {
+			switch {
+			case s.Name() == rpc && s.SpanKind() == trace.SpanKindClient:
+				clientSpan = s
+			case s.Name() == rpc && s.SpanKind() == trace.SpanKindServer:
+				serverSpan = s
+			case s.SpanKind() == trace.SpanKindServer:
+				httpSpan = s
+			}
+		}
+	}
+	if httpSpan == nil || clientSpan == nil || serverSpan == nil {
+		t.Fatalf("spans: http=%v grpc client=%v grpc server=%v, want all three", httpSpan != nil, clientSpan != nil, serverSpan != nil)
+	}
+	for _, s := range []sdktrace.ReadOnlySpan{httpSpan, clientSpan, serverSpan} {
+		if got := s.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("span %q trace id = %s, want the caller's", s.Name(), got)
+		}
+	}
+	if httpSpan.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("http span parent = %s, want the caller's span 00f067aa0ba902b7", httpSpan.Parent().SpanID())
+	}
+	if clientSpan.Parent().SpanID() != httpSpan.SpanContext().SpanID() {
+		t.Errorf("grpc client span parent = %s, want the http span %s", clientSpan.Parent().SpanID(), httpSpan.SpanContext().SpanID())
+	}
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() || !serverSpan.Parent().IsRemote() {
+		t.Errorf("grpc server span parent = %s, want the grpc client span %s across the loopback", serverSpan.Parent().SpanID(), clientSpan.SpanContext().SpanID())
+	}
+}
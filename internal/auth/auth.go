@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Claims — вызывающая сторона, извлечённая из проверенного JWT.
+type Claims struct {
+	Subject string
+	Roles   []string
+	Raw     jwt.MapClaims // все claims токена (для политик, которым нужны дополнительные поля)
+}
+
+// HasRole сообщает, есть ли у вызывающего хотя бы одна из ролей.
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String возвращает строковое значение произвольного claim (пусто, если его нет или это не строка).
+func (c *Claims) String(name string) string {
+	v, _ := c.Raw[name].(string)
+	return v
+}
+
+type claimsKey struct{}
+
+// WithClaims кладёт claims в контекст.
+func WithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// ClaimsFromContext возвращает claims вызывающего; nil — вызов без аутентификации (auth выключен).
+func ClaimsFromContext(ctx context.Context) *Claims {
+	c, _ := ctx.Value(claimsKey{}).(*Claims)
+	return c
+}
+
+// VerifierConfig — параметры проверки токенов.
+type VerifierConfig struct {
+	Issuer    string // ожидаемый iss (пусто — не проверяется)
+	Audience  string // ожидаемый aud (пусто — не проверяется)
+	RoleClaim string // claim с ролью: строка или массив строк
+}
+
+// Verifier проверяет подпись и стандартные claims JWT по ключам из JWKS.
+type Verifier struct {
+	keys   *KeySet
+	cfg    VerifierConfig
+	parser *jwt.Parser
+}
+
+// NewVerifier создаёт Verifier.
+func NewVerifier(keys *KeySet, cfg VerifierConfig) *Verifier {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "role"
+	}
+	return &Verifier{keys: keys, cfg: cfg, parser: jwt.NewParser(opts...)}
+}
+
+// Verify проверяет токен и возвращает claims.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	mc := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(raw, mc, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sub, err := mc.GetSubject()
+	if err != nil || sub == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &Claims{Subject: sub, Roles: stringList(mc[v.cfg.RoleClaim]), Raw: mc}, nil
+}
+
+func stringList(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return strings.Fields(t)
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// UnaryServerInterceptor требует валидный Bearer-токен на каждом RPC. Для методов из restricted
+// дополнительно нужна одна из перечисленных ролей, иначе PermissionDenied.
+func UnaryServerInterceptor(v *Verifier, restricted map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		raw, err := bearerToken(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		claims, err := v.Verify(ctx, raw)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, fmt.Sprintf("invalid token: %v", err))
+		}
+		if roles, ok := restricted[info.FullMethod]; ok && !claims.HasRole(roles...) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires role %s", info.FullMethod, strings.Join(roles, " or "))
+		}
+		return handler(WithClaims(ctx, claims), req)
+	}
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", errors.New("missing authorization header")
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+		return "", errors.New("authorization header must be a Bearer token")
+	}
+	return strings.TrimSpace(token), nil
+}
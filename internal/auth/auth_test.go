@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testKey — RSA-ключ подписи с kid.
+type testKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, key: key}
+}
+
+// jwks сериализует публичные ключи в JWKS.
+func jwks(keys ...testKey) []byte {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kid: k.kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+		})
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+// sign подписывает claims ключом k (RS256).
+func (k testKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = k.kid
+	raw, err := tok.SignedString(k.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// fileKeySet — KeySet из JWKS-файла с ключами keys.
+func fileKeySet(t *testing.T, keys ...testKey) *KeySet {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(keys...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(context.Background(), path, "", 0)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return ks
+}
+
+func TestVerify(t *testing.T) {
+	k1 := newTestKey(t, "k1")
+	v := NewVerifier(fileKeySet(t, k1), VerifierConfig{Issuer: "https://idp", Audience: "search", RoleClaim: "roles"})
+	now := time.Now()
+	valid := func(override jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "op-1", "iss": "https://idp", "aud": "search", "exp": now.Add(time.Hour).Unix(), "roles": []string{"operator", "admin"}}
+		for name, value := range override {
+			if value == nil {
+				delete(c, name)
+			} else {
+				c[name] = value
+			}
+		}
+		return c
+	}
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid(nil)).SignedString([]byte("secret"))
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	other := newTestKey(t, "k1") // тот же kid, другой ключ
+
+	cases := []struct {
+		name  string
+		token string
+		err   string // пусто — токен принимается
+	}{
+		{"valid", k1.sign(t, valid(nil)), ""},
+		{"HS256 rejected", hs256, "signing method HS256 is invalid"},
+		{"none rejected", none, "signing method none is invalid"},
+		{"wrong signature", other.sign(t, valid(nil)), "signature is invalid"},
+		{"missing exp", k1.sign(t, valid(jwt.MapClaims{"exp": nil})), "exp claim is required"},
+		{"expired within leeway", k1.sign(t, valid(jwt.MapClaims{"exp": now.Add(-25 * time.Second).Unix()})), ""},
+		{"expired beyond leeway", k1.sign(t, valid(jwt.MapClaims{"exp": now.Add(-35 * time.Second).Unix()})), "token is expired"},
+		{"not yet valid beyond leeway", k1.sign(t, valid(jwt.MapClaims{"nbf": now.Add(35 * time.Second).Unix()})), "token is not valid yet"},
+		{"wrong issuer", k1.sign(t, valid(jwt.MapClaims{"iss": "https://other"})), "invalid issuer"},
+		{"wrong audience", k1.sign(t, valid(jwt.MapClaims{"aud": "billing"})), "invalid audience"},
+		{"missing subject", k1.sign(t, valid(jwt.MapClaims{"sub": nil})), "token has no subject"},
+		{"malformed", "not.a.jwt", "token is malformed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tc.token)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if claims.Subject != "op-1" || !claims.HasRole("admin") || claims.HasRole("auditor") {
+					t.Errorf("claims = %+v, want op-1 with roles operator, admin", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Verify = %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	k1 := newTestKey(t, "k1")
+	v := NewVerifier(fileKeySet(t, k1), VerifierConfig{})
+	exp := time.Now().Add(time.Hour).Unix()
+	operator := k1.sign(t, jwt.MapClaims{"sub": "op-1", "exp": exp, "role": "operator"})
+	admin := k1.sign(t, jwt.MapClaims{"sub": "adm-1", "exp": exp, "role": "admin"})
+	interceptor := UnaryServerInterceptor(v, map[string][]string{"/search.SearchService/Explain": {"admin"}})
+
+	cases := []struct {
+		name          string
+		authorization []string
+		method        string
+		code          codes.Code
+		subject       string
+	}{
+		{"missing header", nil, "/search.SearchService/SearchTickets", codes.Unauthenticated, ""},
+		{"basic scheme", []string{"Basic b3A6c2VjcmV0"}, "/search.SearchService/SearchTickets", codes.Unauthenticated, ""},
+		{"empty bearer", []string{"Bearer   "}, "/search.SearchService/SearchTickets", codes.Unauthenticated, ""},
+		{"bearer without token", []string{"Bearer"}, "/search.SearchService/SearchTickets", codes.Unauthenticated, ""},
+		{"malformed token", []string{"Bearer abc"}, "/search.SearchService/SearchTickets", codes.Unauthenticated, ""},
+		{"valid", []string{"Bearer " + operator}, "/search.SearchService/SearchTickets", codes.OK, "op-1"},
+		{"scheme is case-insensitive", []string{"bearer " + operator}, "/search.SearchService/SearchTickets", codes.OK, "op-1"},
+		{"role required", []string{"Bearer " + operator}, "/search.SearchService/Explain", codes.PermissionDenied, ""},
+		{"role present", []string{"Bearer " + admin}, "/search.SearchService/Explain", codes.OK, "adm-1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{"authorization": tc.authorization})
+			}
+			var subject string
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				subject = ClaimsFromContext(ctx).Subject
+				return nil, nil
+			})
+			if got := status.Code(err); got != tc.code {
+				t.Fatalf("code = %s (%v), want %s", got, err, tc.code)
+			}
+			if subject != tc.subject {
+				t.Errorf("handler saw subject %q, want %q", subject, tc.subject)
+			}
+		})
+	}
+}
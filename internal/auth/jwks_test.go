@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer отдаёт текущий JWKS и считает запросы.
+type jwksServer struct {
+	mu    sync.Mutex
+	body  []byte
+	calls int
+}
+
+func (s *jwksServer) set(body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = body
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	w.Write(s.body)
+}
+
+// age сдвигает время последней загрузки ключей в прошлое.
+func (ks *KeySet) age(d time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.fetchedAt = ks.fetchedAt.Add(-d)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	k1, k2 := newTestKey(t, "k1"), newTestKey(t, "k2")
+	jwksSrv := &jwksServer{body: jwks(k1)}
+	srv := httptest.NewServer(jwksSrv)
+	defer srv.Close()
+
+	ctx := context.Background()
+	ks, err := NewKeySet(ctx, "", srv.URL, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	v := NewVerifier(ks, VerifierConfig{})
+	exp := time.Now().Add(time.Hour).Unix()
+	if _, err := v.Verify(ctx, k1.sign(t, jwt.MapClaims{"sub": "op-1", "exp": exp})); err != nil {
+		t.Fatalf("Verify(k1): %v", err)
+	}
+
+	// issuer добавил k2: неизвестный kid перечитывает JWKS, но не чаще minRefreshInterval
+	jwksSrv.set(jwks(k1, k2))
+	tokenK2 := k2.sign(t, jwt.MapClaims{"sub": "op-2", "exp": exp})
+	if _, err := v.Verify(ctx, tokenK2); err == nil {
+		t.Fatal("Verify(k2) right after the initial load succeeded, want unknown key id until minRefreshInterval passes")
+	}
+	if got := jwksSrv.count(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1 (refetch is rate-limited)", got)
+	}
+	ks.age(minRefreshInterval + time.Second)
+	claims, err := v.Verify(ctx, tokenK2)
+	if err != nil {
+		t.Fatalf("Verify(k2) after rotation: %v", err)
+	}
+	if claims.Subject != "op-2" || jwksSrv.count() != 2 {
+		t.Errorf("claims = %+v after %d fetches, want op-2 after one refetch", claims, jwksSrv.count())
+	}
+
+	// k1 отозван: после планового перечитывания (refresh) его токены не принимаются
+	jwksSrv.set(jwks(k2))
+	ks.age(time.Hour + time.Second)
+	if _, err := v.Verify(ctx, k1.sign(t, jwt.MapClaims{"sub": "op-1", "exp": exp})); err == nil {
+		t.Error("Verify(k1) after the key was removed succeeded, want unknown key id")
+	}
+
+	if _, err := ks.Key(ctx, "k9"); err == nil {
+		t.Error("Key(k9) succeeded, want unknown key id")
+	}
+}
+
+func TestKeySetEmptyKid(t *testing.T) {
+	k1, k2 := newTestKey(t, "k1"), newTestKey(t, "k2")
+	if _, err := fileKeySet(t, k1).Key(context.Background(), ""); err != nil {
+		t.Errorf("Key(\"\") with a single key: %v", err)
+	}
+	if _, err := fileKeySet(t, k1, k2).Key(context.Background(), ""); err == nil {
+		t.Error("Key(\"\") with two keys succeeded, want ambiguous kid rejected")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		keys int
+		ok   bool
+	}{
+		{"encryption keys skipped", `{"keys":[{"kid":"e","kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"},{"kid":"s","kty":"RSA","n":"AQAB","e":"AQAB"}]}`, 1, true},
+		{"unsupported type skipped", `{"keys":[{"kid":"o","kty":"oct"},{"kid":"s","kty":"RSA","n":"AQAB","e":"AQAB"}]}`, 1, true},
+		{"unsupported curve", `{"keys":[{"kid":"c","kty":"EC","crv":"P-192","x":"AQ","y":"AQ"}]}`, 0, false},
+		{"bad base64", `{"keys":[{"kid":"s","kty":"RSA","n":"!!","e":"AQAB"}]}`, 0, false},
+		{"no signing keys", `{"keys":[]}`, 0, false},
+		{"not json", `keys`, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := parseJWKS([]byte(tc.data))
+			if (err == nil) != tc.ok || len(keys) != tc.keys {
+				t.Errorf("parseJWKS = %d keys, %v; want %d keys, ok=%v", len(keys), err, tc.keys, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Декодер бинарного кодирования Avro (без контейнера object container file): схема разбирается
+// из JSON, значение декодируется в map[string]interface{} для записей, []interface{} для массивов,
+// значение выбранной ветки для union. Логические типы timestamp-millis/timestamp-micros
+// декодируются в time.Time (UTC), остальные — в значение базового типа.
+
+// Schema — разобранная схема Avro.
+type Schema struct {
+	root *node
+}
+
+type node struct {
+	kind    string // примитив, record, enum, array, map, union или fixed
+	logical string
+	name    string  // полное имя именованного типа
+	fields  []field // record
+	symbols []string
+	items   *node   // array, map
+	branch  []*node // union
+	size    int     // fixed
+}
+
+type field struct {
+	name string
+	typ  *node
+}
+
+var errShort = errors.New("avro: unexpected end of data")
+
+// maxDepth — предел вложенности значения: рекурсивная схема без union (запись, содержащая себя)
+// иначе рекурсирует без чтения данных.
+const maxDepth = 64
+
+// Parse разбирает схему в JSON-представлении.
+func Parse(text string) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("avro schema: %w", err)
+	}
+	p := &parser{named: map[string]*node{}}
+	root, err := p.parse(raw, "")
+	if err != nil {
+		return nil, fmt.Errorf("avro schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+type parser struct {
+	named map[string]*node
+}
+
+func (p *parser) parse(raw interface{}, namespace string) (*node, error) {
+	switch v := raw.(type) {
+	case string:
+		return p.ref(v, namespace)
+	case []interface{}:
+		n := &node{kind: "union"}
+		for _, b := range v {
+			bn, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			n.branch = append(n.branch, bn)
+		}
+		return n, nil
+	case map[string]interface{}:
+		return p.complex(v, namespace)
+	}
+	return nil, fmt.Errorf("unexpected schema %v", raw)
+}
+
+// ref возвращает примитивный тип или ранее объявленный именованный тип.
+func (p *parser) ref(name, namespace string) (*node, error) {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &node{kind: name}, nil
+	}
+	if n, ok := p.named[fullName(name, namespace)]; ok {
+		return n, nil
+	}
+	if n, ok := p.named[name]; ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", name)
+}
+
+func (p *parser) complex(v map[string]interface{}, namespace string) (*node, error) {
+	typ, _ := v["type"].(string)
+	logical, _ := v["logicalType"].(string)
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s without name", typ)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		n := &node{kind: typ, logical: logical, name: fullName(name, namespace)}
+		if i := strings.LastIndex(n.name, "."); i >= 0 {
+			namespace = n.name[:i]
+		}
+		p.named[n.name] = n // до полей: запись может ссылаться на себя
+		switch typ {
+		case "record", "error":
+			n.kind = "record"
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				fm, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("record %s: malformed field", n.name)
+				}
+				fname, _ := fm["name"].(string)
+				ft, err := p.parse(fm["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("record %s, field %s: %w", n.name, fname, err)
+				}
+				n.fields = append(n.fields, field{name: fname, typ: ft})
+			}
+		case "enum":
+			symbols, _ := v["symbols"].([]interface{})
+			for _, s := range symbols {
+				sym, _ := s.(string)
+				n.symbols = append(n.symbols, sym)
+			}
+		case "fixed":
+			size, ok := v["size"].(float64)
+			if !ok || size < 0 || size != math.Trunc(size) {
+				return nil, fmt.Errorf("fixed %s: invalid size %v", n.name, v["size"])
+			}
+			n.size = int(size)
+		}
+		return n, nil
+	case "array", "map":
+		key := "items"
+		if typ == "map" {
+			key = "values"
+		}
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typ, err)
+		}
+		return &node{kind: typ, items: items}, nil
+	}
+	// примитив с атрибутами, например {"type": "long", "logicalType": "timestamp-millis"}
+	n, err := p.parse(v["type"], namespace)
+	if err != nil {
+		return nil, err
+	}
+	if logical != "" && n.name == "" {
+		n.logical = logical // узел примитива создаётся на каждую ссылку, общих узлов нет
+	}
+	return n, nil
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// Decode декодирует одно значение схемы из data; лишние байты после значения — ошибка.
+// Длины и число элементов из данных проверяются по размеру data до выделения памяти.
+func (s *Schema) Decode(data []byte) (interface{}, error) {
+	d := &decoder{data: data, items: len(data)}
+	v, err := d.value(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("avro: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	items int // сколько ещё элементов массивов и map можно прочитать: не больше, чем байт в data
+	depth int
+}
+
+func (d *decoder) value(n *node) (interface{}, error) {
+	if d.depth++; d.depth > maxDepth {
+		return nil, fmt.Errorf("avro: value nested deeper than %d", maxDepth)
+	}
+	defer func() { d.depth-- }()
+
+	switch n.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == "int" && (v < math.MinInt32 || v > math.MaxInt32) {
+			return nil, fmt.Errorf("avro: int %d out of range", v)
+		}
+		switch n.logical {
+		case "timestamp-millis":
+			return time.UnixMilli(v).UTC(), nil
+		case "timestamp-micros":
+			return time.UnixMicro(v).UTC(), nil
+		}
+		return v, nil
+	case "float":
+		b, err := d.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes", "string":
+		size, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("avro: negative %s length", n.kind)
+		}
+		if size > int64(len(d.data)-d.pos) {
+			return nil, errShort
+		}
+		b, err := d.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == "string" {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case "fixed":
+		b, err := d.bytes(n.size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(n.symbols) {
+			return nil, fmt.Errorf("avro: enum %s index %d out of range", n.name, i)
+		}
+		return n.symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(n.branch) {
+			return nil, fmt.Errorf("avro: union index %d out of range", i)
+		}
+		return d.value(n.branch[i])
+	case "record":
+		rec := make(map[string]interface{}, len(n.fields))
+		for _, f := range n.fields {
+			v, err := d.value(f.typ)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", n.name, f.name, err)
+			}
+			rec[f.name] = v
+		}
+		return rec, nil
+	case "array", "map":
+		return d.blocks(n)
+	}
+	return nil, fmt.Errorf("avro: unsupported type %q", n.kind)
+}
+
+// blocks декодирует массив или map: последовательность блоков с числом элементов, 0 — конец.
+// Отрицательное число — за ним идёт размер блока в байтах. Число элементов ограничено d.items:
+// элементы нулевой ширины (null) иначе позволяют сколь угодно долгий цикл без чтения данных.
+func (d *decoder) blocks(n *node) (interface{}, error) {
+	var arr []interface{}
+	m := map[string]interface{}{}
+	for {
+		count, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			if count == math.MinInt64 {
+				return nil, fmt.Errorf("avro: %s block count out of range", n.kind)
+			}
+			count = -count
+			size, err := d.long()
+			if err != nil {
+				return nil, err
+			}
+			if size < 0 || size > int64(len(d.data)-d.pos) {
+				return nil, fmt.Errorf("avro: %s block size %d out of range", n.kind, size)
+			}
+		}
+		if count > int64(d.items) {
+			return nil, fmt.Errorf("avro: %s block of %d items exceeds the data size", n.kind, count)
+		}
+		d.items -= int(count)
+		for ; count > 0; count-- {
+			var key string
+			if n.kind == "map" {
+				k, err := d.value(&node{kind: "string"})
+				if err != nil {
+					return nil, err
+				}
+				key = k.(string)
+			}
+			v, err := d.value(n.items)
+			if err != nil {
+				return nil, err
+			}
+			if n.kind == "map" {
+				m[key] = v
+			} else {
+				arr = append(arr, v)
+			}
+		}
+	}
+	if n.kind == "map" {
+		return m, nil
+	}
+	return arr, nil
+}
+
+// long читает zigzag varint.
+func (d *decoder) long() (int64, error) {
+	v, size := binary.Varint(d.data[d.pos:])
+	if size == 0 {
+		return 0, errShort
+	}
+	if size < 0 {
+		return 0, errors.New("avro: varint overflows 64 bits")
+	}
+	d.pos += size
+	return v, nil
+}
+
+func (d *decoder) bytes(size int) ([]byte, error) {
+	if size < 0 || size > len(d.data)-d.pos {
+		return nil, errShort
+	}
+	b := d.data[d.pos : d.pos+size]
+	d.pos += size
+	return b, nil
+}
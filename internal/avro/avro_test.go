@@ -0,0 +1,147 @@
+package avro
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// long кодирует zigzag varint.
+func long(v int64) []byte { return binary.AppendVarint(nil, v) }
+
+func str(s string) []byte { return append(long(int64(len(s))), s...) }
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2024, 5, 6, 8, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		schema string
+		data   []byte
+		want   interface{}
+	}{
+		{"null", `"null"`, nil, nil},
+		{"boolean", `"boolean"`, []byte{1}, true},
+		{"int", `"int"`, long(-42), int64(-42)},
+		{"long", `"long"`, long(math.MaxInt64), int64(math.MaxInt64)},
+		{"float", `"float"`, binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5)), float32(1.5)},
+		{"double", `"double"`, binary.LittleEndian.AppendUint64(nil, math.Float64bits(-2.25)), -2.25},
+		{"bytes", `"bytes"`, cat(long(2), []byte{0xde, 0xad}), []byte{0xde, 0xad}},
+		{"string", `"string"`, str("Cannot join"), "Cannot join"},
+		{"empty string", `"string"`, str(""), ""},
+		{"fixed", `{"type":"fixed","name":"Id","size":3}`, []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"enum", `{"type":"enum","name":"Status","symbols":["open","closed"]}`, long(1), "closed"},
+		{"timestamp-millis", `{"type":"long","logicalType":"timestamp-millis"}`, long(at.UnixMilli()), at},
+		{"timestamp-micros", `{"type":"long","logicalType":"timestamp-micros"}`, long(at.UnixMicro()), at},
+		{"union null", `["null","string"]`, long(0), nil},
+		{"union string", `["null","string"]`, cat(long(1), str("s-1")), "s-1"},
+		{"array", `{"type":"array","items":"long"}`, cat(long(2), long(7), long(8), long(0)), []interface{}{int64(7), int64(8)}},
+		{"array of blocks", `{"type":"array","items":"long"}`, cat(long(1), long(7), long(1), long(8), long(0)), []interface{}{int64(7), int64(8)}},
+		{"array negative count", `{"type":"array","items":"string"}`, cat(long(-2), long(4), str("a"), str("b"), long(0)), []interface{}{"a", "b"}},
+		{"empty array", `{"type":"array","items":"long"}`, long(0), []interface{}(nil)},
+		{"map", `{"type":"map","values":"long"}`, cat(long(1), str("a"), long(1), long(0)), map[string]interface{}{"a": int64(1)}},
+		{"map negative count", `{"type":"map","values":"string"}`, cat(long(-1), long(4), str("k"), str("v"), long(0)), map[string]interface{}{"k": "v"}},
+		{
+			"record",
+			`{"type":"record","name":"Ticket","namespace":"psds","fields":[{"name":"ticket_id","type":"long"},{"name":"subject","type":["null","string"]}]}`,
+			cat(long(7), long(1), str("Logs")),
+			map[string]interface{}{"ticket_id": int64(7), "subject": "Logs"},
+		},
+		{
+			"recursive record",
+			`{"type":"record","name":"Node","fields":[{"name":"v","type":"long"},{"name":"next","type":["null","Node"]}]}`,
+			cat(long(1), long(1), long(2), long(0)),
+			map[string]interface{}{"v": int64(1), "next": map[string]interface{}{"v": int64(2), "next": nil}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.schema)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := s.Decode(tc.data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Decode = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsMalformedData(t *testing.T) {
+	const (
+		longs    = `{"type":"array","items":"long"}`
+		nulls    = `{"type":"array","items":"null"}`
+		mapOfStr = `{"type":"map","values":"string"}`
+	)
+	for _, tc := range []struct {
+		name   string
+		schema string
+		data   []byte
+		want   string
+	}{
+		{"empty long", `"long"`, nil, "unexpected end"},
+		{"truncated varint", `"long"`, []byte{0x80}, "unexpected end"},
+		{"varint overflow", `"long"`, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, "overflows"},
+		{"int out of range", `"int"`, long(math.MaxInt32 + 1), "out of range"},
+		{"truncated double", `"double"`, []byte{0, 0, 0}, "unexpected end"},
+		{"truncated string", `"string"`, cat(long(10), []byte("abc")), "unexpected end"},
+		{"huge string length", `"string"`, long(math.MaxInt64), "unexpected end"},
+		{"negative string length", `"string"`, long(-1), "negative string length"},
+		{"truncated fixed", `{"type":"fixed","name":"Id","size":4}`, []byte{1}, "unexpected end"},
+		{"enum out of range", `{"type":"enum","name":"S","symbols":["a"]}`, long(1), "out of range"},
+		{"union out of range", `["null","string"]`, long(2), "out of range"},
+		{"negative union index", `["null","string"]`, long(-1), "out of range"},
+		{"trailing bytes", `"boolean"`, []byte{1, 0}, "trailing bytes"},
+		{"array without end", longs, cat(long(1), long(7)), "unexpected end"},
+		{"truncated array item", longs, cat(long(2), long(7)), "unexpected end"},
+		{"huge array count", longs, long(math.MaxInt64), "exceeds the data size"},
+		{"huge null array count", nulls, long(math.MaxInt64), "exceeds the data size"},
+		{"null array blocks", nulls, cat(long(3), long(3), long(0)), "exceeds the data size"},
+		{"min negative count", longs, long(math.MinInt64), "block count out of range"},
+		{"huge negative count", longs, cat(long(-math.MaxInt64), long(1), long(7)), "exceeds the data size"},
+		{"negative block size", longs, cat(long(-1), long(-1), long(7), long(0)), "block size"},
+		{"block size beyond data", longs, cat(long(-1), long(100), long(7), long(0)), "block size"},
+		{"huge map count", mapOfStr, long(math.MaxInt64), "exceeds the data size"},
+		{"truncated map key", mapOfStr, cat(long(1), long(5), []byte("k")), "unexpected end"},
+		{"truncated record", `{"type":"record","name":"R","fields":[{"name":"a","type":"long"},{"name":"b","type":"string"}]}`, long(1), "R.b"},
+		{"record containing itself", `{"type":"record","name":"Loop","fields":[{"name":"self","type":"Loop"}]}`, nil, "nested deeper"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.schema)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if v, err := s.Decode(tc.data); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Decode = %#v, %v; want error containing %q", v, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, schema := range []string{
+		`{"type":`,
+		`"Unknown"`,
+		`{"type":"record","fields":[]}`,
+		`{"type":"fixed","name":"Id","size":-1}`,
+		`{"type":"fixed","name":"Id"}`,
+		`{"type":"array","items":"Unknown"}`,
+	} {
+		if _, err := Parse(schema); err == nil {
+			t.Errorf("Parse(%s) succeeded, want error", schema)
+		}
+	}
+}
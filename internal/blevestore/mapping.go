@@ -0,0 +1,111 @@
+package blevestore
+
+import (
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// Служебные поля документа.
+const (
+	// fieldSource хранит исходный документ (JSON), как _source в ES; не индексируется.
+	fieldSource = "_source"
+	// fieldNames — индексируемый список непустых полей документа, как _field_names в ES (для exists).
+	fieldNames = "_field_names"
+)
+
+// indexMapping переводит маппинг ES в маппинг Bleve: keyword — keyword-анализатор (точное совпадение),
+// text — standard, мульти-поля индексируются под своим полным именем (display_name.keyword).
+// nested индексируется как обычный вложенный объект: связь полей внутри одного элемента не сохраняется.
+func indexMapping(m *query.Mapping) *mapping.IndexMappingImpl {
+	im := mapping.NewIndexMapping()
+	im.DefaultAnalyzer = standard.Name
+	doc := mapping.NewDocumentMapping()
+
+	source := mapping.NewTextFieldMapping()
+	source.Index = false
+	source.Store = true
+	source.IncludeInAll = false
+	source.DocValues = false
+	doc.AddFieldMappingsAt(fieldSource, source)
+
+	names := mapping.NewKeywordFieldMapping()
+	names.IncludeInAll = false
+	names.DocValues = false
+	doc.AddFieldMappingsAt(fieldNames, names)
+
+	if m != nil {
+		addProperties(doc, m.Properties)
+	}
+	im.DefaultMapping = doc
+	return im
+}
+
+func addProperties(doc *mapping.DocumentMapping, props map[string]query.Property) {
+	for name, p := range props {
+		if p.Type == query.TypeNested || len(p.Properties) > 0 {
+			sub := mapping.NewDocumentMapping()
+			addProperties(sub, p.Properties)
+			doc.AddSubDocumentMapping(name, sub)
+			continue
+		}
+		fms := []*mapping.FieldMapping{fieldMapping(p)}
+		for subName, sp := range p.Fields {
+			fm := fieldMapping(sp)
+			fm.Name = name + "." + subName
+			fms = append(fms, fm)
+		}
+		doc.AddFieldMappingsAt(name, fms...)
+	}
+}
+
+func fieldMapping(p query.Property) *mapping.FieldMapping {
+	switch p.Type {
+	case query.TypeKeyword:
+		fm := mapping.NewKeywordFieldMapping()
+		fm.Analyzer = keyword.Name
+		return fm
+	case query.TypeLong:
+		return mapping.NewNumericFieldMapping()
+	case query.TypeDate:
+		return mapping.NewDateTimeFieldMapping()
+	case query.TypeBoolean:
+		return mapping.NewBooleanFieldMapping()
+	default:
+		fm := mapping.NewTextFieldMapping()
+		fm.Analyzer = standard.Name
+		if p.Analyzer != "" {
+			fm.Analyzer = p.Analyzer
+		}
+		return fm
+	}
+}
+
+// fieldNameList собирает пути непустых полей документа (включая вложенные объекты и массивы объектов).
+func fieldNameList(src map[string]interface{}, prefix string, out []string) []string {
+	for k, v := range src {
+		path := prefix + k
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+		case map[string]interface{}:
+			out = fieldNameList(v, path+".", out)
+		case []interface{}:
+			if len(v) == 0 {
+				continue
+			}
+			for _, item := range v {
+				if obj, ok := item.(map[string]interface{}); ok {
+					out = fieldNameList(obj, path+".", out)
+				}
+			}
+		}
+		out = append(out, path)
+	}
+	return out
+}
@@ -0,0 +1,323 @@
+// Package blevestore — встроенное хранилище поиска на Bleve (индексы на локальном диске) для небольших
+// установок без кластера Elasticsearch. Реализует elasticsearch.IndexSearcher, переводя маппинги и
+// запросы Query DSL в Bleve. inner_hits не возвращаются: nested хранится как обычный вложенный объект.
+package blevestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// mappingKey — ключ внутреннего хранилища Bleve, под которым сохраняется маппинг ES индекса.
+var mappingKey = []byte("search-service.mapping")
+
+// Store — набор индексов Bleve в каталоге dir (по подкаталогу на индекс).
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	indices map[string]*index
+
+	writeMu sync.Mutex // сериализует запись: частичные обновления — чтение _source и переиндексация
+}
+
+type index struct {
+	bleve.Index
+	fields fields
+}
+
+// New создаёт хранилище в каталоге dir (создаётся при необходимости).
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("bleve dir: %w", err)
+	}
+	return &Store{dir: dir, indices: map[string]*index{}}, nil
+}
+
+var _ elasticsearch.IndexSearcher = (*Store)(nil)
+
+// EnsureIndex открывает индекс с диска или создаёт его с маппингом. Маппинг существующего индекса
+// не меняется (как в ES — только через переиндексацию).
+func (s *Store) EnsureIndex(ctx context.Context, name string, mapping *query.Mapping) error {
+	_, err := s.open(name, mapping)
+	return err
+}
+
+// open возвращает открытый индекс; mapping используется только при создании.
+func (s *Store) open(name string, mapping *query.Mapping) (*index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if idx, ok := s.indices[name]; ok {
+		return idx, nil
+	}
+	path := filepath.Join(s.dir, name)
+	bi, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		bi, err = bleve.New(path, indexMapping(mapping))
+		if err == nil && mapping != nil {
+			var data []byte
+			if data, err = json.Marshal(mapping.Properties); err == nil {
+				err = bi.SetInternal(mappingKey, data)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open bleve index %s: %w", name, err)
+	}
+	idx := &index{Index: bi, fields: fields{}}
+	if data, err := bi.GetInternal(mappingKey); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &idx.fields); err != nil {
+			return nil, fmt.Errorf("bleve index %s: stored mapping: %w", name, err)
+		}
+	}
+	s.indices[name] = idx
+	return idx, nil
+}
+
+// UpdateDocument сливает поля doc верхнего уровня с сохранённым документом (или создаёт его)
+// по правилам opts (UpdateOptions.Apply); проверка порядка и запись — под writeMu.
+func (s *Store) UpdateDocument(ctx context.Context, name, id string, doc interface{}, opts *elasticsearch.UpdateOptions) error {
+	src, err := elasticsearch.ToSource(doc)
+	if err != nil {
+		return err
+	}
+	var applyErr error
+	err = s.update(ctx, name, id, func(old map[string]interface{}) map[string]interface{} {
+		if applyErr = opts.Apply(old, src); applyErr != nil {
+			return nil
+		}
+		return old
+	})
+	if err != nil {
+		return err
+	}
+	return applyErr
+}
+
+// UpsertNested заменяет элемент массива path с тем же значением key или добавляет item в конец;
+// отсутствующий документ создаётся из doc.
+func (s *Store) UpsertNested(ctx context.Context, name, id, path, key string, item, doc map[string]interface{}) error {
+	obj, err := elasticsearch.ToSource(item)
+	if err != nil {
+		return err
+	}
+	base, err := elasticsearch.ToSource(doc)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, name, id, func(old map[string]interface{}) map[string]interface{} {
+		if len(old) == 0 {
+			if base == nil {
+				base = map[string]interface{}{}
+			}
+			base[path] = []interface{}{obj}
+			return base
+		}
+		items, _ := old[path].([]interface{})
+		for i, existing := range items {
+			if e, ok := existing.(map[string]interface{}); ok && fmt.Sprint(e[key]) == fmt.Sprint(obj[key]) {
+				items[i] = obj
+				return old
+			}
+		}
+		old[path] = append(items, obj)
+		return old
+	})
+}
+
+// updateByQueryBatch — сколько id совпавших документов читается за один запрос.
+const updateByQueryBatch = 500
+
+// UpdateByQuery сливает fields с каждым документом, подходящим под q. Сначала собираются id всех
+// совпавших документов, затем каждый переиндексируется.
+func (s *Store) UpdateByQuery(ctx context.Context, name string, q query.Query, fields map[string]interface{}) (int64, error) {
+	set, err := elasticsearch.ToSource(fields)
+	if err != nil {
+		return 0, err
+	}
+	idx, err := s.open(name, nil)
+	if err != nil {
+		return 0, err
+	}
+	bq, err := idx.fields.translate(q)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for {
+		sr := bleve.NewSearchRequestOptions(bq, updateByQueryBatch, len(ids), false)
+		sr.SortBy([]string{"_id"}) // стабильный порядок между страницами
+		res, err := idx.SearchInContext(ctx, sr)
+		if err != nil {
+			return 0, fmt.Errorf("bleve search %s: %w", name, err)
+		}
+		for _, h := range res.Hits {
+			ids = append(ids, h.ID)
+		}
+		if len(res.Hits) < updateByQueryBatch {
+			break
+		}
+	}
+	for i, id := range ids {
+		err := s.update(ctx, name, id, func(old map[string]interface{}) map[string]interface{} {
+			for k, v := range set {
+				old[k] = v
+			}
+			return old
+		})
+		if err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+// update переиндексирует документ целиком: fn получает сохранённый _source (пустой, если документа нет);
+// nil от fn оставляет документ без изменений.
+func (s *Store) update(ctx context.Context, name, id string, fn func(map[string]interface{}) map[string]interface{}) error {
+	idx, err := s.open(name, nil)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	old, _, err := idx.source(ctx, id)
+	if err != nil {
+		return fmt.Errorf("bleve index %s: read %s: %w", name, id, err)
+	}
+	src := fn(old)
+	if src == nil {
+		return nil
+	}
+	return s.put(name, id, src)
+}
+
+// put индексирует src под id; вызывается под writeMu.
+func (s *Store) put(name, id string, src map[string]interface{}) error {
+	idx, err := s.open(name, nil)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("marshal document: %w", err)
+	}
+	names := fieldNameList(src, "", nil)
+	src[fieldSource] = string(data)
+	src[fieldNames] = names
+	if err := idx.Index.Index(id, src); err != nil {
+		return fmt.Errorf("bleve index %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetDocument возвращает сохранённый _source документа (nil, если документа нет).
+func (s *Store) GetDocument(ctx context.Context, name, id string) (map[string]interface{}, error) {
+	idx, err := s.open(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	src, found, err := idx.source(ctx, id)
+	if err != nil || !found {
+		return nil, err
+	}
+	return src, nil
+}
+
+// source читает сохранённый _source документа; для отсутствующего документа — пустой объект и found=false.
+func (idx *index) source(ctx context.Context, id string) (src map[string]interface{}, found bool, err error) {
+	sr := bleve.NewSearchRequest(bleve.NewDocIDQuery([]string{id}))
+	sr.Fields = []string{fieldSource}
+	res, err := idx.SearchInContext(ctx, sr)
+	if err != nil {
+		return nil, false, err
+	}
+	src = map[string]interface{}{}
+	if len(res.Hits) == 0 {
+		return src, false, nil
+	}
+	raw, _ := res.Hits[0].Fields[fieldSource].(string)
+	if err := json.Unmarshal([]byte(raw), &src); err != nil {
+		return nil, false, fmt.Errorf("decode source: %w", err)
+	}
+	return src, true, nil
+}
+
+func (s *Store) Search(ctx context.Context, name string, req *query.Search) (*elasticsearch.SearchResponse, error) {
+	idx, err := s.open(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Aggs) > 0 {
+		return nil, errors.New("bleve store: aggregations are not supported")
+	}
+	q, err := idx.fields.translate(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	sr := bleve.NewSearchRequestOptions(q, req.Size, req.From, req.Explain)
+	sr.Fields = []string{fieldSource}
+	if len(req.Sort) > 0 {
+		order := make(search.SortOrder, 0, len(req.Sort))
+		for _, so := range req.Sort {
+			if so.Field == query.FieldScore {
+				order = append(order, &search.SortScore{Desc: so.Order != query.Asc})
+				continue
+			}
+			missing := search.SortFieldMissingLast
+			if so.Missing == "_first" {
+				missing = search.SortFieldMissingFirst
+			}
+			order = append(order, &search.SortField{Field: so.Field, Desc: so.Order == query.Desc, Missing: missing})
+		}
+		sr.SortByCustom(order)
+	}
+
+	res, err := idx.SearchInContext(ctx, sr)
+	if err != nil {
+		return nil, fmt.Errorf("bleve search %s: %w", name, err)
+	}
+
+	resp := &elasticsearch.SearchResponse{Took: res.Took.Milliseconds()}
+	resp.Hits.Total.Value = int64(res.Total)
+	for _, h := range res.Hits {
+		hit := elasticsearch.SearchHit{ID: h.ID, Score: h.Score}
+		if raw, ok := h.Fields[fieldSource].(string); ok {
+			if err := json.Unmarshal([]byte(raw), &hit.Source); err != nil {
+				return nil, fmt.Errorf("bleve hit %s: decode source: %w", h.ID, err)
+			}
+		}
+		if req.Explain && h.Expl != nil {
+			hit.Explanation, _ = json.Marshal(h.Expl)
+		}
+		resp.Hits.Hits = append(resp.Hits.Hits, hit)
+	}
+	if req.Profile {
+		resp.Profile, _ = json.Marshal(map[string]interface{}{"backend": "bleve", "query": q, "cost": res.Cost})
+	}
+	return resp, nil
+}
+
+// Close закрывает все открытые индексы.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for name, idx := range s.indices {
+		if err := idx.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", name, err))
+		}
+		delete(s.indices, name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package blevestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+func TestStoreSearch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnsureIndex(ctx, "operators", elasticsearch.OperatorsMapping()); err != nil {
+		t.Fatal(err)
+	}
+	docs := map[string]map[string]interface{}{
+		"u-1": {"user_id": "u-1", "display_name": "Anna Petrova", "region": "eu", "role": "operator", "created_at": "2024-05-01T10:00:00Z"},
+		"u-2": {"user_id": "u-2", "display_name": "Anna", "region": "us", "role": "supervisor", "created_at": "2024-05-03T10:00:00Z"},
+		"u-3": {"user_id": "u-3", "display_name": "Boris", "region": "eu", "created_at": "2024-05-06T10:00:00.5Z"},
+	}
+	for id, doc := range docs {
+		if err := s.UpdateDocument(ctx, "operators", id, doc, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		q    query.Query
+		sort []query.Sort
+		want []string
+	}{
+		{"exact keyword subfield", query.Term("display_name.keyword", "Anna"), nil, []string{"u-2"}},
+		{"term on keyword", query.Term("region", "eu"), []query.Sort{query.SortBy("user_id", query.Asc)}, []string{"u-1", "u-3"}},
+		{"match analyzed", query.Match("display_name", "anna"), []query.Sort{query.SortBy("user_id", query.Desc)}, []string{"u-2", "u-1"}},
+		{"bool filter and must_not", &query.BoolQuery{
+			Filter:  []query.Query{query.Term("region", "eu")},
+			MustNot: []query.Query{query.Exists("role")},
+		}, nil, []string{"u-3"}},
+		{"terms", query.Terms("role", "operator", "supervisor"), []query.Sort{query.SortBy("user_id", query.Asc)}, []string{"u-1", "u-2"}},
+		{"date range [gte, lt)", &query.RangeQuery{Field: "created_at", GTE: "2024-05-03T00:00:00Z", LT: "2024-05-06T10:00:00.5Z"}, nil, []string{"u-2"}},
+		{"should only", &query.BoolQuery{Should: []query.Query{query.Term("user_id", "u-3"), query.Term("user_id", "u-9")}}, nil, []string{"u-3"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := s.Search(ctx, "operators", &query.Search{Query: tc.q, Size: 10, Sort: tc.sort})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range resp.Hits.Hits {
+				got = append(got, h.ID)
+				if h.Source["user_id"] != h.ID {
+					t.Errorf("hit %s: source not restored: %v", h.ID, h.Source)
+				}
+			}
+			if len(got) != len(tc.want) || resp.Hits.Total.Value != int64(len(tc.want)) {
+				t.Fatalf("got %v (total %d), want %v", got, resp.Hits.Total.Value, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+
+	// Индекс и маппинг переживают перезапуск.
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	resp, err := s.Search(ctx, "operators", &query.Search{Query: query.Term("display_name.keyword", "Anna Petrova"), Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Hits.Total.Value != 1 || resp.Hits.Hits[0].ID != "u-1" {
+		t.Fatalf("after reopen: got %+v", resp.Hits.Hits)
+	}
+}
+
+func TestStorePartialUpdates(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.EnsureIndex(ctx, "tickets", elasticsearch.TicketsMapping()); err != nil {
+		t.Fatal(err)
+	}
+	upsert := func(id, text string) {
+		t.Helper()
+		item := map[string]interface{}{"comment_id": id, "author": "op-1", "text": text}
+		if err := s.UpsertNested(ctx, "tickets", "7", "comments", "comment_id", item, map[string]interface{}{"ticket_id": 7}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upsert("c-1", "printer offline")
+	if err := s.UpdateDocument(ctx, "tickets", "7", map[string]interface{}{"ticket_id": 7, "status": "open"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	upsert("c-2", "toner replaced")
+	upsert("c-1", "printer works")
+
+	resp, err := s.Search(ctx, "tickets", &query.Search{Size: 10, Query: &query.BoolQuery{
+		Filter: []query.Query{query.Term("status", "open")},
+		Must:   []query.Query{query.Nested("comments", query.Match("comments.text", "toner"))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits.Hits) != 1 {
+		t.Fatalf("hits = %d, want 1", len(resp.Hits.Hits))
+	}
+	comments, _ := resp.Hits.Hits[0].Source["comments"].([]interface{})
+	if len(comments) != 2 || comments[0].(map[string]interface{})["text"] != "printer works" {
+		t.Fatalf("comments = %v, want c-1 replaced in place and c-2 appended", comments)
+	}
+	// старый текст c-1 больше не находится
+	resp, err = s.Search(ctx, "tickets", &query.Search{Size: 10, Query: query.Nested("comments", query.Match("comments.text", "offline"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits.Hits) != 0 {
+		t.Fatalf("stale comment text still matches: %+v", resp.Hits.Hits)
+	}
+
+	n, err := s.UpdateByQuery(ctx, "tickets", query.Term("status", "open"), map[string]interface{}{"operator_region": "north-west"})
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateByQuery = %d, %v; want 1 document", n, err)
+	}
+	resp, err = s.Search(ctx, "tickets", &query.Search{Size: 10, Query: query.Term("operator_region", "north-west")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits.Hits) != 1 || len(resp.Hits.Hits[0].Source["comments"].([]interface{})) != 2 {
+		t.Fatalf("after update by query: %+v, want ticket 7 with its comments", resp.Hits.Hits)
+	}
+}
+
+func TestStoreUpdateDocumentEventOrder(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	newer := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	if err := s.UpdateDocument(ctx, "operators", "u-1", map[string]interface{}{"region": "eu"}, &elasticsearch.UpdateOptions{EventAt: newer}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdateDocument(ctx, "operators", "u-1", map[string]interface{}{"region": "us"}, &elasticsearch.UpdateOptions{EventAt: newer.Add(-time.Hour)})
+	if !errors.Is(err, elasticsearch.ErrStale) {
+		t.Fatalf("older event: err = %v, want ErrStale", err)
+	}
+	doc, err := s.GetDocument(ctx, "operators", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["region"] != "eu" {
+		t.Fatalf("region = %v, want eu from the newer event", doc["region"])
+	}
+}
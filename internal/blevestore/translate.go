@@ -0,0 +1,243 @@
+package blevestore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	bq "github.com/blevesearch/bleve/v2/search/query"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// fields описывает типы полей индекса для перевода запросов (из маппинга, с которым индекс создан).
+type fields map[string]query.Property
+
+// lookup возвращает свойство поля; мульти-поле (display_name.keyword) — своё подполе.
+func (f fields) lookup(field string) (query.Property, bool) {
+	if p, ok := f[field]; ok {
+		return p, true
+	}
+	if parent, sub, ok := strings.Cut(field, "."); ok {
+		if p, ok := f[parent]; ok {
+			if sp, ok := p.Fields[sub]; ok {
+				return sp, true
+			}
+			return fields(p.Properties).lookup(sub)
+		}
+	}
+	return query.Property{}, false
+}
+
+// translate переводит запрос Query DSL в запрос Bleve.
+func (f fields) translate(q query.Query) (bq.Query, error) {
+	switch q := q.(type) {
+	case nil, *query.MatchAllQuery:
+		return bleve.NewMatchAllQuery(), nil
+	case *query.TermQuery:
+		return f.term(q.Field, q.Value), nil
+	case *query.TermsQuery:
+		if len(q.Values) == 0 {
+			return bleve.NewMatchNoneQuery(), nil
+		}
+		terms := make([]bq.Query, 0, len(q.Values))
+		for _, v := range q.Values {
+			terms = append(terms, f.term(q.Field, v))
+		}
+		return bleve.NewDisjunctionQuery(terms...), nil
+	case *query.MatchQuery:
+		return f.match(q.Field, q.Text, q.Operator, q.Fuzziness), nil
+	case *query.MultiMatchQuery:
+		matches := make([]bq.Query, 0, len(q.Fields))
+		for _, spec := range q.Fields {
+			field, boost, _ := strings.Cut(spec, "^")
+			m := f.match(field, q.Text, q.Operator, "")
+			if boost != "" {
+				var b float64
+				if _, err := fmt.Sscan(boost, &b); err == nil {
+					m.SetBoost(b)
+				}
+			}
+			matches = append(matches, m)
+		}
+		return bleve.NewDisjunctionQuery(matches...), nil
+	case *query.RangeQuery:
+		return f.rangeQuery(q)
+	case *query.ExistsQuery:
+		field := q.Field
+		if p, ok := f[strings.SplitN(field, ".", 2)[0]]; ok && len(p.Fields) > 0 {
+			field = strings.SplitN(field, ".", 2)[0] // мульти-поле существует вместе с родителем
+		}
+		t := bleve.NewTermQuery(field)
+		t.SetField(fieldNames)
+		return t, nil
+	case *query.NestedQuery:
+		return f.translate(q.Query)
+	case *query.BoolQuery:
+		return f.boolQuery(q)
+	default:
+		return nil, fmt.Errorf("bleve store: unsupported query %q", q.Kind())
+	}
+}
+
+func (f fields) term(field string, value interface{}) bq.Query {
+	p, _ := f.lookup(field)
+	switch v := value.(type) {
+	case bool:
+		b := bleve.NewBoolFieldQuery(v)
+		b.SetField(field)
+		return b
+	case float64, float32, int, int32, int64:
+		n := toFloat(v)
+		incl := true
+		r := bleve.NewNumericRangeInclusiveQuery(&n, &n, &incl, &incl)
+		r.SetField(field)
+		return r
+	}
+	text := fmt.Sprint(value)
+	if p.Type == query.TypeText {
+		text = strings.ToLower(text) // term по text-полю сравнивается с токенами, как в ES
+	}
+	t := bleve.NewTermQuery(text)
+	t.SetField(field)
+	return t
+}
+
+func (f fields) match(field, text, operator, fuzziness string) *bq.MatchQuery {
+	m := bleve.NewMatchQuery(text)
+	m.SetField(field)
+	if operator == query.OperatorAnd {
+		m.SetOperator(bq.MatchQueryOperatorAnd)
+	}
+	if fuzziness != "" && fuzziness != "0" {
+		m.SetFuzziness(1)
+	}
+	return m
+}
+
+func (f fields) rangeQuery(q *query.RangeQuery) (bq.Query, error) {
+	lower, lowerIncl := q.GTE, true
+	if q.GT != nil {
+		lower, lowerIncl = q.GT, false
+	}
+	upper, upperIncl := q.LTE, true
+	if q.LT != nil {
+		upper, upperIncl = q.LT, false
+	}
+	p, _ := f.lookup(q.Field)
+	switch {
+	case isNumber(lower) || isNumber(upper):
+		var minV, maxV *float64
+		if lower != nil {
+			v := toFloat(lower)
+			minV = &v
+		}
+		if upper != nil {
+			v := toFloat(upper)
+			maxV = &v
+		}
+		r := bleve.NewNumericRangeInclusiveQuery(minV, maxV, &lowerIncl, &upperIncl)
+		r.SetField(q.Field)
+		return r, nil
+	case p.Type == query.TypeDate || isTime(lower) || isTime(upper):
+		r := bleve.NewDateRangeInclusiveStringQuery(str(lower), str(upper), &lowerIncl, &upperIncl)
+		r.SetField(q.Field)
+		return r, nil
+	default:
+		r := bleve.NewTermRangeInclusiveQuery(str(lower), str(upper), &lowerIncl, &upperIncl)
+		r.SetField(q.Field)
+		return r, nil
+	}
+}
+
+func (f fields) boolQuery(q *query.BoolQuery) (bq.Query, error) {
+	list := func(qs []query.Query) ([]bq.Query, error) {
+		out := make([]bq.Query, 0, len(qs))
+		for _, c := range qs {
+			t, err := f.translate(c)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, t)
+		}
+		return out, nil
+	}
+	must, err := list(q.Must)
+	if err != nil {
+		return nil, err
+	}
+	should, err := list(q.Should)
+	if err != nil {
+		return nil, err
+	}
+	mustNot, err := list(q.MustNot)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := list(q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(must)+len(should) == 0 && len(filter) > 0 {
+		// Только фильтры: ES возвращает все подходящие документы со score 0.
+		must = append(must, bleve.NewMatchAllQuery())
+	}
+	if len(must)+len(should)+len(filter) == 0 && len(mustNot) > 0 {
+		must = append(must, bleve.NewMatchAllQuery())
+	}
+	b := bleve.NewBooleanQuery()
+	b.AddMust(must...)
+	b.AddShould(should...)
+	b.AddMustNot(mustNot...)
+	if len(filter) > 0 {
+		b.AddFilter(bleve.NewConjunctionQuery(filter...))
+	}
+	minShould := q.MinimumShouldMatch
+	if minShould == 0 && len(should) > 0 && len(q.Must) == 0 && len(q.Filter) == 0 {
+		minShould = 1 // ES: bool только из should требует хотя бы одно совпадение
+	}
+	if len(should) > 0 {
+		b.SetMinShould(float64(minShould))
+	}
+	return b, nil
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int32, int64:
+		return true
+	}
+	return false
+}
+
+func isTime(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+func str(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
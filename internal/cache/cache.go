@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache — кэш результатов поиска. Значения — сериализованные байты, чтобы реализацию можно было
+// заменить на внешнюю (например, Redis). Каждая запись помечается тегами; InvalidateTag удаляет все
+// записи с тегом (например, все результаты по индексу после индексации в него документа).
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string)
+	InvalidateTag(ctx context.Context, tag string)
+}
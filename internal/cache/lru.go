@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU — in-process кэш с ограничением по числу записей и TTL на запись.
+type LRU struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List // фронт — самые свежие
+	items map[string]*list.Element
+	tags  map[string]map[string]struct{} // tag → ключи
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// NewLRU создаёт кэш на size записей.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+		tags:  map[string]map[string]struct{}{},
+	}
+}
+
+var _ Cache = (*LRU)(nil)
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	e := &entry{key: key, value: value, expires: time.Now().Add(ttl), tags: tags}
+	c.items[key] = c.order.PushFront(e)
+	for _, t := range tags {
+		if c.tags[t] == nil {
+			c.tags[t] = map[string]struct{}{}
+		}
+		c.tags[t][key] = struct{}{}
+	}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) InvalidateTag(_ context.Context, tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tags[tag] {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+	delete(c.tags, tag)
+}
+
+// remove удаляет запись и её привязки к тегам. Вызывается под c.mu.
+func (c *LRU) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.order.Remove(el)
+	delete(c.items, e.key)
+	for _, t := range e.tags {
+		if keys := c.tags[t]; keys != nil {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(c.tags, t)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	if _, ok := c.Get(ctx, "a"); !ok { // a становится самой свежей
+		t.Fatal("a is missing")
+	}
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("b is still cached, want it evicted as least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted, want kept", key)
+		}
+	}
+	c.Set(ctx, "c", []byte("4"), time.Minute) // перезапись не увеличивает число записей
+	if v, ok := c.Get(ctx, "c"); !ok || string(v) != "4" || c.order.Len() != 2 {
+		t.Errorf("c = %q, %v with %d entries; want the new value and 2 entries", v, ok, c.order.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	c.Set(ctx, "short", []byte("1"), 10*time.Millisecond, "tickets")
+	c.Set(ctx, "long", []byte("2"), time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get(ctx, "short"); ok {
+		t.Error("expired entry returned")
+	}
+	if _, ok := c.Get(ctx, "long"); !ok {
+		t.Error("unexpired entry is missing")
+	}
+	if len(c.items) != 1 || len(c.tags) != 0 {
+		t.Errorf("items=%d tags=%d, want the expired entry and its tag dropped", len(c.items), len(c.tags))
+	}
+}
+
+func TestLRUInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	c.Set(ctx, "t1", []byte("1"), time.Minute, "tickets")
+	c.Set(ctx, "t2", []byte("2"), time.Minute, "tickets", "sessions")
+	c.Set(ctx, "s1", []byte("3"), time.Minute, "sessions")
+	c.InvalidateTag(ctx, "tickets")
+	for key, want := range map[string]bool{"t1": false, "t2": false, "s1": true} {
+		if _, ok := c.Get(ctx, key); ok != want {
+			t.Errorf("Get(%s) cached=%v, want %v", key, ok, want)
+		}
+	}
+	if keys := c.tags["sessions"]; len(keys) != 1 {
+		t.Errorf("sessions tag keys = %v, want only s1", keys)
+	}
+	c.InvalidateTag(ctx, "unknown") // неизвестный тег — не ошибка
+}
@@ -2,26 +2,95 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+)
+
+// Хранилища индексов (STORAGE, флаг --storage команды api).
+const (
+	StorageElasticsearch = "elasticsearch"
+	StorageMemory        = "memory"
+	StorageBleve         = "bleve"
 )
 
 type Config struct {
-	AppHost  string
-	HTTPPort string
-	GRPCPort string
-	LogLevel string
+	AppHost   string
+	HTTPPort  string
+	GRPCPort  string
+	LogLevel  string // debug | info | warn | error
+	LogFormat string // json | text
+
+	Storage   string // elasticsearch | memory (in-process, для локальной разработки) | bleve (встроенные индексы на диске)
+	BlevePath string // каталог индексов Bleve (STORAGE=bleve)
 
 	Elasticsearch struct {
 		URL                string
 		InsecureSkipVerify bool   // skip TLS cert verification (dev only)
 		Username           string // Basic auth (optional)
 		Password           string
+		SlowQuery          time.Duration // порог медленного поискового запроса для лога (0 — выключен)
+		Flavor             string        // auto | elasticsearch | opensearch (auto — определить по GET /)
+	}
+
+	KafkaBrokers  []string
+	KafkaGroupID  string
+	KafkaTopics   []string
+	KafkaDLQTopic string // dead letter топик для необработанных сообщений (пусто — выключен: некорректное сообщение отбрасывается, при сбое ES повторяется до успеха)
+	KafkaRoutes   string // маршруты сообщений к обработчикам: "psds.ticket.*=ticket,psds.events:session.*=session"
+
+	SessionStatesFile string // JSON-машина состояний статуса сессии (пусто — встроенная)
+
+	SchemaRegistry struct {
+		URL      string // Confluent-совместимый реестр схем protobuf/Avro-сообщений Kafka (пусто — только JSON)
+		Username string // Basic auth (опционально)
+		Password string
+		Dir      string // локальный каталог схем вместо реестра: <id>.avsc|.proto
+	}
+
+	WorkerHTTPPort string // порт HTTP-сервера worker'а (/metrics, /health)
+
+	Auth struct {
+		Enabled     bool          // требовать JWT на всех RPC (включая grpc-gateway)
+		JWKSFile    string        // путь к JWKS (взаимоисключающе с JWKSURL)
+		JWKSURL     string        // URL JWKS
+		JWKSRefresh time.Duration // период перечитывания JWKS по URL
+		Issuer      string        // ожидаемый iss (опционально)
+		Audience    string        // ожидаемый aud (опционально)
+		RoleClaim   string        // claim с ролью (строка или массив)
+		ServiceRole string        // роль, которой разрешены Index* RPC
+		AdminRole   string        // роль, которой разрешён Explain RPC
+		PolicyFile  string        // JSON-политика row-level доступа к результатам поиска (пусто — без ограничений)
 	}
 
-	KafkaBrokers []string
-	KafkaGroupID string
-	KafkaTopics  []string
+	ExplainEnabled bool // Explain RPC: отладка запросов в обход кэша с телом запроса ES (по умолчанию выключен)
+
+	RateLimit struct {
+		Enabled bool
+		Search  string // бюджет search RPC на вызывающего, "rps:burst"
+		Index   string // бюджет Index* RPC на вызывающего, "rps:burst"
+		Routes  string // переопределения по RPC: "SearchSessions=5:10,IndexTicket=50:100"
+	}
+
+	Cache struct {
+		Enabled bool
+		Size    int           // максимум записей в LRU
+		TTL     time.Duration // время жизни результата
+	}
+
+	Tenancy struct {
+		Mode  string // none | index | filter
+		Claim string // JWT claim с арендатором (при AUTH_ENABLED обязателен в токене; заголовок x-tenant-id должен с ним совпадать)
+	}
+
+	Tracing struct {
+		Exporter     string  // none | otlp | stdout
+		OTLPEndpoint string  // host:port OTLP/gRPC коллектора
+		OTLPInsecure bool    // без TLS до коллектора
+		SampleRatio  float64 // доля сэмплируемых трейсов (0..1)
+	}
 }
 
 func Load() (*Config, error) {
@@ -31,10 +100,19 @@ func Load() (*Config, error) {
 		GRPCPort: firstEnv("GRPC_PORT", "METRICS_PORT", "9096"),
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
+	cfg.LogFormat = getEnv("LOG_FORMAT", "json")
+	cfg.Storage = strings.ToLower(getEnv("STORAGE", StorageElasticsearch))
+	cfg.BlevePath = getEnv("BLEVE_PATH", "./data/bleve")
 	cfg.Elasticsearch.URL = getEnv("ELASTICSEARCH_URL", "http://localhost:9200")
 	cfg.Elasticsearch.InsecureSkipVerify = parseBool(getEnv("ELASTICSEARCH_INSECURE_SKIP_VERIFY", "false"))
 	cfg.Elasticsearch.Username = getEnv("ELASTICSEARCH_USERNAME", "")
 	cfg.Elasticsearch.Password = getEnv("ELASTICSEARCH_PASSWORD", "")
+	cfg.Elasticsearch.Flavor = strings.ToLower(getEnv("ELASTICSEARCH_FLAVOR", "auto"))
+	slowQuery, err := time.ParseDuration(getEnv("SLOW_QUERY_THRESHOLD", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("SLOW_QUERY_THRESHOLD: %w", err)
+	}
+	cfg.Elasticsearch.SlowQuery = slowQuery
 
 	// Kafka config
 	brokersStr := getEnv("KAFKA_BROKERS", "")
@@ -58,13 +136,105 @@ func Load() (*Config, error) {
 		}
 	}
 	cfg.KafkaTopics = kafkaTopics
+	cfg.KafkaDLQTopic = getEnv("KAFKA_DLQ_TOPIC", "")
+	cfg.KafkaRoutes = getEnv("KAFKA_ROUTES", "psds.ticket.*=ticket,psds.session.*=session,psds.operator.*=operator")
+	cfg.SessionStatesFile = getEnv("SESSION_STATES_FILE", "")
+	cfg.SchemaRegistry.URL = getEnv("SCHEMA_REGISTRY_URL", "")
+	cfg.SchemaRegistry.Username = getEnv("SCHEMA_REGISTRY_USERNAME", "")
+	cfg.SchemaRegistry.Password = getEnv("SCHEMA_REGISTRY_PASSWORD", "")
+	cfg.SchemaRegistry.Dir = getEnv("SCHEMA_DIR", "")
+	cfg.WorkerHTTPPort = getEnv("WORKER_HTTP_PORT", "9097")
+
+	cfg.Auth.Enabled = parseBool(getEnv("AUTH_ENABLED", "false"))
+	cfg.Auth.JWKSFile = getEnv("JWT_JWKS_FILE", "")
+	cfg.Auth.JWKSURL = getEnv("JWT_JWKS_URL", "")
+	refresh, err := time.ParseDuration(getEnv("JWT_JWKS_REFRESH", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_JWKS_REFRESH: %w", err)
+	}
+	cfg.Auth.JWKSRefresh = refresh
+	cfg.Auth.Issuer = getEnv("JWT_ISSUER", "")
+	cfg.Auth.Audience = getEnv("JWT_AUDIENCE", "")
+	cfg.Auth.RoleClaim = getEnv("JWT_ROLE_CLAIM", "role")
+	cfg.Auth.ServiceRole = getEnv("AUTH_SERVICE_ROLE", "service")
+	cfg.Auth.AdminRole = getEnv("AUTH_ADMIN_ROLE", "admin")
+	cfg.Auth.PolicyFile = getEnv("POLICY_FILE", "")
+
+	cfg.ExplainEnabled = parseBool(getEnv("EXPLAIN_ENABLED", "false"))
+
+	cfg.RateLimit.Enabled = parseBool(getEnv("RATE_LIMIT_ENABLED", "false"))
+	cfg.RateLimit.Search = getEnv("RATE_LIMIT_SEARCH", "20:40")
+	cfg.RateLimit.Index = getEnv("RATE_LIMIT_INDEX", "100:200")
+	cfg.RateLimit.Routes = getEnv("RATE_LIMIT_ROUTES", "")
+
+	cfg.Cache.Enabled = parseBool(getEnv("CACHE_ENABLED", "false"))
+	cacheSize, err := strconv.Atoi(getEnv("CACHE_SIZE", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("CACHE_SIZE: %w", err)
+	}
+	cfg.Cache.Size = cacheSize
+	cacheTTL, err := time.ParseDuration(getEnv("CACHE_TTL", "3s"))
+	if err != nil {
+		return nil, fmt.Errorf("CACHE_TTL: %w", err)
+	}
+	cfg.Cache.TTL = cacheTTL
+
+	cfg.Tenancy.Mode = strings.ToLower(getEnv("TENANCY_MODE", "none"))
+	cfg.Tenancy.Claim = getEnv("TENANT_CLAIM", "tenant_id")
+
+	cfg.Tracing.Exporter = getEnv("OTEL_TRACES_EXPORTER", "none")
+	cfg.Tracing.OTLPEndpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	cfg.Tracing.OTLPInsecure = parseBool(getEnv("OTEL_EXPORTER_OTLP_INSECURE", "false"))
+	ratio, err := strconv.ParseFloat(getEnv("OTEL_TRACES_SAMPLER_ARG", "1"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %w", err)
+	}
+	cfg.Tracing.SampleRatio = ratio
 
 	return cfg, nil
 }
 
 func (c *Config) Validate() error {
-	if c.Elasticsearch.URL == "" {
-		return errors.New("config: ELASTICSEARCH_URL is required")
+	switch c.Storage {
+	case StorageElasticsearch:
+		if c.Elasticsearch.URL == "" {
+			return errors.New("config: ELASTICSEARCH_URL is required")
+		}
+		switch c.Elasticsearch.Flavor {
+		case "auto", "elasticsearch", "opensearch":
+		default:
+			return errors.New("config: ELASTICSEARCH_FLAVOR must be auto, elasticsearch or opensearch")
+		}
+	case StorageMemory:
+	case StorageBleve:
+		if c.BlevePath == "" {
+			return errors.New("config: BLEVE_PATH is required when STORAGE=bleve")
+		}
+	default:
+		return errors.New("config: STORAGE must be elasticsearch, memory or bleve")
+	}
+	if c.Auth.Enabled && (c.Auth.JWKSFile == "") == (c.Auth.JWKSURL == "") {
+		return errors.New("config: AUTH_ENABLED requires exactly one of JWT_JWKS_FILE or JWT_JWKS_URL")
+	}
+	if c.Auth.PolicyFile != "" && !c.Auth.Enabled {
+		return errors.New("config: POLICY_FILE requires AUTH_ENABLED (policy rules are evaluated against JWT claims)")
+	}
+	if c.SchemaRegistry.URL != "" && c.SchemaRegistry.Dir != "" {
+		return errors.New("config: SCHEMA_REGISTRY_URL and SCHEMA_DIR are mutually exclusive")
+	}
+	if c.Cache.Enabled && (c.Cache.Size <= 0 || c.Cache.TTL <= 0) {
+		return errors.New("config: CACHE_SIZE and CACHE_TTL must be positive when CACHE_ENABLED")
+	}
+	switch c.Tenancy.Mode {
+	case "none", "index", "filter":
+	default:
+		return errors.New("config: TENANCY_MODE must be none, index or filter")
+	}
+	if c.Auth.Enabled && c.Tenancy.Mode != "none" && c.Tenancy.Claim == "" {
+		return errors.New("config: TENANT_CLAIM is required when AUTH_ENABLED and TENANCY_MODE is index or filter")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("config: OTEL_TRACES_SAMPLER_ARG must be within [0, 1]")
 	}
 	return nil
 }
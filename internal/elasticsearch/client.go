@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/base64"
@@ -8,18 +9,33 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/psds-microservice/search-service/internal/query"
 )
 
-// Client is a simple Elasticsearch HTTP client
+// Client is a simple Elasticsearch HTTP client. It also speaks to OpenSearch 2.x: see Flavor.
 type Client struct {
 	baseURL string
 	http    *http.Client
+
+	flavorMu sync.Mutex
+	flavor   Flavor // FlavorAuto until detected
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithFlavor pins the backend flavor instead of detecting it from GET /.
+func WithFlavor(f Flavor) ClientOption {
+	return func(c *Client) { c.flavor = f }
 }
 
 // NewClient creates a new Elasticsearch client. skipTLSVerify disables TLS cert verification (dev only).
-// username/password enable HTTP Basic auth when username is non-empty.
-func NewClient(baseURL string, skipTLSVerify bool, username, password string) *Client {
+// username/password enable HTTP Basic auth when username is non-empty (also used by the OpenSearch security plugin).
+func NewClient(baseURL string, skipTLSVerify bool, username, password string, opts ...ClientOption) *Client {
 	var transport http.RoundTripper = http.DefaultTransport
 	if skipTLSVerify {
 		t := http.DefaultTransport.(*http.Transport).Clone()
@@ -29,10 +45,15 @@ func NewClient(baseURL string, skipTLSVerify bool, username, password string) *C
 	if username != "" {
 		transport = &basicAuthTransport{base: transport, username: username, password: password}
 	}
-	return &Client{
+	c := &Client{
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		http:    &http.Client{Transport: transport},
+		flavor:  FlavorAuto,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 type basicAuthTransport struct {
@@ -47,48 +68,200 @@ func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error
 	return t.base.RoundTrip(req)
 }
 
-// IndexDocument indexes a document in Elasticsearch
-func (c *Client) IndexDocument(ctx context.Context, index, id string, doc interface{}) error {
+// GetDocument читает документ по id. Отсутствие документа — не ошибка, отсутствие индекса — ошибка.
+func (c *Client) GetDocument(ctx context.Context, index, id string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/%s/_doc/%s", c.baseURL, index, id)
-	body, err := json.Marshal(doc)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("marshal document: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return fmt.Errorf("execute request: %w", err)
+		return nil, fmt.Errorf("execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		// 404 без объекта error — документа нет; с ним (index_not_found_exception) — ошибка
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		var missing struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(body, &missing) == nil && missing.Error == nil {
+			return nil, nil
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil, responseError(resp)
+	}
 	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("elasticsearch error: %s - %s", resp.Status, string(bodyBytes))
+		return nil, responseError(resp)
+	}
+	var result struct {
+		Found  bool                   `json:"found"`
+		Source map[string]interface{} `json:"_source"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if !result.Found {
+		return nil, nil
 	}
+	return result.Source, nil
+}
 
-	return nil
+// retryOnConflict — сколько раз _update повторяется, если документ изменился между чтением и записью.
+const retryOnConflict = 3
+
+// updateScript сливает params.doc с документом и дописывает params.defaults, которых в нём ещё нет.
+// С params.event_at запись, которая старше уже применённого события, не выполняется (noop); с params.expect —
+// запись, вычисленная по другим значениям полей документа (noop).
+const updateScript = `boolean skip = params.event_at != null && ctx._source.event_at != null && ctx._source.event_at > params.event_at;
+if (!skip && params.expect != null) { for (entry in params.expect.entrySet()) { if (ctx._source[entry.getKey()] != entry.getValue()) { skip = true; } } }
+if (skip) { ctx.op = 'noop'; } else {
+for (entry in params.doc.entrySet()) { ctx._source[entry.getKey()] = entry.getValue(); }
+for (entry in params.defaults.entrySet()) { if (ctx._source[entry.getKey()] == null) { ctx._source[entry.getKey()] = entry.getValue(); } }
+if (params.event_at != null) { ctx._source.event_at = params.event_at; }
+}`
+
+// UpdateDocument сливает doc с существующим документом (частичное обновление) или создаёт новый.
+// Без opts — doc_as_upsert, иначе painless-скрипт: проверки порядка и Expect и запись выполняются атомарно
+// на стороне Elasticsearch; новый документ создаётся из doc, defaults и event_at.
+func (c *Client) UpdateDocument(ctx context.Context, index, id string, doc interface{}, opts *UpdateOptions) error {
+	url := fmt.Sprintf("%s/%s/_update/%s?retry_on_conflict=%d", c.baseURL, index, id, retryOnConflict)
+	if opts == nil || (len(opts.Defaults) == 0 && opts.EventAt.IsZero() && len(opts.Expect) == 0) {
+		body := map[string]interface{}{"doc": doc, "doc_as_upsert": true}
+		return c.do(ctx, http.MethodPost, url, body, nil)
+	}
+	fields, err := ToSource(doc)
+	if err != nil {
+		return err
+	}
+	upsert := map[string]interface{}{}
+	if err := opts.Apply(upsert, fields); err != nil {
+		return err
+	}
+	defaults := opts.Defaults
+	if defaults == nil {
+		defaults = map[string]interface{}{}
+	}
+	params := map[string]interface{}{"doc": fields, "defaults": defaults}
+	if !opts.EventAt.IsZero() {
+		params["event_at"] = opts.EventAt.UnixMilli()
+	}
+	if len(opts.Expect) > 0 {
+		// при noop ответ вернёт event_at документа: по нему ErrStale отличается от ErrConflict
+		url += "&_source_includes=" + FieldEventAt
+		params["expect"] = opts.Expect
+	}
+	body := map[string]interface{}{
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": updateScript,
+			"params": params,
+		},
+		"upsert": upsert,
+	}
+	var result struct {
+		Result string `json:"result"`
+		Get    struct {
+			Source map[string]interface{} `json:"_source"`
+		} `json:"get"`
+	}
+	if err := c.do(ctx, http.MethodPost, url, body, &result); err != nil {
+		return err
+	}
+	if result.Result != "noop" {
+		return nil
+	}
+	if len(opts.Expect) > 0 && !opts.Stale(result.Get.Source) {
+		return ErrConflict
+	}
+	return ErrStale
+}
+
+// upsertNestedScript заменяет элемент nested-массива с тем же ключом или добавляет item в конец.
+const upsertNestedScript = `if (!(ctx._source[params.path] instanceof List)) { ctx._source[params.path] = []; }
+def items = ctx._source[params.path];
+boolean found = false;
+for (int i = 0; i < items.size(); i++) {
+  if (items[i][params.key] == params.item[params.key]) { items.set(i, params.item); found = true; break; }
 }
+if (!found) { items.add(params.item); }`
 
-// Search performs a search query
-func (c *Client) Search(ctx context.Context, index string, query map[string]interface{}, limit int, offset int) (*SearchResponse, error) {
-	searchQuery := map[string]interface{}{
-		"size":  limit,
-		"from":  offset,
-		"query": query,
+// UpsertNested обновляет один элемент nested-массива painless-скриптом: параллельные записи других
+// элементов не затирают друг друга (конфликты версий повторяет Elasticsearch).
+func (c *Client) UpsertNested(ctx context.Context, index, id, path, key string, item, doc map[string]interface{}) error {
+	upsert := make(map[string]interface{}, len(doc)+1)
+	for k, v := range doc {
+		upsert[k] = v
+	}
+	upsert[path] = []interface{}{item}
+	url := fmt.Sprintf("%s/%s/_update/%s?retry_on_conflict=%d", c.baseURL, index, id, retryOnConflict)
+	body := map[string]interface{}{
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": upsertNestedScript,
+			"params": map[string]interface{}{"path": path, "key": key, "item": item},
+		},
+		"upsert": upsert,
 	}
+	return c.do(ctx, http.MethodPost, url, body, nil)
+}
+
+// setFieldsScript записывает params.fields в поля верхнего уровня каждого совпавшего документа;
+// документ, в котором все поля уже такие, не переписывается (noop).
+const setFieldsScript = `boolean changed = false;
+for (entry in params.fields.entrySet()) { if (ctx._source[entry.getKey()] != entry.getValue()) { ctx._source[entry.getKey()] = entry.getValue(); changed = true; } }
+if (!changed) { ctx.op = 'noop'; }`
+
+// UpdateByQuery выполняет _update_by_query painless-скриптом с conflicts=proceed: документы, изменённые
+// во время запроса, пропускаются, и запрос повторяется (до retryOnConflict раз) — уже обновлённые при
+// повторе не переписываются. Если конфликты остались, возвращается ошибка с числом обновлённых.
+func (c *Client) UpdateByQuery(ctx context.Context, index string, q query.Query, fields map[string]interface{}) (int64, error) {
+	url := fmt.Sprintf("%s/%s/_update_by_query?conflicts=proceed", c.baseURL, index)
+	body := map[string]interface{}{
+		"query": q,
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": setFieldsScript,
+			"params": map[string]interface{}{"fields": fields},
+		},
+	}
+	var updated int64
+	for attempt := 0; ; attempt++ {
+		var result struct {
+			Updated          int64             `json:"updated"`
+			VersionConflicts int64             `json:"version_conflicts"`
+			Failures         []json.RawMessage `json:"failures"`
+		}
+		if err := c.do(ctx, http.MethodPost, url, body, &result); err != nil {
+			return updated, err
+		}
+		updated += result.Updated
+		if len(result.Failures) > 0 {
+			return updated, fmt.Errorf("update by query: %d failures, first: %s", len(result.Failures), result.Failures[0])
+		}
+		if result.VersionConflicts == 0 {
+			return updated, nil
+		}
+		if attempt == retryOnConflict {
+			return updated, fmt.Errorf("update by query: %d documents not updated: version conflicts", result.VersionConflicts)
+		}
+	}
+}
 
+// Search performs a search query. Profile/Explain on req enable query profiling and per-hit
+// score explanations; they are expensive and meant for debugging single queries.
+func (c *Client) Search(ctx context.Context, index string, searchQuery *query.Search) (*SearchResponse, error) {
 	body, err := json.Marshal(searchQuery)
 	if err != nil {
 		return nil, fmt.Errorf("marshal query: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/%s/_search", c.baseURL, index)
+	if searchQuery.PIT != nil {
+		url = c.baseURL + "/_search" // индексы задаёт point in time
+	}
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(body)))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
@@ -102,8 +275,7 @@ func (c *Client) Search(ctx context.Context, index string, query map[string]inte
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("elasticsearch error: %s - %s", resp.Status, string(bodyBytes))
+		return nil, responseError(resp)
 	}
 
 	var result SearchResponse
@@ -115,7 +287,7 @@ func (c *Client) Search(ctx context.Context, index string, query map[string]inte
 }
 
 // EnsureIndex creates an index if it doesn't exist
-func (c *Client) EnsureIndex(ctx context.Context, index string, mapping map[string]interface{}) error {
+func (c *Client) EnsureIndex(ctx context.Context, index string, mapping *query.Mapping) error {
 	url := fmt.Sprintf("%s/%s", c.baseURL, index)
 
 	// Check if index exists
@@ -129,17 +301,21 @@ func (c *Client) EnsureIndex(ctx context.Context, index string, mapping map[stri
 	}
 	resp.Body.Close()
 
-	if resp.StatusCode == 200 {
-		return nil // Index already exists
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return c.putMapping(ctx, index, mapping) // Index already exists: add new fields
+	case http.StatusNotFound:
+	default:
+		// например, 401/403 от security plugin: создание индекса упадёт или скроет настоящую причину
+		return responseError(resp)
 	}
 
 	// Create index with mapping
-	if mapping == nil {
-		mapping = make(map[string]interface{})
-	}
-	body, err := json.Marshal(mapping)
-	if err != nil {
-		return fmt.Errorf("marshal mapping: %w", err)
+	body := []byte("{}")
+	if mapping != nil {
+		if body, err = json.Marshal(mapping); err != nil {
+			return fmt.Errorf("marshal mapping: %w", err)
+		}
 	}
 
 	req, err = http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(body)))
@@ -155,22 +331,137 @@ func (c *Client) EnsureIndex(ctx context.Context, index string, mapping map[stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("elasticsearch error: %s - %s", resp.Status, string(bodyBytes))
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+// do отправляет JSON-запрос и декодирует JSON-ответ в out (если out не nil).
+func (c *Client) do(ctx context.Context, method, url string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
 	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
 	return nil
 }
 
+// maxErrorBody ограничивает чтение тела ответа с ошибкой, maxErrorReason — длину причины в тексте ошибки.
+const (
+	maxErrorBody   = 64 << 10
+	maxErrorReason = 512
+)
+
+// ResponseError — неуспешный ответ Elasticsearch: HTTP-статус и тип/причина корневой ошибки.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Type       string // пусто, если тело ответа — не JSON-ошибка Elasticsearch
+	Reason     string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch error: %s - %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch error: %s - %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// responseError строит ошибку по неуспешному ответу Elasticsearch. В ошибку попадают только тип и причина
+// корневой ошибки, а не всё (возможно, огромное) тело — ошибки остаются читаемыми в логах.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var parsed struct {
+		Error struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	e := &ResponseError{StatusCode: resp.StatusCode, Status: resp.Status, Reason: string(body)}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Type != "" {
+		e.Type, e.Reason = parsed.Error.Type, parsed.Error.Reason
+	}
+	if len(e.Reason) > maxErrorReason {
+		e.Reason = e.Reason[:maxErrorReason] + "..."
+	}
+	return e
+}
+
 // SearchResponse represents Elasticsearch search response
 type SearchResponse struct {
+	Took         int64                      `json:"took"`              // время выполнения на сервере, мс
+	PITID        string                     `json:"pit_id,omitempty"`  // обновлённый id point in time (поиск по PIT)
+	Profile      json.RawMessage            `json:"profile,omitempty"` // только при "profile": true в запросе
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+	Hits         struct {
+		Total TotalHits   `json:"total"`
+		Hits  []SearchHit `json:"hits"`
+	} `json:"hits"`
+}
+
+// TotalHits — hits.total. Elasticsearch 7+ и OpenSearch возвращают {"value": n, "relation": "eq"|"gte"},
+// Elasticsearch 6 и запросы с rest_total_hits_as_int=true — просто число.
+type TotalHits struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation,omitempty"`
+}
+
+func (t *TotalHits) UnmarshalJSON(data []byte) error {
+	if n, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64); err == nil {
+		*t = TotalHits{Value: n, Relation: "eq"}
+		return nil
+	}
+	type plain TotalHits
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// SearchHit — документ в ответе поиска.
+type SearchHit struct {
+	ID          string                 `json:"_id"`
+	Score       float64                `json:"_score"`
+	Source      map[string]interface{} `json:"_source"`
+	Sort        []interface{}          `json:"sort,omitempty"` // значения сортировки для search_after
+	Highlight   map[string][]string    `json:"highlight,omitempty"`
+	Explanation json.RawMessage        `json:"_explanation,omitempty"` // только при "explain": true в запросе
+	Nested      *NestedIdentity        `json:"_nested,omitempty"`      // позиция inner hit в nested-массиве
+	InnerHits   map[string]InnerHits   `json:"inner_hits,omitempty"`   // совпавшие вложенные документы по имени inner_hits
+}
+
+// NestedIdentity — положение inner hit: nested-поле и индекс элемента в массиве.
+type NestedIdentity struct {
+	Field  string `json:"field"`
+	Offset int    `json:"offset"`
+}
+
+// InnerHits — секция inner_hits хита: её _source — вложенный элемент, а не корневой документ.
+type InnerHits struct {
 	Hits struct {
-		Total struct {
-			Value int64 `json:"value"`
-		} `json:"total"`
-		Hits []struct {
-			ID     string                 `json:"_id"`
-			Source map[string]interface{} `json:"_source"`
-		} `json:"hits"`
+		Total TotalHits   `json:"total"`
+		Hits  []SearchHit `json:"hits"`
 	} `json:"hits"`
 }
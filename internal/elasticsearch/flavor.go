@@ -0,0 +1,133 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Flavor — бэкенд поиска за HTTP API. У обоих общие Query DSL и API документов; различаются пути
+// и тела запросов point in time и форма некоторых ответов (hits.total, id снимка).
+type Flavor string
+
+const (
+	FlavorAuto          Flavor = "auto" // определить по GET / при первом обращении
+	FlavorElasticsearch Flavor = "elasticsearch"
+	FlavorOpenSearch    Flavor = "opensearch"
+)
+
+// ParseFlavor проверяет имя бэкенда из конфигурации.
+func ParseFlavor(s string) (Flavor, error) {
+	switch f := Flavor(strings.ToLower(s)); f {
+	case "", FlavorAuto:
+		return FlavorAuto, nil
+	case FlavorElasticsearch, FlavorOpenSearch:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown flavor %q: want auto, elasticsearch or opensearch", s)
+}
+
+// Flavor возвращает тип бэкенда; при auto определяет его по корневому эндпоинту.
+// Неудачное определение не кэшируется — следующий вызов повторит попытку.
+func (c *Client) Flavor(ctx context.Context) (Flavor, error) {
+	c.flavorMu.Lock()
+	defer c.flavorMu.Unlock()
+	if c.flavor != FlavorAuto {
+		return c.flavor, nil
+	}
+	f, err := c.detectFlavor(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.flavor = f
+	return f, nil
+}
+
+// detectFlavor читает GET /: OpenSearch возвращает version.distribution = "opensearch",
+// у Elasticsearch поля distribution нет.
+func (c *Client) detectFlavor(ctx context.Context) (Flavor, error) {
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+			Number       string `json:"number"`
+		} `json:"version"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.baseURL+"/", nil, &info); err != nil {
+		return "", fmt.Errorf("detect flavor: %w", err)
+	}
+	if strings.EqualFold(info.Version.Distribution, "opensearch") {
+		return FlavorOpenSearch, nil
+	}
+	return FlavorElasticsearch, nil
+}
+
+// OpenPIT открывает point in time на index для согласованного постраничного чтения через search_after.
+// Elasticsearch: POST /{index}/_pit → {"id"}; OpenSearch 2.x: POST /{index}/_search/point_in_time → {"pit_id"}.
+func (c *Client) OpenPIT(ctx context.Context, index string, keepAlive time.Duration) (string, error) {
+	flavor, err := c.Flavor(ctx)
+	if err != nil {
+		return "", err
+	}
+	path := "/_pit"
+	if flavor == FlavorOpenSearch {
+		path = "/_search/point_in_time"
+	}
+	url := fmt.Sprintf("%s/%s%s?keep_alive=%s", c.baseURL, index, path, keepAliveParam(keepAlive))
+	var resp struct {
+		ID    string `json:"id"`
+		PITID string `json:"pit_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, url, nil, &resp); err != nil {
+		return "", fmt.Errorf("open point in time: %w", err)
+	}
+	if resp.PITID != "" {
+		return resp.PITID, nil
+	}
+	return resp.ID, nil
+}
+
+// ClosePIT освобождает point in time.
+// Elasticsearch: DELETE /_pit {"id"}; OpenSearch 2.x: DELETE /_search/point_in_time {"pit_id": [...]}.
+func (c *Client) ClosePIT(ctx context.Context, id string) error {
+	flavor, err := c.Flavor(ctx)
+	if err != nil {
+		return err
+	}
+	if flavor == FlavorOpenSearch {
+		var resp struct {
+			Pits []struct {
+				Succeeded bool `json:"succeeded"`
+			} `json:"pits"`
+		}
+		body := map[string][]string{"pit_id": {id}}
+		if err := c.do(ctx, http.MethodDelete, c.baseURL+"/_search/point_in_time", body, &resp); err != nil {
+			return fmt.Errorf("close point in time: %w", err)
+		}
+		if len(resp.Pits) == 0 || !resp.Pits[0].Succeeded {
+			return errors.New("close point in time: not freed")
+		}
+		return nil
+	}
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := c.do(ctx, http.MethodDelete, c.baseURL+"/_pit", map[string]string{"id": id}, &resp); err != nil {
+		return fmt.Errorf("close point in time: %w", err)
+	}
+	if !resp.Succeeded {
+		return errors.New("close point in time: not freed")
+	}
+	return nil
+}
+
+// keepAliveParam записывает длительность в понятном обоим бэкендам виде (целые секунды, не меньше 1s).
+func keepAliveParam(d time.Duration) string {
+	secs := int64(d / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return fmt.Sprintf("%ds", secs)
+}
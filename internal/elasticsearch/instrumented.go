@@ -0,0 +1,104 @@
+package elasticsearch
+
+import (
+	"context"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/metrics"
+	"github.com/psds-microservice/search-service/internal/query"
+	"github.com/psds-microservice/search-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumented — обёртка IndexSearcher: span и длительность вызова по операции и индексу.
+type Instrumented struct {
+	next IndexSearcher
+}
+
+// NewInstrumented возвращает IndexSearcher, который пишет метрики и трейсы каждого вызова next.
+func NewInstrumented(next IndexSearcher) *Instrumented {
+	return &Instrumented{next: next}
+}
+
+var _ IndexSearcher = (*Instrumented)(nil)
+
+// start открывает клиентский span вызова Elasticsearch.
+func start(ctx context.Context, operation, index string, attrs ...attribute.KeyValue) (context.Context, trace.Span, time.Time) {
+	attrs = append(attrs,
+		semconv.DBSystemNameElasticsearch,
+		semconv.DBOperationName(operation),
+		semconv.DBCollectionName(index),
+	)
+	ctx, span := tracing.Tracer().Start(ctx, "elasticsearch."+operation+" "+index,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, span, time.Now()
+}
+
+// finish завершает span и записывает метрику длительности.
+func finish(span trace.Span, operation, index string, began time.Time, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	metrics.ESDuration.WithLabelValues(operation, index, metrics.Outcome(err)).Observe(time.Since(began).Seconds())
+}
+
+func (i *Instrumented) Search(ctx context.Context, index string, req *query.Search) (*SearchResponse, error) {
+	operation := "search"
+	if req.Profile || req.Explain {
+		operation = "explain"
+	}
+	var attrs []attribute.KeyValue
+	if req.Query != nil {
+		attrs = append(attrs, attribute.String("db.query.type", req.Query.Kind()))
+	}
+	ctx, span, began := start(ctx, operation, index, attrs...)
+	resp, err := i.next.Search(ctx, index, req)
+	if resp != nil {
+		span.SetAttributes(attribute.Int64("db.response.hits_total", resp.Hits.Total.Value))
+	}
+	finish(span, operation, index, began, err)
+	return resp, err
+}
+
+func (i *Instrumented) GetDocument(ctx context.Context, index, id string) (map[string]interface{}, error) {
+	ctx, span, began := start(ctx, "get", index, attribute.String("db.document.id", id))
+	doc, err := i.next.GetDocument(ctx, index, id)
+	finish(span, "get", index, began, err)
+	return doc, err
+}
+
+func (i *Instrumented) UpdateDocument(ctx context.Context, index, id string, doc interface{}, opts *UpdateOptions) error {
+	ctx, span, began := start(ctx, "update", index, attribute.String("db.document.id", id))
+	err := i.next.UpdateDocument(ctx, index, id, doc, opts)
+	finish(span, "update", index, began, err)
+	return err
+}
+
+func (i *Instrumented) UpsertNested(ctx context.Context, index, id, path, key string, item, doc map[string]interface{}) error {
+	ctx, span, began := start(ctx, "upsert_nested", index, attribute.String("db.document.id", id), attribute.String("db.nested.path", path))
+	err := i.next.UpsertNested(ctx, index, id, path, key, item, doc)
+	finish(span, "upsert_nested", index, began, err)
+	return err
+}
+
+func (i *Instrumented) UpdateByQuery(ctx context.Context, index string, q query.Query, fields map[string]interface{}) (int64, error) {
+	ctx, span, began := start(ctx, "update_by_query", index, attribute.String("db.query.type", q.Kind()))
+	n, err := i.next.UpdateByQuery(ctx, index, q, fields)
+	span.SetAttributes(attribute.Int64("db.response.updated", n))
+	finish(span, "update_by_query", index, began, err)
+	return n, err
+}
+
+func (i *Instrumented) EnsureIndex(ctx context.Context, index string, mapping *query.Mapping) error {
+	ctx, span, began := start(ctx, "ensure_index", index)
+	err := i.next.EnsureIndex(ctx, index, mapping)
+	finish(span, "ensure_index", index, began, err)
+	return err
+}
@@ -1,12 +1,30 @@
 package elasticsearch
 
-import "context"
+import (
+	"context"
+
+	"github.com/psds-microservice/search-service/internal/query"
+)
 
 // IndexSearcher abstracts Elasticsearch search/index operations for testing and swapping implementations.
 type IndexSearcher interface {
-	Search(ctx context.Context, index string, query map[string]interface{}, limit, offset int) (*SearchResponse, error)
-	IndexDocument(ctx context.Context, index, id string, doc interface{}) error
-	EnsureIndex(ctx context.Context, index string, mapping map[string]interface{}) error
+	Search(ctx context.Context, index string, req *query.Search) (*SearchResponse, error)
+	// GetDocument возвращает _source документа id (nil, если документа нет). В отличие от Search видит
+	// запись сразу (realtime get), поэтому подходит для обновлений «прочитать — изменить — записать».
+	GetDocument(ctx context.Context, index, id string) (map[string]interface{}, error)
+	// UpdateDocument сливает поля doc верхнего уровня с документом id, создавая его при отсутствии.
+	// Поля, которых нет в doc, сохраняются (например, nested-массивы, которые ведёт UpsertNested).
+	// opts задаёт поля по умолчанию, порядок записей по времени события и ожидаемые значения полей (см. UpdateOptions).
+	UpdateDocument(ctx context.Context, index, id string, doc interface{}, opts *UpdateOptions) error
+	// UpsertNested кладёт item в массив path (поле верхнего уровня) документа id: заменяет элемент
+	// с тем же значением поля key или добавляет в конец. Отсутствующий документ создаётся из doc
+	// с path = [item].
+	UpsertNested(ctx context.Context, index, id, path, key string, item, doc map[string]interface{}) error
+	// UpdateByQuery записывает поля верхнего уровня во все документы, подходящие под q, и возвращает
+	// число обновлённых. Затрагиваются только документы, уже видимые поиску; документы, изменённые
+	// параллельной записью во время обновления, обновляются повторно.
+	UpdateByQuery(ctx context.Context, index string, q query.Query, fields map[string]interface{}) (int64, error)
+	EnsureIndex(ctx context.Context, index string, mapping *query.Mapping) error
 }
 
 // Ensure *Client implements IndexSearcher at compile time.
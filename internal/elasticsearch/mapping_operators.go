@@ -1,21 +1,26 @@
 package elasticsearch
 
+import "github.com/psds-microservice/search-service/internal/query"
+
 // OperatorsMapping возвращает маппинг индекса операторов для Elasticsearch.
-// Поля: user_id, region, role (keyword), display_name (text + keyword subfield).
-func OperatorsMapping() map[string]interface{} {
-	return map[string]interface{}{
-		"mappings": map[string]interface{}{
-			"properties": map[string]interface{}{
-				"user_id": map[string]interface{}{"type": "keyword"},
-				"display_name": map[string]interface{}{
-					"type": "text",
-					"fields": map[string]interface{}{
-						"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
-					},
+// Поля: user_id, region, role, tenant_id (keyword), display_name (text + keyword subfield),
+// created_at/updated_at (date), event_at (long, см. FieldEventAt).
+func OperatorsMapping() *query.Mapping {
+	return &query.Mapping{
+		Properties: map[string]query.Property{
+			"user_id": {Type: query.TypeKeyword},
+			"display_name": {
+				Type: query.TypeText,
+				Fields: map[string]query.Property{
+					"keyword": {Type: query.TypeKeyword, IgnoreAbove: 256},
 				},
-				"region": map[string]interface{}{"type": "keyword"},
-				"role":   map[string]interface{}{"type": "keyword"},
 			},
+			"region":     {Type: query.TypeKeyword},
+			"role":       {Type: query.TypeKeyword},
+			"tenant_id":  {Type: query.TypeKeyword},
+			"created_at": {Type: query.TypeDate},
+			"updated_at": {Type: query.TypeDate},
+			FieldEventAt: {Type: query.TypeLong},
 		},
 	}
 }
@@ -1,15 +1,33 @@
 package elasticsearch
 
+import "github.com/psds-microservice/search-service/internal/query"
+
 // SessionsMapping возвращает маппинг индекса сессий для Elasticsearch.
-// Поля: session_id, client_id, pin, status (keyword).
-func SessionsMapping() map[string]interface{} {
-	return map[string]interface{}{
-		"mappings": map[string]interface{}{
-			"properties": map[string]interface{}{
-				"session_id": map[string]interface{}{"type": "keyword"},
-				"client_id":  map[string]interface{}{"type": "keyword"},
-				"pin":        map[string]interface{}{"type": "keyword"},
-				"status":     map[string]interface{}{"type": "keyword"},
+// Поля: session_id, client_id, pin, status, tenant_id, operator_ids (keyword), created_at/updated_at/ended_at (date),
+// participant_count/active_participant_count/event_at (long), participants (nested: operator_id keyword, joined_at/left_at date).
+func SessionsMapping() *query.Mapping {
+	return &query.Mapping{
+		Properties: map[string]query.Property{
+			"session_id": {Type: query.TypeKeyword},
+			"client_id":  {Type: query.TypeKeyword},
+			"pin":        {Type: query.TypeKeyword},
+			"status":     {Type: query.TypeKeyword},
+			"tenant_id":  {Type: query.TypeKeyword},
+			"created_at": {Type: query.TypeDate},
+			"updated_at": {Type: query.TypeDate},
+			FieldEventAt: {Type: query.TypeLong},
+			"ended_at":   {Type: query.TypeDate},
+
+			"operator_ids":             {Type: query.TypeKeyword},
+			"participant_count":        {Type: query.TypeLong},
+			"active_participant_count": {Type: query.TypeLong},
+			"participants": {
+				Type: query.TypeNested,
+				Properties: map[string]query.Property{
+					"operator_id": {Type: query.TypeKeyword},
+					"joined_at":   {Type: query.TypeDate},
+					"left_at":     {Type: query.TypeDate},
+				},
 			},
 		},
 	}
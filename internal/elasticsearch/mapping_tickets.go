@@ -1,23 +1,47 @@
 package elasticsearch
 
+import "github.com/psds-microservice/search-service/internal/query"
+
 // TicketsMapping возвращает маппинг индекса тикетов для Elasticsearch.
-// Поля: ticket_id (long), session_id/client_id/operator_id/status (keyword), subject (text+keyword), notes (text).
-func TicketsMapping() map[string]interface{} {
-	return map[string]interface{}{
-		"mappings": map[string]interface{}{
-			"properties": map[string]interface{}{
-				"ticket_id":   map[string]interface{}{"type": "long"},
-				"session_id":  map[string]interface{}{"type": "keyword"},
-				"client_id":   map[string]interface{}{"type": "keyword"},
-				"operator_id": map[string]interface{}{"type": "keyword"},
-				"subject": map[string]interface{}{
-					"type": "text",
-					"fields": map[string]interface{}{
-						"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 512},
-					},
+// Поля: ticket_id (long), session_id/client_id/operator_id/status/tenant_id (keyword), subject (text+keyword), notes (text),
+// created_at/updated_at (date), event_at (long), копии атрибутов оператора и сессии: operator_display_name (text+keyword),
+// operator_region/session_status/session_pin (keyword), comments (nested: comment_id/author keyword, text text, created_at date).
+func TicketsMapping() *query.Mapping {
+	return &query.Mapping{
+		Properties: map[string]query.Property{
+			"ticket_id":   {Type: query.TypeLong},
+			"session_id":  {Type: query.TypeKeyword},
+			"client_id":   {Type: query.TypeKeyword},
+			"operator_id": {Type: query.TypeKeyword},
+			"subject": {
+				Type: query.TypeText,
+				Fields: map[string]query.Property{
+					"keyword": {Type: query.TypeKeyword, IgnoreAbove: 512},
+				},
+			},
+			"notes":      {Type: query.TypeText},
+			"status":     {Type: query.TypeKeyword},
+			"tenant_id":  {Type: query.TypeKeyword},
+			"created_at": {Type: query.TypeDate},
+			"updated_at": {Type: query.TypeDate},
+			FieldEventAt: {Type: query.TypeLong},
+			"operator_display_name": {
+				Type: query.TypeText,
+				Fields: map[string]query.Property{
+					"keyword": {Type: query.TypeKeyword, IgnoreAbove: 256},
+				},
+			},
+			"operator_region": {Type: query.TypeKeyword},
+			"session_status":  {Type: query.TypeKeyword},
+			"session_pin":     {Type: query.TypeKeyword},
+			"comments": {
+				Type: query.TypeNested,
+				Properties: map[string]query.Property{
+					"comment_id": {Type: query.TypeKeyword},
+					"author":     {Type: query.TypeKeyword},
+					"text":       {Type: query.TypeText},
+					"created_at": {Type: query.TypeDate},
 				},
-				"notes":  map[string]interface{}{"type": "text"},
-				"status": map[string]interface{}{"type": "keyword"},
 			},
 		},
 	}
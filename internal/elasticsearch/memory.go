@@ -0,0 +1,649 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// Memory — IndexSearcher в памяти процесса для локальной разработки и тестов. Вычисляет то подмножество
+// Query DSL, которое генерирует сервис (match_all, term, terms, match, multi_match, range, exists,
+// nested, bool), сортировку и пагинацию. Текстовые поля анализируются приведением к нижнему регистру
+// и разбиением по небуквенно-цифровым символам; score — грубое приближение релевантности ES, достаточное для порядка.
+type Memory struct {
+	mu      sync.RWMutex
+	indices map[string]*memIndex
+}
+
+type memIndex struct {
+	mapping *query.Mapping
+	docs    map[string]*memDoc
+	seq     int
+}
+
+type memDoc struct {
+	id     string
+	seq    int // порядок вставки: при равенстве — как порядок в индексе ES
+	source map[string]interface{}
+}
+
+// NewMemory создаёт пустое хранилище в памяти.
+func NewMemory() *Memory {
+	return &Memory{indices: map[string]*memIndex{}}
+}
+
+var _ IndexSearcher = (*Memory)(nil)
+
+func (m *Memory) EnsureIndex(ctx context.Context, index string, mapping *query.Mapping) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.indices[index]; !ok {
+		m.indices[index] = &memIndex{mapping: mapping, docs: map[string]*memDoc{}}
+	}
+	return nil
+}
+
+// GetDocument возвращает копию сохранённого source (nil, если документа нет).
+func (m *Memory) GetDocument(ctx context.Context, index, id string) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	idx, ok := m.indices[index]
+	if !ok {
+		return nil, fmt.Errorf("elasticsearch error: 404 Not Found - index_not_found_exception: no such index [%s]", index)
+	}
+	doc, ok := idx.docs[id]
+	if !ok {
+		return nil, nil
+	}
+	return copySource(doc.source), nil // вызывающий может менять вложенные значения
+}
+
+// UpdateDocument сливает поля верхнего уровня doc с сохранённым документом (doc_as_upsert)
+// по правилам opts (UpdateOptions.Apply); проверка порядка и запись — под одной блокировкой.
+func (m *Memory) UpdateDocument(ctx context.Context, index, id string, doc interface{}, opts *UpdateOptions) error {
+	source, err := ToSource(doc)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var applyErr error
+	m.put(index, id, func(old map[string]interface{}) map[string]interface{} {
+		applyErr = opts.Apply(old, source)
+		return old
+	})
+	return applyErr
+}
+
+// UpsertNested заменяет или дописывает item в массив path, как painless-скрипт Client.UpsertNested.
+func (m *Memory) UpsertNested(ctx context.Context, index, id, path, key string, item, doc map[string]interface{}) error {
+	obj, err := ToSource(item)
+	if err != nil {
+		return err
+	}
+	base, err := ToSource(doc)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(index, id, func(old map[string]interface{}) map[string]interface{} {
+		if len(old) == 0 {
+			if base == nil {
+				base = map[string]interface{}{}
+			}
+			base[path] = []interface{}{obj}
+			return base
+		}
+		items, _ := old[path].([]interface{})
+		for i, existing := range items {
+			if e, ok := existing.(map[string]interface{}); ok && compareValues(e[key], obj[key]) == 0 {
+				items[i] = obj
+				return old
+			}
+		}
+		old[path] = append(items, obj)
+		return old
+	})
+	return nil
+}
+
+// UpdateByQuery сливает fields с каждым документом, подходящим под q.
+func (m *Memory) UpdateByQuery(ctx context.Context, index string, q query.Query, fields map[string]interface{}) (int64, error) {
+	set, err := ToSource(fields)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, ok := m.indices[index]
+	if !ok {
+		return 0, fmt.Errorf("elasticsearch error: 404 Not Found - index_not_found_exception: no such index [%s]", index)
+	}
+	var ids []string
+	for id, doc := range idx.docs {
+		ok, _, err := idx.eval(q, doc.source)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		m.put(index, id, func(old map[string]interface{}) map[string]interface{} {
+			for k, v := range set {
+				old[k] = copyValue(v) // у каждого документа своя копия вложенных значений
+			}
+			return old
+		})
+	}
+	return int64(len(ids)), nil
+}
+
+// put заменяет документ id на update(текущий source); новый документ начинается с пустого source.
+// update получает глубокую копию: сохранённые документы не меняются на месте, поэтому источники,
+// уже отданные из Search и GetDocument, не видят последующих записей. Вызывающий держит m.mu.
+func (m *Memory) put(index, id string, update func(map[string]interface{}) map[string]interface{}) {
+	idx, ok := m.indices[index]
+	if !ok {
+		idx = &memIndex{docs: map[string]*memDoc{}}
+		m.indices[index] = idx
+	}
+	current := map[string]interface{}{}
+	seq := idx.seq
+	if existing, ok := idx.docs[id]; ok {
+		seq = existing.seq
+		current = copySource(existing.source)
+	} else {
+		idx.seq++
+	}
+	idx.docs[id] = &memDoc{id: id, seq: seq, source: update(current)}
+}
+
+// copySource — глубокая копия JSON-документа (объекты и массивы; скаляры неизменяемы).
+func copySource(src map[string]interface{}) map[string]interface{} {
+	if src == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copySource(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = copyValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+func (m *Memory) Search(ctx context.Context, index string, req *query.Search) (*SearchResponse, error) {
+	began := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	idx, ok := m.indices[index]
+	if !ok {
+		return nil, fmt.Errorf("elasticsearch error: 404 Not Found - index_not_found_exception: no such index [%s]", index)
+	}
+	q := req.Query
+	if q == nil {
+		q = query.MatchAll()
+	}
+
+	type scored struct {
+		doc   *memDoc
+		score float64
+	}
+	var matched []scored
+	for _, doc := range idx.docs {
+		ok, score, err := idx.eval(q, doc.source)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, scored{doc: doc, score: score})
+		}
+	}
+
+	sortBy := req.Sort
+	if len(sortBy) == 0 {
+		sortBy = []query.Sort{query.SortBy(query.FieldScore, query.Desc)}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, s := range sortBy {
+			var c int
+			if s.Field == query.FieldScore {
+				c = compareFloat(matched[i].score, matched[j].score)
+			} else {
+				a, b := idx.firstValue(matched[i].doc.source, s.Field), idx.firstValue(matched[j].doc.source, s.Field)
+				if a == nil || b == nil {
+					// отсутствующие значения — в конце независимо от направления, если не задано иное
+					if c = compareMissing(a, b); s.Missing == "_first" {
+						c = -c
+					}
+					if c != 0 {
+						return c < 0
+					}
+					continue
+				}
+				c = compareValues(a, b)
+			}
+			if s.Order == query.Desc || (s.Order == "" && s.Field == query.FieldScore) {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return matched[i].doc.seq < matched[j].doc.seq
+	})
+
+	resp := &SearchResponse{}
+	resp.Hits.Total.Value = int64(len(matched))
+	for i := req.From; i < len(matched) && i < req.From+req.Size; i++ {
+		h := matched[i]
+		hit := SearchHit{ID: h.doc.id, Score: h.score, Source: copySource(h.doc.source)}
+		if inner, err := idx.innerHits(q, h.doc); err != nil {
+			return nil, err
+		} else if len(inner) > 0 {
+			hit.InnerHits = inner
+		}
+		if req.Explain {
+			hit.Explanation, _ = json.Marshal(map[string]interface{}{
+				"value":       h.score,
+				"description": "in-memory score: number of matched scoring terms",
+			})
+		}
+		resp.Hits.Hits = append(resp.Hits.Hits, hit)
+	}
+	if req.Profile {
+		resp.Profile, _ = json.Marshal(map[string]interface{}{"backend": "memory", "scanned": len(idx.docs)})
+	}
+	resp.Took = time.Since(began).Milliseconds()
+	return resp, nil
+}
+
+// eval сообщает, подходит ли документ под q, и его вклад в score.
+func (idx *memIndex) eval(q query.Query, src map[string]interface{}) (bool, float64, error) {
+	switch q := q.(type) {
+	case *query.MatchAllQuery:
+		return true, 1, nil
+	case *query.TermQuery:
+		return idx.anyValue(src, q.Field, func(v interface{}) bool { return idx.termEquals(q.Field, v, q.Value) }), 1, nil
+	case *query.TermsQuery:
+		return idx.anyValue(src, q.Field, func(v interface{}) bool {
+			for _, want := range q.Values {
+				if idx.termEquals(q.Field, v, want) {
+					return true
+				}
+			}
+			return false
+		}), 1, nil
+	case *query.MatchQuery:
+		n := idx.matchTokens(src, q.Field, q.Text, q.Operator)
+		return n > 0, float64(n), nil
+	case *query.MultiMatchQuery:
+		best := 0
+		for _, f := range q.Fields {
+			field, _, _ := strings.Cut(f, "^")
+			if n := idx.matchTokens(src, field, q.Text, q.Operator); n > best {
+				best = n
+			}
+		}
+		return best > 0, float64(best), nil
+	case *query.RangeQuery:
+		return idx.anyValue(src, q.Field, func(v interface{}) bool { return inRange(v, q) }), 1, nil
+	case *query.ExistsQuery:
+		return idx.anyValue(src, q.Field, func(v interface{}) bool { return v != nil && v != "" }), 0, nil
+	case *query.NestedQuery:
+		items, _ := lookup(src, q.Path).([]interface{})
+		for _, item := range items {
+			if obj, ok := item.(map[string]interface{}); ok {
+				ok, score, err := idx.eval(q.Query, nestUnder(q.Path, obj))
+				if err != nil || ok {
+					return ok, score, err
+				}
+			}
+		}
+		return false, 0, nil
+	case *query.BoolQuery:
+		return idx.evalBool(q, src)
+	default:
+		return false, 0, fmt.Errorf("memory store: unsupported query %q", q.Kind())
+	}
+}
+
+// innerHits собирает inner_hits nested-запросов из q, которые могут дать совпадение (не под must_not).
+// Подсветка inner hits не поддерживается.
+func (idx *memIndex) innerHits(q query.Query, doc *memDoc) (map[string]InnerHits, error) {
+	out := map[string]InnerHits{}
+	var walk func(q query.Query) error
+	walk = func(q query.Query) error {
+		switch q := q.(type) {
+		case *query.BoolQuery:
+			for _, group := range [][]query.Query{q.Must, q.Filter, q.Should} {
+				for _, c := range group {
+					if err := walk(c); err != nil {
+						return err
+					}
+				}
+			}
+		case *query.NestedQuery:
+			if q.InnerHits == nil {
+				return nil
+			}
+			name := q.InnerHits.Name
+			if name == "" {
+				name = q.Path
+			}
+			var section InnerHits
+			items, _ := lookup(doc.source, q.Path).([]interface{})
+			for i, item := range items {
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				ok, score, err := idx.eval(q.Query, nestUnder(q.Path, obj))
+				if err != nil {
+					return err
+				}
+				if ok {
+					section.Hits.Hits = append(section.Hits.Hits, SearchHit{
+						ID: doc.id, Score: score, Source: copySource(obj), Nested: &NestedIdentity{Field: q.Path, Offset: i},
+					})
+				}
+			}
+			if len(section.Hits.Hits) == 0 {
+				return nil
+			}
+			section.Hits.Total = TotalHits{Value: int64(len(section.Hits.Hits)), Relation: "eq"}
+			sort.SliceStable(section.Hits.Hits, func(i, j int) bool {
+				return section.Hits.Hits[i].Score > section.Hits.Hits[j].Score
+			})
+			size := q.InnerHits.Size
+			if size == 0 {
+				size = 3 // по умолчанию в ES
+			}
+			if len(section.Hits.Hits) > size {
+				section.Hits.Hits = section.Hits.Hits[:size]
+			}
+			out[name] = section
+		}
+		return nil
+	}
+	if err := walk(q); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (idx *memIndex) evalBool(q *query.BoolQuery, src map[string]interface{}) (bool, float64, error) {
+	var score float64
+	for _, c := range q.Must {
+		ok, s, err := idx.eval(c, src)
+		if err != nil || !ok {
+			return false, 0, err
+		}
+		score += s
+	}
+	for _, c := range q.Filter {
+		if ok, _, err := idx.eval(c, src); err != nil || !ok {
+			return false, 0, err
+		}
+	}
+	for _, c := range q.MustNot {
+		if ok, _, err := idx.eval(c, src); err != nil || ok {
+			return false, 0, err
+		}
+	}
+	matchedShould := 0
+	for _, c := range q.Should {
+		ok, s, err := idx.eval(c, src)
+		if err != nil {
+			return false, 0, err
+		}
+		if ok {
+			matchedShould++
+			score += s
+		}
+	}
+	minShould := q.MinimumShouldMatch
+	if minShould == 0 && len(q.Should) > 0 && len(q.Must) == 0 && len(q.Filter) == 0 {
+		minShould = 1 // ES: bool только из should требует хотя бы одного совпадения
+	}
+	return matchedShould >= minShould, score, nil
+}
+
+// resolve переводит поле запроса в путь в source: мульти-поле ("display_name.keyword") читает значение
+// родителя; также сообщает, анализируется ли значение (text без подполя). Поля nested/object
+// ("comments.text") разрешаются через properties родителя.
+func (idx *memIndex) resolve(field string) (path string, analyzed bool) {
+	if idx.mapping == nil {
+		return field, false
+	}
+	props, prefix, rest := idx.mapping.Properties, "", field
+	for {
+		if p, ok := props[rest]; ok {
+			return prefix + rest, p.Type == query.TypeText
+		}
+		parent, sub, ok := strings.Cut(rest, ".")
+		if !ok {
+			return field, false
+		}
+		p, ok := props[parent]
+		if !ok {
+			return field, false
+		}
+		if _, ok := p.Fields[sub]; ok {
+			return prefix + parent, false
+		}
+		props, prefix, rest = p.Properties, prefix+parent+".", sub
+	}
+}
+
+// anyValue применяет pred к значению поля или к каждому элементу, если это массив.
+func (idx *memIndex) anyValue(src map[string]interface{}, field string, pred func(interface{}) bool) bool {
+	path, _ := idx.resolve(field)
+	v := lookup(src, path)
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			if pred(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return v != nil && pred(v)
+}
+
+func (idx *memIndex) firstValue(src map[string]interface{}, field string) interface{} {
+	path, _ := idx.resolve(field)
+	v := lookup(src, path)
+	if items, ok := v.([]interface{}); ok {
+		if len(items) == 0 {
+			return nil
+		}
+		return items[0]
+	}
+	return v
+}
+
+// termEquals сравнивает как term в ES: точное значение для keyword/числовых полей, один токен для text.
+func (idx *memIndex) termEquals(field string, have, want interface{}) bool {
+	if _, analyzed := idx.resolve(field); analyzed {
+		s, _ := have.(string)
+		for _, tok := range tokenize(s) {
+			if tok == fmt.Sprint(want) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareValues(have, want) == 0
+}
+
+// matchTokens возвращает, сколько токенов запроса есть в поле (0, если оператор "and" и какого-то нет).
+func (idx *memIndex) matchTokens(src map[string]interface{}, field, text, operator string) int {
+	path, analyzed := idx.resolve(field)
+	if !analyzed {
+		if idx.anyValue(src, field, func(v interface{}) bool { return fmt.Sprint(v) == text }) {
+			return 1
+		}
+		return 0
+	}
+	have := map[string]bool{}
+	collect := func(v interface{}) {
+		if s, ok := v.(string); ok {
+			for _, tok := range tokenize(s) {
+				have[tok] = true
+			}
+		}
+	}
+	if items, ok := lookup(src, path).([]interface{}); ok {
+		for _, item := range items {
+			collect(item)
+		}
+	} else {
+		collect(lookup(src, path))
+	}
+	n := 0
+	for _, tok := range tokenize(text) {
+		if have[tok] {
+			n++
+		} else if operator == query.OperatorAnd {
+			return 0
+		}
+	}
+	return n
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// lookup читает путь через точку; массивы объектов по пути дают массив конечных значений.
+func lookup(src map[string]interface{}, path string) interface{} {
+	if v, ok := src[path]; ok {
+		return v
+	}
+	head, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return nil
+	}
+	switch v := src[head].(type) {
+	case map[string]interface{}:
+		return lookup(v, rest)
+	case []interface{}:
+		var out []interface{}
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				if leaf := lookup(obj, rest); leaf != nil {
+					out = append(out, leaf)
+				}
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// nestUnder переносит вложенный объект под его путь, чтобы внутренние запросы использовали полные пути ("comments.body").
+func nestUnder(path string, obj map[string]interface{}) map[string]interface{} {
+	parts := strings.Split(path, ".")
+	out := obj
+	for i := len(parts) - 1; i >= 0; i-- {
+		out = map[string]interface{}{parts[i]: out}
+	}
+	return out
+}
+
+func inRange(v interface{}, q *query.RangeQuery) bool {
+	if q.GT != nil && compareValues(v, q.GT) <= 0 {
+		return false
+	}
+	if q.GTE != nil && compareValues(v, q.GTE) < 0 {
+		return false
+	}
+	if q.LT != nil && compareValues(v, q.LT) >= 0 {
+		return false
+	}
+	if q.LTE != nil && compareValues(v, q.LTE) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareValues сравнивает числа как числа, метки времени RFC 3339 — хронологически, остальное — как строки.
+func compareValues(a, b interface{}) int {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compareFloat(x, y)
+		}
+	}
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+	if ta, err := time.Parse(time.RFC3339Nano, as); err == nil {
+		if tb, err := time.Parse(time.RFC3339Nano, bs); err == nil {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(as, bs)
+}
+
+// compareMissing ставит отсутствующее (nil) значение после присутствующего.
+func compareMissing(a, b interface{}) int {
+	switch {
+	case a == nil && b != nil:
+		return 1
+	case a != nil && b == nil:
+		return -1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
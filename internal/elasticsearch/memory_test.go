@@ -0,0 +1,161 @@
+package elasticsearch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+func TestMemorySearch(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.EnsureIndex(ctx, "tickets", TicketsMapping()); err != nil {
+		t.Fatal(err)
+	}
+	docs := map[string]map[string]interface{}{
+		"1": {"ticket_id": 1, "subject": "Printer offline", "status": "open", "created_at": "2024-01-05T10:00:00Z"},
+		"2": {"ticket_id": 2, "subject": "Login fails after reset", "status": "open", "created_at": "2024-02-01T10:00:00Z"},
+		"3": {"ticket_id": 3, "subject": "Printer jams", "status": "closed"},
+	}
+	for id, doc := range docs {
+		if err := m.UpdateDocument(ctx, "tickets", id, doc, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		req  *query.Search
+		want []string
+	}{
+		{"match", &query.Search{Query: query.Match("subject", "PRINTER"), Size: 10, Sort: []query.Sort{query.SortBy("ticket_id", query.Asc)}}, []string{"1", "3"}},
+		{"match and", &query.Search{Query: &query.MatchQuery{Field: "subject", Text: "printer jams", Operator: query.OperatorAnd}, Size: 10}, []string{"3"}},
+		{"term on keyword subfield", &query.Search{Query: query.Term("subject.keyword", "Printer jams"), Size: 10}, []string{"3"}},
+		{"bool", &query.Search{Query: &query.BoolQuery{
+			Filter:  []query.Query{query.Term("status", "open")},
+			MustNot: []query.Query{query.Match("subject", "login")},
+		}, Size: 10}, []string{"1"}},
+		{"range", &query.Search{Query: &query.RangeQuery{Field: "created_at", GTE: "2024-01-10T00:00:00Z"}, Size: 10}, []string{"2"}},
+		{"sort desc missing last", &query.Search{Query: query.MatchAll(), Size: 10, Sort: []query.Sort{query.SortBy("created_at", query.Desc)}}, []string{"2", "1", "3"}},
+		{"pagination", &query.Search{Query: query.MatchAll(), From: 1, Size: 1, Sort: []query.Sort{query.SortBy("ticket_id", query.Asc)}}, []string{"2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := m.Search(ctx, "tickets", tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range resp.Hits.Hits {
+				got = append(got, h.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryNestedUpsertAndInnerHits(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.EnsureIndex(ctx, "tickets", TicketsMapping()); err != nil {
+		t.Fatal(err)
+	}
+	comment := func(id, text string) map[string]interface{} {
+		return map[string]interface{}{"comment_id": id, "author": "op-1", "text": text}
+	}
+	// комментарий раньше тикета: документ создаётся из заготовки, затем тикет дописывает поля, не трогая comments
+	if err := m.UpsertNested(ctx, "tickets", "1", "comments", "comment_id", comment("c-1", "printer still offline"), map[string]interface{}{"ticket_id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateDocument(ctx, "tickets", "1", map[string]interface{}{"ticket_id": 1, "subject": "Printer", "status": "open"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []map[string]interface{}{comment("c-2", "rebooted, toner replaced"), comment("c-1", "printer offline again")} {
+		if err := m.UpsertNested(ctx, "tickets", "1", "comments", "comment_id", c, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := m.Search(ctx, "tickets", &query.Search{Size: 10, Query: &query.BoolQuery{
+		Filter: []query.Query{query.Term("status", "open")},
+		Must: []query.Query{&query.NestedQuery{
+			Path:      "comments",
+			Query:     &query.MatchQuery{Field: "comments.text", Text: "TONER replaced", Operator: query.OperatorAnd},
+			InnerHits: &query.InnerHits{Size: 1},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits.Hits) != 1 {
+		t.Fatalf("hits = %d, want 1", len(resp.Hits.Hits))
+	}
+	hit := resp.Hits.Hits[0]
+	if hit.Source["subject"] != "Printer" || len(hit.Source["comments"].([]interface{})) != 2 {
+		t.Fatalf("source = %v, want subject and both comments (c-1 replaced, c-2 appended)", hit.Source)
+	}
+	inner := hit.InnerHits["comments"].Hits.Hits
+	if len(inner) != 1 || inner[0].Source["comment_id"] != "c-2" || inner[0].Nested.Offset != 1 {
+		t.Fatalf("inner hits = %+v, want c-2 at offset 1", inner)
+	}
+}
+
+func TestMemoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.EnsureIndex(ctx, "tickets", TicketsMapping()); err != nil {
+		t.Fatal(err)
+	}
+	comment := map[string]interface{}{"comment_id": "c-1", "author": "op-1", "text": "printer offline"}
+	if err := m.UpsertNested(ctx, "tickets", "1", "comments", "comment_id", comment, map[string]interface{}{"ticket_id": 1, "status": "open"}); err != nil {
+		t.Fatal(err)
+	}
+	req := &query.Search{Size: 10, Query: &query.NestedQuery{
+		Path: "comments", Query: query.Match("comments.text", "printer"), InnerHits: &query.InnerHits{},
+	}}
+	resp, err := m.Search(ctx, "tickets", req)
+	if err != nil || len(resp.Hits.Hits) != 1 {
+		t.Fatalf("Search = %+v, %v; want ticket 1", resp, err)
+	}
+	before := resp.Hits.Hits[0]
+
+	// запись после поиска не меняет уже отданный источник
+	if err := m.UpsertNested(ctx, "tickets", "1", "comments", "comment_id",
+		map[string]interface{}{"comment_id": "c-1", "author": "op-1", "text": "fixed"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.UpdateByQuery(ctx, "tickets", query.Term("status", "open"), map[string]interface{}{"tags": []string{"hw"}}); err != nil {
+		t.Fatal(err)
+	}
+	if text := before.Source["comments"].([]interface{})[0].(map[string]interface{})["text"]; text != "printer offline" {
+		t.Errorf("returned source changed to %q after UpsertNested", text)
+	}
+	if _, ok := before.Source["tags"]; ok {
+		t.Error("returned source got tags after UpdateByQuery")
+	}
+
+	// изменение отданного источника и inner hit не попадает в индекс
+	before.Source["status"] = "closed"
+	before.InnerHits["comments"].Hits.Hits[0].Source["text"] = "mutated"
+	doc, err := m.GetDocument(ctx, "tickets", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["tags"].([]interface{})[0] = "mutated"
+	stored, err := m.GetDocument(ctx, "tickets", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := stored["comments"].([]interface{})
+	if stored["status"] != "open" || comments[0].(map[string]interface{})["text"] != "fixed" || stored["tags"].([]interface{})[0] != "hw" {
+		t.Errorf("stored = %v, want status open, comment text fixed, tags [hw]", stored)
+	}
+}
@@ -0,0 +1,124 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// ErrMappingConflict — маппинг существующего индекса нельзя дополнить на месте: у поля изменился тип
+// (например, comments стал nested). Такой индекс переносится командой `search-service migrate up` (MigrateIndex).
+var ErrMappingConflict = errors.New("index mapping conflicts with the current mapping, run `search-service migrate up`")
+
+// migrateSuffix — суффикс временного индекса, через который MigrateIndex переиндексирует документы.
+const migrateSuffix = "-migrate"
+
+// putMapping добавляет в существующий индекс поля mapping, которых в нём ещё нет (PUT /{index}/_mapping).
+// Новые поля у старых документов остаются пустыми до их следующей записи.
+func (c *Client) putMapping(ctx context.Context, index string, mapping *query.Mapping) error {
+	if mapping == nil || len(mapping.Properties) == 0 {
+		return nil
+	}
+	body := map[string]interface{}{"properties": mapping.Properties}
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/%s/_mapping", c.baseURL, index), body, nil)
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusBadRequest && respErr.Type == "illegal_argument_exception" {
+		return fmt.Errorf("%s: %w (%s)", index, ErrMappingConflict, respErr.Reason)
+	}
+	if err != nil {
+		return fmt.Errorf("put mapping: %w", err)
+	}
+	return nil
+}
+
+// MigrateIndex приводит индекс к mapping. Совместимые изменения применяются как в EnsureIndex; при
+// ErrMappingConflict документы переиндексируются через временный индекс {index}-migrate: копия, пересоздание
+// индекса с новым маппингом, обратная копия. Во время переноса запись в индекс должна быть остановлена.
+func (c *Client) MigrateIndex(ctx context.Context, index string, mapping *query.Mapping) error {
+	err := c.EnsureIndex(ctx, index, mapping)
+	if !errors.Is(err, ErrMappingConflict) {
+		return err
+	}
+
+	tmp := index + migrateSuffix
+	exists, err := c.indexExists(ctx, tmp)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// прерванный перенос: документы могут быть только во временном индексе, удалять его нельзя вслепую
+		return fmt.Errorf("migrate %s: index %s is left from an interrupted migration, check and delete it first", index, tmp)
+	}
+
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/%s", c.baseURL, tmp), mapping, nil); err != nil {
+		return fmt.Errorf("migrate %s: create %s: %w", index, tmp, err)
+	}
+	if err := c.reindex(ctx, index, tmp); err != nil {
+		return fmt.Errorf("migrate %s: copy to %s: %w", index, tmp, err)
+	}
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", c.baseURL, index), nil, nil); err != nil {
+		return fmt.Errorf("migrate %s: delete: %w", index, err)
+	}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/%s", c.baseURL, index), mapping, nil); err != nil {
+		return fmt.Errorf("migrate %s: recreate (documents are kept in %s): %w", index, tmp, err)
+	}
+	if err := c.reindex(ctx, tmp, index); err != nil {
+		return fmt.Errorf("migrate %s: copy back (documents are kept in %s): %w", index, tmp, err)
+	}
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", c.baseURL, tmp), nil, nil); err != nil {
+		return fmt.Errorf("migrate %s: delete %s: %w", index, tmp, err)
+	}
+	return nil
+}
+
+// indexExists проверяет наличие индекса (HEAD /{index}).
+func (c *Client) indexExists(ctx context.Context, index string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf("%s/%s", c.baseURL, index), nil)
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("check index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, responseError(resp)
+	}
+}
+
+// reindex синхронно копирует все документы из src в dst (_reindex с wait_for_completion) и делает их
+// видимыми для поиска. Частичная копия — ошибка: исходный индекс после неё удалять нельзя.
+func (c *Client) reindex(ctx context.Context, src, dst string) error {
+	body := map[string]interface{}{
+		"source": map[string]interface{}{"index": src},
+		"dest":   map[string]interface{}{"index": dst},
+	}
+	var resp struct {
+		Total    int64             `json:"total"`
+		Created  int64             `json:"created"`
+		Updated  int64             `json:"updated"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	url := fmt.Sprintf("%s/_reindex?wait_for_completion=true&refresh=true", c.baseURL)
+	if err := c.do(ctx, http.MethodPost, url, body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Failures) > 0 {
+		return fmt.Errorf("%d failures, first: %s", len(resp.Failures), resp.Failures[0])
+	}
+	if resp.Created+resp.Updated != resp.Total {
+		return fmt.Errorf("copied %d of %d documents", resp.Created+resp.Updated, resp.Total)
+	}
+	return nil
+}
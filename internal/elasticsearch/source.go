@@ -0,0 +1,20 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToSource приводит документ к виду JSON-объекта, как его хранит бэкенд (_source): числа
+// читаются обратно как float64, поля с omitempty пропадают. Общий для всех реализаций IndexSearcher.
+func ToSource(doc interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal document: %w", err)
+	}
+	var source map[string]interface{}
+	if err := json.Unmarshal(data, &source); err != nil {
+		return nil, fmt.Errorf("document must be a JSON object: %w", err)
+	}
+	return source, nil
+}
@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// FieldEventAt — служебное поле документа: время последнего применённого события (Unix, мс).
+// По нему UpdateDocument упорядочивает записи с UpdateOptions.EventAt.
+const FieldEventAt = "event_at"
+
+// ErrStale — UpdateDocument не применил запись: документ уже изменён более поздним событием.
+var ErrStale = errors.New("stale update: the document has a newer event")
+
+// ErrConflict — UpdateDocument не применил запись: документ не совпадает с UpdateOptions.Expect
+// (изменён параллельной записью после чтения). Вызывающий перечитывает документ и повторяет запись.
+var ErrConflict = errors.New("conflicting update: the document changed since it was read")
+
+// UpdateOptions уточняет частичное обновление UpdateDocument; nil — простое слияние полей.
+type UpdateOptions struct {
+	// Defaults записываются, только если этих полей ещё нет в документе (например, created_at).
+	Defaults map[string]interface{}
+	// EventAt — время события, породившего запись. Ненулевое: запись применяется, только если документ
+	// не изменён событием позже (FieldEventAt), иначе UpdateDocument возвращает ErrStale.
+	// Сравниваются только времена событий — updated_at, выставленный сервисом, в проверке не участвует.
+	EventAt time.Time
+	// Expect — значения полей, при которых запись была вычислена (nil — поля нет). Запись применяется,
+	// только если документ всё ещё их содержит, иначе UpdateDocument возвращает ErrConflict. Проверяется
+	// у существующего документа; новый создаётся без проверки.
+	Expect map[string]interface{}
+}
+
+// Stale сообщает, что doc уже изменён событием позже o.EventAt.
+func (o *UpdateOptions) Stale(doc map[string]interface{}) bool {
+	if o == nil || o.EventAt.IsZero() {
+		return false
+	}
+	stored, ok := doc[FieldEventAt].(float64) // _source после JSON: числа — float64
+	return ok && int64(stored) > o.EventAt.UnixMilli()
+}
+
+// Conflict сообщает, что doc не совпадает с o.Expect.
+func (o *UpdateOptions) Conflict(doc map[string]interface{}) bool {
+	if o == nil || doc == nil {
+		return false
+	}
+	for k, v := range o.Expect {
+		if !reflect.DeepEqual(doc[k], v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply сливает source (поля верхнего уровня в виде ToSource) с документом doc так же, как painless-скрипт
+// Client.UpdateDocument; для реализаций IndexSearcher без скриптов. Устаревшая запись doc не меняет.
+func (o *UpdateOptions) Apply(doc, source map[string]interface{}) error {
+	if o.Stale(doc) {
+		return ErrStale
+	}
+	if len(doc) > 0 && o.Conflict(doc) {
+		return ErrConflict
+	}
+	for k, v := range source {
+		doc[k] = v
+	}
+	if o == nil {
+		return nil
+	}
+	for k, v := range o.Defaults {
+		if doc[k] == nil {
+			doc[k] = v
+		}
+	}
+	if !o.EventAt.IsZero() {
+		doc[FieldEventAt] = float64(o.EventAt.UnixMilli())
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/metrics"
+	"github.com/psds-microservice/search-service/internal/ratelimit"
+	"github.com/psds-microservice/search-service/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader — заголовок/ключ metadata с идентификатором запроса.
+const RequestIDHeader = "x-request-id"
+
+// MetricsUnaryInterceptor считает количество и латентность RPC по методу и коду ответа.
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err).String()
+		metrics.GRPCRequests.WithLabelValues(info.FullMethod, code).Inc()
+		metrics.GRPCDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// LoggingUnaryInterceptor назначает запросу request_id (из x-request-id или новый UUID), возвращает его
+// в заголовке ответа, кладёт в контекст логгер с request_id и method и пишет access-лог.
+func LoggingUnaryInterceptor(base *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := incomingRequestID(ctx)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+		l := base.With("request_id", requestID, "method", info.FullMethod)
+		ctx = logger.WithContext(ctx, l)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		level := slog.LevelDebug
+		if err != nil {
+			level = slog.LevelWarn
+		}
+		l.Log(ctx, level, "rpc finished", "code", code.String(), "duration_ms", time.Since(start).Milliseconds())
+		return resp, err
+	}
+}
+
+// TenantUnaryInterceptor определяет арендатора запроса и кладёт его в контекст. С claim (аутентификация
+// включена) арендатор берётся только из этого claim токена: без него запрос отклоняется, а заголовок
+// x-tenant-id, если передан, должен с ним совпадать. Пустой claim (аутентификация выключена) — арендатор
+// из заголовка.
+func TenantUnaryInterceptor(claim string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := incomingHeader(ctx, tenant.Header)
+		if claim != "" {
+			claims := auth.ClaimsFromContext(ctx)
+			if claims == nil {
+				return nil, status.Error(codes.Unauthenticated, "tenant requires an authenticated caller")
+			}
+			fromToken := claims.String(claim)
+			if fromToken == "" {
+				return nil, status.Errorf(codes.PermissionDenied, "token has no tenant claim %q", claim)
+			}
+			if id != "" && id != fromToken {
+				return nil, status.Error(codes.PermissionDenied, "tenant does not match token")
+			}
+			id = fromToken
+		}
+		if id != "" {
+			ctx = tenant.WithTenant(ctx, id)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RetryAfterHeader — ключ metadata с рекомендуемой паузой (секунды) при ResourceExhausted; gateway отдаёт его как Retry-After.
+const RetryAfterHeader = "retry-after"
+
+// RateLimitUnaryInterceptor ограничивает частоту вызовов по вызывающему: subject токена, а без
+// аутентификации — IP клиента. При превышении бюджета возвращает ResourceExhausted с retry-after.
+func RateLimitUnaryInterceptor(l *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route := path.Base(info.FullMethod)
+		ok, retryAfter := l.Allow(route, callerKey(ctx))
+		if !ok {
+			seconds := ratelimit.RetryAfterSeconds(retryAfter)
+			_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.Itoa(seconds)))
+			metrics.GRPCRateLimited.WithLabelValues(info.FullMethod).Inc()
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s, retry after %ds", route, seconds)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// callerKey идентифицирует вызывающего для rate limiting.
+func callerKey(ctx context.Context) string {
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		return "sub:" + claims.Subject
+	}
+	return "ip:" + clientIP(ctx)
+}
+
+// clientIP возвращает IP клиента. X-Forwarded-For учитывается только для запросов с loopback
+// (это grpc-gateway этого же процесса, который дописывает адрес клиента последним), чтобы
+// внешний gRPC-клиент не мог подменить адрес.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host := p.Addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if xff := incomingHeader(ctx, "x-forwarded-for"); xff != "" {
+			parts := strings.Split(xff, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	return host
+}
+
+func incomingRequestID(ctx context.Context) string {
+	return incomingHeader(ctx, RequestIDHeader)
+}
+
+func incomingHeader(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
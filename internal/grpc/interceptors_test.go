@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/ratelimit"
+	"github.com/psds-microservice/search-service/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var searchTickets = &grpc.UnaryServerInfo{FullMethod: "/search_service.SearchService/SearchTickets"}
+
+// withClaims — контекст запроса, прошедшего аутентификацию с claims.
+func withClaims(ctx context.Context, sub string, raw jwt.MapClaims) context.Context {
+	return auth.WithClaims(ctx, &auth.Claims{Subject: sub, Raw: raw})
+}
+
+func TestTenantUnaryInterceptor(t *testing.T) {
+	header := func(id string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenant.Header, id))
+	}
+	cases := []struct {
+		name   string
+		claim  string
+		ctx    context.Context
+		code   codes.Code
+		tenant string
+	}{
+		{"no auth: header", "", header("acme"), codes.OK, "acme"},
+		{"no auth: no header", "", context.Background(), codes.OK, ""},
+		{"claim", "tenant_id", withClaims(context.Background(), "op-1", jwt.MapClaims{"tenant_id": "acme"}), codes.OK, "acme"},
+		{"claim and matching header", "tenant_id", withClaims(header("acme"), "op-1", jwt.MapClaims{"tenant_id": "acme"}), codes.OK, "acme"},
+		{"claim and other header", "tenant_id", withClaims(header("globex"), "op-1", jwt.MapClaims{"tenant_id": "acme"}), codes.PermissionDenied, ""},
+		{"token without claim: header ignored", "tenant_id", withClaims(header("globex"), "op-1", jwt.MapClaims{}), codes.PermissionDenied, ""},
+		{"auth on, no caller", "tenant_id", header("acme"), codes.Unauthenticated, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			_, err := TenantUnaryInterceptor(tc.claim)(tc.ctx, nil, searchTickets, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				got = tenant.FromContext(ctx)
+				return nil, nil
+			})
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("code = %s (%v), want %s", code, err, tc.code)
+			}
+			if got != tc.tenant {
+				t.Errorf("tenant = %q, want %q", got, tc.tenant)
+			}
+		})
+	}
+}
+
+// headerStream — ServerTransportStream, запоминающий заголовки ответа.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return searchTickets.FullMethod }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	interceptor := RateLimitUnaryInterceptor(ratelimit.New(ratelimit.Budget{RPS: 0.1, Burst: 1}, ratelimit.Budget{RPS: 1, Burst: 1}, nil))
+	call := func(ctx context.Context) (*headerStream, error) {
+		stream := &headerStream{}
+		_, err := interceptor(grpc.NewContextWithServerTransportStream(ctx, stream), nil, searchTickets,
+			func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+		return stream, err
+	}
+	op1 := withClaims(context.Background(), "op-1", nil)
+	if _, err := call(op1); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	stream, err := call(op1)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call = %v, want ResourceExhausted", err)
+	}
+	if got := stream.header.Get(RetryAfterHeader); len(got) != 1 || got[0] != "10" {
+		t.Errorf("retry-after = %v, want [10] (one token at 0.1 rps)", got)
+	}
+	if _, err := call(withClaims(context.Background(), "op-2", nil)); err != nil {
+		t.Errorf("another caller: %v, want its own budget", err)
+	}
+}
+
+func TestCallerKey(t *testing.T) {
+	withPeer := func(addr string, xff string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5000}})
+		if xff != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", xff))
+		}
+		return ctx
+	}
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"subject wins over IP", withClaims(withPeer("203.0.113.5", ""), "op-1", nil), "sub:op-1"},
+		{"remote peer", withPeer("203.0.113.5", ""), "ip:203.0.113.5"},
+		{"remote peer: XFF ignored", withPeer("203.0.113.5", "198.51.100.7"), "ip:203.0.113.5"},
+		{"loopback gateway: last XFF entry", withPeer("127.0.0.1", "10.0.0.1, 198.51.100.7"), "ip:198.51.100.7"},
+		{"loopback without XFF", withPeer("::1", ""), "ip:::1"},
+		{"no peer", context.Background(), "ip:"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := callerKey(tc.ctx); got != tc.want {
+				t.Errorf("callerKey = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,19 +2,27 @@ package grpc
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"time"
 
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/querylang"
 	"github.com/psds-microservice/search-service/internal/service"
 	"github.com/psds-microservice/search-service/internal/validator"
 	"github.com/psds-microservice/search-service/pkg/gen/search_service"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Deps — зависимости gRPC-сервера (D: зависимость от абстракций).
 type Deps struct {
-	SearchSvc service.SearchServicer
-	Validator *validator.Validator
+	SearchSvc      service.SearchServicer
+	Validator      *validator.Validator
+	Logger         *slog.Logger
+	ExplainEnabled bool // Explain RPC доступен (иначе Unimplemented)
 }
 
 // Server implements search_service.SearchServiceServer
@@ -28,45 +36,200 @@ func NewServer(deps Deps) *Server {
 	return &Server{Deps: deps}
 }
 
-func (s *Server) mapError(err error) error {
+func (s *Server) mapError(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
 	}
-	log.Printf("grpc: error: %v", err)
+	if code, ok := helpyerrors.CodeOf(err); ok {
+		if c, known := grpcCodes[code]; known && c != codes.Internal {
+			return status.Error(c, err.Error())
+		}
+	}
+	logger.FromContext(ctx, s.Logger).Error("request failed", logger.Err(err))
 	return status.Error(codes.Internal, err.Error())
 }
 
-func (s *Server) SearchTickets(ctx context.Context, req *search_service.SearchTicketsRequest) (*search_service.SearchTicketsResponse, error) {
-	limit := int(req.GetLimit())
-	if err := s.Validator.ValidateSearchLimit(limit); err != nil {
+// grpcCodes сопоставляет коды helpy/errors кодам gRPC.
+var grpcCodes = map[helpyerrors.Code]codes.Code{
+	helpyerrors.CodeInvalidArgument:    codes.InvalidArgument,
+	helpyerrors.CodeNotFound:           codes.NotFound,
+	helpyerrors.CodeUnauthenticated:    codes.Unauthenticated,
+	helpyerrors.CodePermissionDenied:   codes.PermissionDenied,
+	helpyerrors.CodeAlreadyExists:      codes.AlreadyExists,
+	helpyerrors.CodeFailedPrecondition: codes.FailedPrecondition,
+	helpyerrors.CodeInternal:           codes.Internal,
+}
+
+// pagination проверяет limit и offset поискового запроса.
+func (s *Server) pagination(limit, offset int32) (int, int, error) {
+	if err := s.Validator.ValidateSearchLimit(int(limit)); err != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.Validator.ValidateSearchOffset(int(offset)); err != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return int(limit), int(offset), nil
+}
+
+// timeRange преобразует диапазон дат запроса и проверяет его границы.
+func (s *Server) timeRange(name string, r *search_service.TimeRange) (service.TimeRange, error) {
+	for _, ts := range []*timestamppb.Timestamp{r.GetFrom(), r.GetTo()} {
+		if ts == nil {
+			continue
+		}
+		if err := ts.CheckValid(); err != nil {
+			return service.TimeRange{}, status.Error(codes.InvalidArgument, "validation: "+name+": "+err.Error())
+		}
+	}
+	tr := service.TimeRange{From: asTime(r.GetFrom()), To: asTime(r.GetTo())}
+	if err := s.Validator.ValidateTimeRange(name, tr.From, tr.To); err != nil {
+		return service.TimeRange{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return tr, nil
+}
+
+// callerID — идентификатор аутентифицированного вызывающего (sub из JWT) или пусто.
+func callerID(ctx context.Context) string {
+	if c := auth.ClaimsFromContext(ctx); c != nil {
+		return c.Subject
+	}
+	return ""
+}
+
+// asTime возвращает время timestamp'а; nil — нулевое время (AsTime дал бы начало эпохи).
+func asTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func (s *Server) ticketFilters(ctx context.Context, req *search_service.SearchTicketsRequest) (*service.TicketFilters, error) {
+	limit, offset, err := s.pagination(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.timeRange("created", req.GetCreated())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.timeRange("updated", req.GetUpdated())
+	if err != nil {
+		return nil, err
+	}
+	f := &service.TicketFilters{
+		Status:          req.GetStatus(),
+		Statuses:        req.GetStatuses(),
+		ExcludeStatuses: req.GetExcludeStatus(),
+		SessionID:       req.GetSessionId(),
+		ClientID:        req.GetClientId(),
+		ClientIDs:       req.GetClientIds(),
+		OperatorID:      req.GetOperatorId(),
+		OperatorIDs:     req.GetOperatorIds(),
+		OperatorRegion:  req.GetOperatorRegion(),
+		OperatorRegions: req.GetOperatorRegions(),
+		Text:            req.GetText(),
+		Created:         created,
+		Updated:         updated,
+		Limit:           limit,
+		Offset:          offset,
+	}
+	if err := querylang.Tickets(req.GetQuery(), callerID(ctx), f); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	offset := int(req.GetOffset())
-	if err := s.Validator.ValidateSearchOffset(offset); err != nil {
+	return f, nil
+}
+
+func (s *Server) sessionFilters(ctx context.Context, req *search_service.SearchSessionsRequest) (*service.SessionFilters, error) {
+	limit, offset, err := s.pagination(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.timeRange("created", req.GetCreated())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.timeRange("updated", req.GetUpdated())
+	if err != nil {
+		return nil, err
+	}
+	ended, err := s.timeRange("ended", req.GetEnded())
+	if err != nil {
+		return nil, err
+	}
+	f := &service.SessionFilters{
+		Status:          req.GetStatus(),
+		Statuses:        req.GetStatuses(),
+		ExcludeStatuses: req.GetExcludeStatus(),
+		ClientID:        req.GetClientId(),
+		ClientIDs:       req.GetClientIds(),
+		PIN:             req.GetPin(),
+		OperatorID:      req.GetOperatorId(),
+		OperatorIDs:     req.GetOperatorIds(),
+		Created:         created,
+		Updated:         updated,
+		Ended:           ended,
+		Limit:           limit,
+		Offset:          offset,
+	}
+	if err := querylang.Sessions(req.GetQuery(), callerID(ctx), f); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	return f, nil
+}
 
-	filters := &service.TicketFilters{
-		Status:     req.GetStatus(),
-		SessionID:  req.GetSessionId(),
-		ClientID:   req.GetClientId(),
-		OperatorID: req.GetOperatorId(),
-		Limit:      limit,
-		Offset:     offset,
+func (s *Server) operatorFilters(ctx context.Context, req *search_service.SearchOperatorsRequest) (*service.OperatorFilters, error) {
+	limit, offset, err := s.pagination(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.timeRange("created", req.GetCreated())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.timeRange("updated", req.GetUpdated())
+	if err != nil {
+		return nil, err
+	}
+	f := &service.OperatorFilters{
+		Region:      req.GetRegion(),
+		Regions:     req.GetRegions(),
+		Role:        req.GetRole(),
+		Roles:       req.GetRoles(),
+		DisplayName: req.GetDisplayName(),
+		Created:     created,
+		Updated:     updated,
+		Limit:       limit,
+		Offset:      offset,
+	}
+	if err := querylang.Operators(req.GetQuery(), f); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return f, nil
+}
+
+func (s *Server) SearchTickets(ctx context.Context, req *search_service.SearchTicketsRequest) (*search_service.SearchTicketsResponse, error) {
+	filters, err := s.ticketFilters(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	result, err := s.SearchSvc.SearchTickets(ctx, filters)
 	if err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	ticketHits := make([]*search_service.TicketHit, len(result.Tickets))
 	for i, t := range result.Tickets {
 		ticketHits[i] = &search_service.TicketHit{
-			TicketId:  t.TicketID,
-			SessionId: t.SessionID,
-			Subject:   t.Subject,
-			Snippet:   t.Snippet,
+			TicketId:            t.TicketID,
+			SessionId:           t.SessionID,
+			Subject:             t.Subject,
+			Snippet:             t.Snippet,
+			MatchedCommentId:    t.MatchedCommentID,
+			OperatorDisplayName: t.OperatorName,
+			OperatorRegion:      t.OperatorRegion,
+			SessionStatus:       t.SessionStatus,
 		}
 	}
 
@@ -78,35 +241,25 @@ func (s *Server) SearchTickets(ctx context.Context, req *search_service.SearchTi
 }
 
 func (s *Server) SearchSessions(ctx context.Context, req *search_service.SearchSessionsRequest) (*search_service.SearchSessionsResponse, error) {
-	limit := int(req.GetLimit())
-	if err := s.Validator.ValidateSearchLimit(limit); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
-	}
-	offset := int(req.GetOffset())
-	if err := s.Validator.ValidateSearchOffset(offset); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
-	}
-
-	filters := &service.SessionFilters{
-		Status:   req.GetStatus(),
-		ClientID: req.GetClientId(),
-		PIN:      req.GetPin(),
-		Limit:    limit,
-		Offset:   offset,
+	filters, err := s.sessionFilters(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	result, err := s.SearchSvc.SearchSessions(ctx, filters)
 	if err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	sessionHits := make([]*search_service.SessionHit, len(result.Sessions))
 	for i, ses := range result.Sessions {
 		sessionHits[i] = &search_service.SessionHit{
-			SessionId: ses.SessionID,
-			Pin:       ses.PIN,
-			Status:    ses.Status,
-			Snippet:   ses.Snippet,
+			SessionId:        ses.SessionID,
+			Pin:              ses.PIN,
+			Status:           ses.Status,
+			Snippet:          ses.Snippet,
+			OperatorIds:      ses.OperatorIDs,
+			ParticipantCount: int32(ses.ParticipantCount),
 		}
 	}
 
@@ -118,26 +271,14 @@ func (s *Server) SearchSessions(ctx context.Context, req *search_service.SearchS
 }
 
 func (s *Server) SearchOperators(ctx context.Context, req *search_service.SearchOperatorsRequest) (*search_service.SearchOperatorsResponse, error) {
-	limit := int(req.GetLimit())
-	if err := s.Validator.ValidateSearchLimit(limit); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
-	}
-	offset := int(req.GetOffset())
-	if err := s.Validator.ValidateSearchOffset(offset); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
-	}
-
-	filters := &service.OperatorFilters{
-		Region:      req.GetRegion(),
-		Role:        req.GetRole(),
-		DisplayName: req.GetDisplayName(),
-		Limit:       limit,
-		Offset:      offset,
+	filters, err := s.operatorFilters(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	result, err := s.SearchSvc.SearchOperators(ctx, filters)
 	if err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	operatorHits := make([]*search_service.OperatorHit, len(result.Operators))
@@ -170,10 +311,12 @@ func (s *Server) IndexTicket(ctx context.Context, req *search_service.IndexTicke
 		Subject:    req.GetSubject(),
 		Notes:      req.GetNotes(),
 		Status:     req.GetStatus(),
+		CreatedAt:  asTime(req.GetCreatedAt()),
+		UpdatedAt:  asTime(req.GetUpdatedAt()),
 	}
 
 	if err := s.SearchSvc.IndexTicket(ctx, in); err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	return &search_service.IndexResponse{Ok: true}, nil
@@ -189,10 +332,13 @@ func (s *Server) IndexSession(ctx context.Context, req *search_service.IndexSess
 		ClientID:  req.GetClientId(),
 		PIN:       req.GetPin(),
 		Status:    req.GetStatus(),
+		CreatedAt: asTime(req.GetCreatedAt()),
+		UpdatedAt: asTime(req.GetUpdatedAt()),
+		EndedAt:   asTime(req.GetEndedAt()),
 	}
 
 	if err := s.SearchSvc.IndexSession(ctx, in); err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	return &search_service.IndexResponse{Ok: true}, nil
@@ -208,11 +354,66 @@ func (s *Server) IndexOperator(ctx context.Context, req *search_service.IndexOpe
 		DisplayName: req.GetDisplayName(),
 		Region:      req.GetRegion(),
 		Role:        req.GetRole(),
+		CreatedAt:   asTime(req.GetCreatedAt()),
+		UpdatedAt:   asTime(req.GetUpdatedAt()),
 	}
 
 	if err := s.SearchSvc.IndexOperator(ctx, in); err != nil {
-		return nil, s.mapError(err)
+		return nil, s.mapError(ctx, err)
 	}
 
 	return &search_service.IndexResponse{Ok: true}, nil
 }
+
+func (s *Server) Explain(ctx context.Context, req *search_service.ExplainRequest) (*search_service.ExplainResponse, error) {
+	if !s.ExplainEnabled {
+		return nil, status.Error(codes.Unimplemented, "Explain is disabled (EXPLAIN_ENABLED)")
+	}
+	var (
+		result *service.Explanation
+		err    error
+	)
+	switch q := req.GetSearch().(type) {
+	case *search_service.ExplainRequest_Tickets:
+		filters, ferr := s.ticketFilters(ctx, q.Tickets)
+		if ferr != nil {
+			return nil, ferr
+		}
+		result, err = s.SearchSvc.ExplainTickets(ctx, filters)
+	case *search_service.ExplainRequest_Sessions:
+		filters, ferr := s.sessionFilters(ctx, q.Sessions)
+		if ferr != nil {
+			return nil, ferr
+		}
+		result, err = s.SearchSvc.ExplainSessions(ctx, filters)
+	case *search_service.ExplainRequest_Operators:
+		filters, ferr := s.operatorFilters(ctx, q.Operators)
+		if ferr != nil {
+			return nil, ferr
+		}
+		result, err = s.SearchSvc.ExplainOperators(ctx, filters)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "one of tickets, sessions or operators is required")
+	}
+	if err != nil {
+		return nil, s.mapError(ctx, err)
+	}
+
+	hits := make([]*search_service.HitExplanation, len(result.Hits))
+	for i, h := range result.Hits {
+		hits[i] = &search_service.HitExplanation{
+			Id:          h.ID,
+			Score:       h.Score,
+			Explanation: string(h.Explanation),
+		}
+	}
+
+	return &search_service.ExplainResponse{
+		Index:   result.Index,
+		Query:   string(result.Query),
+		Profile: string(result.Profile),
+		TookMs:  result.TookMs,
+		Total:   result.Total,
+		Hits:    hits,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/psds-microservice/search-service/internal/validator"
+	"github.com/psds-microservice/search-service/pkg/gen/search_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestExplainDisabledByDefault(t *testing.T) {
+	srv := NewServer(Deps{})
+	_, err := srv.Explain(context.Background(), &search_service.ExplainRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Explain error = %v, want UNIMPLEMENTED while EXPLAIN_ENABLED is off", err)
+	}
+}
+
+func TestQueryNarrowsExplicitFilters(t *testing.T) {
+	ctx := context.Background()
+	svc, err := service.NewSearchServiceWithIndexer(elasticsearch.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, st := range []string{"open", "closed"} {
+		if err := svc.IndexTicket(ctx, &service.IndexTicketInput{TicketID: int64(i + 1), SessionID: "s-1", ClientID: "c-1", Subject: "ticket", Status: st}); err != nil {
+			t.Fatalf("IndexTicket: %v", err)
+		}
+	}
+	srv := NewServer(Deps{SearchSvc: svc, Validator: validator.New()})
+
+	cases := []struct {
+		query string
+		want  int64
+	}{
+		{"", 1},
+		{"status:open", 1},
+		{"status:closed", 0},
+		{"status:open,closed", 1},
+	}
+	for _, tc := range cases {
+		res, err := srv.SearchTickets(ctx, &search_service.SearchTicketsRequest{Status: "open", Query: tc.query})
+		if err != nil {
+			t.Fatalf("status=open query=%q: %v", tc.query, err)
+		}
+		if res.GetTotal() != tc.want {
+			t.Errorf("status=open query=%q: total=%d, want %d", tc.query, res.GetTotal(), tc.want)
+		}
+	}
+}
+
+func TestRequestLogCarriesRequestIDAndTruncatedESError(t *testing.T) {
+	reason := strings.Repeat("x", 4096)
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/_search") {
+			w.Write([]byte(`{}`)) // индексы уже есть
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `{"error":{"type":"search_phase_execution_exception","reason":%q},"status":500}`, reason)
+	}))
+	defer es.Close()
+	svc, err := service.NewSearchService(elasticsearch.NewClient(es.URL, false, "", "", elasticsearch.WithFlavor(elasticsearch.FlavorElasticsearch)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(Deps{SearchSvc: svc, Validator: validator.New()})
+
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, "req-42"))
+	_, err = LoggingUnaryInterceptor(base)(ctx, &search_service.SearchTicketsRequest{}, searchTickets, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.SearchTickets(ctx, req.(*search_service.SearchTicketsRequest))
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("SearchTickets error = %v, want INTERNAL", err)
+	}
+
+	entries := map[string]map[string]interface{}{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var e map[string]interface{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("log line %s: %v", line, err)
+		}
+		entries[e["msg"].(string)] = e
+	}
+	for _, msg := range []string{"request failed", "rpc finished"} {
+		e, ok := entries[msg]
+		if !ok {
+			t.Fatalf("no %q entry in log:\n%s", msg, buf.String())
+		}
+		if e["request_id"] != "req-42" || e["method"] != searchTickets.FullMethod {
+			t.Errorf("%q: request_id=%v method=%v, want req-42 and %s", msg, e["request_id"], e["method"], searchTickets.FullMethod)
+		}
+	}
+	logged, _ := entries["request failed"]["error"].(string)
+	if !strings.Contains(logged, "search_phase_execution_exception") || !strings.HasSuffix(logged, "...") || len(logged) > 1024 {
+		t.Errorf("logged error (%d bytes) = %.200q..., want the error type and a truncated reason", len(logged), logged)
+	}
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// CloudEvents (Kafka protocol binding): в binary mode атрибуты события — заголовки ce_*, данные — значение
+// сообщения с форматом из content-type; в structured mode значение — JSON-конверт (content-type
+// application/cloudevents+json) с атрибутами и data/data_base64. type события заменяет поле event
+// (и выбирает маршрут), subject — идентификатор документа, если его нет в данных, time — время события:
+// умолчание для updated_at; по нему сервис отклоняет события старше последнего применённого к документу.
+// id (вместе с source) — ключ идемпотентности: повторно доставленное событие пропускается. Это защита
+// «по возможности»: обработанные ключи помнит LRU в памяти процесса (seenEventsSize, seenEventsTTL),
+// он теряется при рестарте и не разделяется между репликами — после ребалансировки или рестарта
+// событие может быть обработано повторно. Корректность повтора обеспечивают идемпотентные записи
+// (upsert по id документа и комментария) и проверка порядка по времени события.
+// Сообщения без CloudEvents-атрибутов обрабатываются как прежде (плоский JSON).
+
+const (
+	headerCESpecVersion = "ce_specversion"
+	mediaCloudEvents    = "application/cloudevents+json"
+	ceSpecVersion       = "1.0"
+)
+
+// CloudEvent — атрибуты CloudEvents, используемые worker'ом.
+type CloudEvent struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	Time    time.Time
+}
+
+// key — ключ идемпотентности события (id уникален в пределах source).
+func (ce *CloudEvent) key() string {
+	return ce.Source + "\x00" + ce.ID
+}
+
+// apply переносит в событие атрибуты: type заменяет тип события, subject задаёт идентификатор
+// документа, если его нет в данных. Для nil (сообщение не CloudEvent) ничего не делает.
+func (ce *CloudEvent) apply(event, id *string) {
+	if ce == nil {
+		return
+	}
+	*event = ce.Type
+	if *id == "" {
+		*id = ce.Subject
+	}
+}
+
+type cloudEventKey struct{}
+
+func withCloudEvent(ctx context.Context, ce *CloudEvent) context.Context {
+	return context.WithValue(ctx, cloudEventKey{}, ce)
+}
+
+// cloudEventFrom возвращает атрибуты CloudEvents обрабатываемого сообщения (nil — сообщение не CloudEvent).
+func cloudEventFrom(ctx context.Context) *CloudEvent {
+	ce, _ := ctx.Value(cloudEventKey{}).(*CloudEvent)
+	return ce
+}
+
+// structuredEvent — конверт CloudEvents в structured mode.
+type structuredEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      string          `json:"data_base64"`
+}
+
+// unwrapCloudEvent извлекает атрибуты CloudEvents. Для structured mode возвращает сообщение, значение
+// которого — данные события, а content-type — их формат. nil — сообщение не CloudEvent.
+func unwrapCloudEvent(msg kafka.Message) (*CloudEvent, kafka.Message, error) {
+	headers := headerCarrier(msg.Headers)
+	if spec := headers.Get(headerCESpecVersion); spec != "" {
+		ce := &CloudEvent{
+			ID:      headers.Get("ce_id"),
+			Source:  headers.Get("ce_source"),
+			Type:    headers.Get("ce_type"),
+			Subject: headers.Get("ce_subject"),
+		}
+		err := ce.init(spec, headers.Get("ce_time"))
+		return ce, msg, err
+	}
+
+	contentType, err := payloadMediaType(msg.Headers)
+	if err != nil || contentType != mediaCloudEvents {
+		return nil, msg, nil // формат полезной нагрузки проверит Decoder
+	}
+	var env structuredEvent
+	if err := json.Unmarshal(msg.Value, &env); err != nil {
+		return nil, msg, fmt.Errorf("unmarshal cloudevent: %w", err)
+	}
+	ce := &CloudEvent{ID: env.ID, Source: env.Source, Type: env.Type, Subject: env.Subject}
+	if err := ce.init(env.SpecVersion, env.Time); err != nil {
+		return nil, msg, err
+	}
+
+	data := []byte(env.Data)
+	if string(data) == "null" {
+		data = nil
+	}
+	switch {
+	case env.DataBase64 != "":
+		if data, err = base64.StdEncoding.DecodeString(env.DataBase64); err != nil {
+			return nil, msg, fmt.Errorf("cloudevent %s: data_base64: %w", env.ID, err)
+		}
+	case env.DataContentType != "" && !isJSONMediaType(env.DataContentType):
+		return nil, msg, fmt.Errorf("cloudevent %s: %s data must be in data_base64", env.ID, env.DataContentType)
+	}
+	unwrapped := msg
+	unwrapped.Value = data
+	unwrapped.Headers = nil
+	for _, h := range msg.Headers {
+		if !strings.EqualFold(h.Key, headerContentType) {
+			unwrapped.Headers = append(unwrapped.Headers, h)
+		}
+	}
+	if env.DataContentType != "" {
+		unwrapped.Headers = append(unwrapped.Headers, kafka.Header{Key: headerContentType, Value: []byte(env.DataContentType)})
+	}
+	return ce, unwrapped, nil
+}
+
+// init проверяет обязательные атрибуты и разбирает time.
+func (ce *CloudEvent) init(specVersion, eventTime string) error {
+	if specVersion != ceSpecVersion {
+		return fmt.Errorf("cloudevent: unsupported specversion %q", specVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return errors.New("cloudevent: id, source and type are required")
+	}
+	if eventTime != "" {
+		t, err := time.Parse(time.RFC3339Nano, eventTime)
+		if err != nil {
+			return fmt.Errorf("cloudevent %s: time: %w", ce.ID, err)
+		}
+		ce.Time = t
+	}
+	return nil
+}
+
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"))
+}
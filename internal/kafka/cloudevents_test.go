@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/schemaregistry"
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestConsumeCloudEvents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "3.proto"), []byte(operatorProtoSchema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	structured := kafka.Header{Key: "content-type", Value: []byte(mediaCloudEvents + "; charset=utf-8")}
+	binaryMode := func(id, typ, subject, at string) []kafka.Header {
+		return []kafka.Header{
+			{Key: "ce_specversion", Value: []byte("1.0")},
+			{Key: "ce_id", Value: []byte(id)},
+			{Key: "ce_source", Value: []byte("/session-manager")},
+			{Key: "ce_type", Value: []byte(typ)},
+			{Key: "ce_subject", Value: []byte(subject)},
+			{Key: "ce_time", Value: []byte(at)},
+			{Key: "content-type", Value: []byte("application/json")},
+		}
+	}
+	operator := base64.StdEncoding.EncodeToString(framed(3, []byte{0}, protoPayload(t, operatorProtoSchema, "OperatorEvent", `{"display_name":"Anna Petrova","region":"eu"}`)))
+
+	broker := NewMemoryBroker(topicSessionEvents, topicTicketEvents, topicOperatorCreated)
+	broker.Produce(topicSessionEvents, nil, []byte(`{"client_id":"c-1"}`), binaryMode("e-1", "session.created", "s-1", "2024-05-06T08:30:00Z")...)
+	broker.Produce(topicSessionEvents, nil, []byte(`{"client_id":"c-1"}`), binaryMode("e-1", "session.created", "s-1", "2024-05-06T09:00:00Z")...) // повторная доставка
+	broker.Produce(topicSessionEvents, nil, []byte(`{"event":"session.created","session_id":"s-2","client_id":"c-2"}`))                            // плоский JSON
+	broker.Produce(topicTicketEvents, nil, []byte(`{"specversion":"1.0","id":"t-1","source":"/tickets","type":"ticket.created","subject":"7",
+		"time":"2024-05-06T08:45:00Z","datacontenttype":"application/json","data":{"session_id":"s-1","subject":"Cannot join"}}`), structured)
+	broker.Produce(topicTicketEvents, nil, []byte(`{"specversion":"1.0","id":"t-2","source":"/tickets","type":"ticket.comment_added","subject":"7",
+		"data":{"author":"op-1","text":"Camera driver crashes on join"}}`), structured)
+	broker.Produce(topicTicketEvents, nil, []byte(`{"specversion":"1.0","source":"/tickets","type":"ticket.created","data":{}}`), structured) // без id — в DLQ
+	broker.Produce(topicOperatorCreated, nil, []byte(`{"specversion":"1.0","id":"o-1","source":"/operators","type":"operator.created","subject":"op-1",
+		"datacontenttype":"application/x-protobuf","data_base64":"`+operator+`"}`), structured)
+
+	svc := newMemoryService(t)
+	runConsumerWith(t, broker, NewDecoder(schemaregistry.NewDir(dir)), defaultRoutes, svc)
+	ctx := context.Background()
+
+	created := time.Date(2024, 5, 6, 8, 30, 0, 0, time.UTC)
+	sessions, err := svc.SearchSessions(ctx, &service.SessionFilters{Created: service.TimeRange{From: created, To: created.Add(time.Minute)}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if sessions.Total != 1 || sessions.Sessions[0].SessionID != "s-1" {
+		t.Errorf("sessions created at %s = %+v, want s-1 from subject with time from the first delivery", created, sessions.Sessions)
+	}
+	if all, err := svc.SearchSessions(ctx, &service.SessionFilters{}); err != nil || all.Total != 2 {
+		t.Errorf("sessions = %+v, %v, want s-1 and the legacy s-2", all, err)
+	}
+	tickets, err := svc.SearchTickets(ctx, &service.TicketFilters{Text: "camera driver"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if tickets.Total != 1 || tickets.Tickets[0].TicketID != 7 || tickets.Tickets[0].MatchedCommentID != "t-2" {
+		t.Errorf("tickets = %+v, want ticket 7 with the comment identified by the event id", tickets.Tickets)
+	}
+	operators, err := svc.SearchOperators(ctx, &service.OperatorFilters{})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if operators.Total != 1 || operators.Operators[0].UserID != "op-1" {
+		t.Errorf("operators = %+v, want op-1 from protobuf data_base64", operators.Operators)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 1 || headerCarrier(dead[0].Headers).Get("dlq_topic") != topicTicketEvents {
+		t.Errorf("dlq = %+v, want only the event without id", dead)
+	}
+}
+
+func TestConsumeCloudEventsIgnoresOlderEvents(t *testing.T) {
+	structured := kafka.Header{Key: "content-type", Value: []byte(mediaCloudEvents)}
+	event := func(id, at, status string) []byte {
+		return []byte(`{"specversion":"1.0","id":"` + id + `","source":"/tickets","type":"ticket.updated","subject":"7",
+			"time":"` + at + `","data":{"session_id":"s-1","status":"` + status + `"}}`)
+	}
+	broker := NewMemoryBroker(topicTicketEvents)
+	broker.Produce(topicTicketEvents, nil, event("t-2", "2024-05-06T10:00:00Z", "closed"), structured)
+	broker.Produce(topicTicketEvents, nil, event("t-1", "2024-05-06T09:00:00Z", "open"), structured) // пришло позже, произошло раньше
+
+	svc := newMemoryService(t)
+	runConsumer(t, broker, svc)
+
+	tickets, err := svc.SearchTickets(context.Background(), &service.TicketFilters{Status: "closed"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if tickets.Total != 1 {
+		t.Errorf("closed tickets = %+v, want ticket 7 kept closed by the newer event", tickets.Tickets)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 0 {
+		t.Errorf("dlq = %+v, want the older event skipped, not dead-lettered", dead)
+	}
+}
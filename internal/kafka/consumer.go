@@ -2,18 +2,65 @@ package kafka
 
 import (
 	"context"
-	"log"
-	"strings"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/cache"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/metrics"
 	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/psds-microservice/search-service/internal/tenant"
 	"github.com/segmentio/kafka-go"
 )
 
-// RunConsumer запускает Kafka consumer: читает сообщения, по топику выбирает обработчик (ticket/session/operator), индексирует в ES.
-func RunConsumer(ctx context.Context, brokers []string, groupID string, topics []string, searchSvc service.SearchServicer) {
+// errSkipped — сообщение корректно прочитано, но не содержит данных для индексации.
+var errSkipped = errors.New("skipped")
+
+// errMalformed — сообщение не удалось разобрать (формат, схема, JSON): повтор не поможет.
+var errMalformed = errors.New("malformed message")
+
+// malformed помечает ошибку разбора сообщения как errMalformed.
+func malformed(err error) error {
+	return fmt.Errorf("%w: %w", errMalformed, err)
+}
+
+// terminal сообщает, что повторная обработка сообщения не поможет: оно не разбирается или отклонено
+// сервисом как некорректное (например, без арендатора). Остальные ошибки — сбои хранилища, их повторяют.
+func terminal(err error) bool {
+	return errors.Is(err, errMalformed) || helpyerrors.IsCode(err, helpyerrors.CodeInvalidArgument)
+}
+
+// Ключи обработанных CloudEvents (идемпотентность по возможности: только в памяти процесса, см. cloudevents.go):
+// сколько помнить и как долго.
+const (
+	seenEventsSize = 100000
+	seenEventsTTL  = 24 * time.Hour
+)
+
+// Повтор сообщения, которое не удалось ни обработать, ни отправить в DLQ: пауза удваивается до максимума.
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = 30 * time.Second
+)
+
+// Source — источник сообщений consumer'а: *kafka.Reader в проде, MemoryBroker в тестах.
+type Source interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// RunConsumer запускает Kafka consumer: читает сообщения, приводит их к JSON (decoder), по таблице маршрутов выбирает
+// обработчик (ticket/session/operator), индексирует в ES. Если задан dlqTopic, сообщения, которые не удалось разобрать
+// или проиндексировать, публикуются туда.
+func RunConsumer(ctx context.Context, brokers []string, groupID string, topics []string, dlqTopic string, decoder *Decoder, router *Router, searchSvc service.SearchServicer) {
+	log := logger.Component("kafka")
 	if len(brokers) == 0 || len(topics) == 0 {
-		log.Println("kafka: brokers or topics empty, consumer not started")
+		log.Warn("brokers or topics empty, consumer not started")
 		return
 	}
 
@@ -29,40 +76,139 @@ func RunConsumer(ctx context.Context, brokers []string, groupID string, topics [
 	})
 	defer r.Close()
 
-	log.Printf("kafka consumer: started, group=%s, topics=%v", groupID, topics)
+	dlq := NewDLQ(brokers, dlqTopic)
+	if dlq != nil {
+		defer dlq.Close()
+	}
+
+	log.Info("consumer started", "group", groupID, "topics", topics, "dlq", dlqTopic)
+	Consume(ctx, r, decoder, router, dlq, searchSvc)
+}
 
+// Consume обрабатывает сообщения из src до отмены ctx или закрытия src. Offset сообщения коммитится
+// после успешной обработки, пропуска или публикации в dlq (сообщение уходит туда в исходном виде).
+// Без dlq сообщение, повтор которого не поможет (terminal), отбрасывается с ошибкой в логе и коммитится.
+// Иначе, если dlq == nil или публикация не удалась, сообщение обрабатывается повторно с растущей паузой и
+// партиция стоит, пока обработка или публикация не пройдёт; при отмене ctx offset не коммитится.
+// decoder == nil — принимаются только JSON-сообщения.
+func Consume(ctx context.Context, src Source, decoder *Decoder, router *Router, dlq *DLQ, searchSvc service.SearchServicer) {
+	log := logger.Component("kafka")
+	seen := cache.NewLRU(seenEventsSize)
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("kafka consumer: stopping")
+			log.Info("consumer stopping")
 			return
 		default:
 		}
 
-		msg, err := r.ReadMessage(ctx)
+		msg, err := src.FetchMessage(ctx)
 		if err != nil {
-			if ctx.Err() != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
 				return
 			}
-			log.Printf("kafka read: %v", err)
+			log.Error("read message", logger.Err(err))
 			time.Sleep(time.Second)
 			continue
 		}
+		metrics.KafkaMessages.WithLabelValues(msg.Topic, metrics.ResultConsumed).Inc()
+		if msg.HighWaterMark > 0 {
+			metrics.KafkaLag.WithLabelValues(msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(msg.HighWaterMark - msg.Offset - 1))
+		}
+
+		msgLog := log.With("topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		msgCtx := logger.WithContext(ctx, msgLog)
+		if id := headerCarrier(msg.Headers).Get(tenant.Header); id != "" {
+			msgCtx = tenant.WithTenant(msgCtx, id)
+		}
+		for delay := retryBackoff; !process(msgCtx, msg, decoder, router, dlq, seen, searchSvc); delay = min(2*delay, maxRetryBackoff) {
+			msgLog.Warn("message not committed, retrying", "delay", delay)
+			select {
+			case <-ctx.Done():
+				log.Info("consumer stopping")
+				return
+			case <-time.After(delay):
+			}
+		}
 
-		if err := r.CommitMessages(ctx, msg); err != nil {
-			log.Printf("kafka: commit message: %v", err)
+		if err := src.CommitMessages(ctx, msg); err != nil {
+			msgLog.Error("commit message", logger.Err(err))
 		}
+	}
+}
 
-		topic := msg.Topic
-		switch {
-		case strings.HasPrefix(topic, "psds.ticket."):
-			HandleTicket(ctx, msg, searchSvc)
-		case strings.HasPrefix(topic, "psds.session."):
-			HandleSession(ctx, msg, searchSvc)
-		case strings.HasPrefix(topic, "psds.operator."):
-			HandleOperator(ctx, msg, searchSvc)
-		default:
-			log.Printf("kafka: unknown topic %q, skipping", topic)
+// process обрабатывает msg и сообщает, можно ли коммитить его offset: сообщение проиндексировано,
+// пропущено или отправлено в dlq. false — сообщение нужно обработать повторно.
+func process(ctx context.Context, msg kafka.Message, decoder *Decoder, router *Router, dlq *DLQ, seen cache.Cache, searchSvc service.SearchServicer) bool {
+	msgLog := logger.FromContext(ctx, logger.Component("kafka"))
+	spanCtx, span := startConsumeSpan(ctx, msg)
+	err := handle(spanCtx, msg, decoder, router, seen, searchSvc)
+	endConsumeSpan(span, err)
+	switch {
+	case err == nil:
+		metrics.KafkaMessages.WithLabelValues(msg.Topic, metrics.ResultIndexed).Inc()
+		return true
+	case errors.Is(err, errSkipped):
+		metrics.KafkaMessages.WithLabelValues(msg.Topic, metrics.ResultSkipped).Inc()
+		msgLog.Warn("message skipped", "reason", err.Error())
+		return true
+	case ctx.Err() != nil:
+		return false // остановка consumer'а: сообщение не в DLQ и не закоммичено
+	}
+	msgLog.Error("message failed", logger.Err(err))
+	if dlq == nil {
+		if terminal(err) {
+			msgLog.Error("message dropped: no dlq configured")
+			metrics.KafkaMessages.WithLabelValues(msg.Topic, metrics.ResultFailed).Inc()
+			return true
 		}
+		metrics.KafkaRetries.WithLabelValues(msg.Topic).Inc()
+		return false
+	}
+	if err := dlq.Publish(ctx, msg, err); err != nil {
+		msgLog.Error("publish to dlq", logger.Err(err))
+		metrics.KafkaRetries.WithLabelValues(msg.Topic).Inc()
+		return false
+	}
+	metrics.KafkaMessages.WithLabelValues(msg.Topic, metrics.ResultFailed).Inc()
+	return true
+}
+
+// handle разворачивает CloudEvent, декодирует данные и передаёт их обработчику по маршрутам.
+// Уже обработанный CloudEvent (тот же source и id) пропускается.
+func handle(ctx context.Context, msg kafka.Message, decoder *Decoder, router *Router, seen cache.Cache, searchSvc service.SearchServicer) error {
+	ce, msg, err := unwrapCloudEvent(msg)
+	if err != nil {
+		return malformed(err)
+	}
+	if ce != nil {
+		if _, dup := seen.Get(ctx, ce.key()); dup {
+			return fmt.Errorf("%w: duplicate cloudevent %s from %s", errSkipped, ce.ID, ce.Source)
+		}
+		ctx = withCloudEvent(ctx, ce)
+		if !ce.Time.IsZero() {
+			msg.Time = ce.Time // время события — умолчание для created_at/updated_at
+		}
+	}
+	value, err := decoder.Decode(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	if ce != nil && len(value) == 0 {
+		value = []byte("{}") // событие без данных: всё нужное — в атрибутах
+	}
+	msg.Value = value
+	err = router.dispatch(ctx, msg, searchSvc)
+	if ce != nil && (err == nil || errors.Is(err, errSkipped)) {
+		seen.Set(ctx, ce.key(), nil, seenEventsTTL)
+	}
+	return err
+}
+
+// eventTime возвращает время из события, а если оно не задано — timestamp Kafka-сообщения.
+func eventTime(t time.Time, msg kafka.Message) time.Time {
+	if t.IsZero() {
+		return msg.Time
 	}
+	return t
 }
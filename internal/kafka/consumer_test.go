@@ -0,0 +1,584 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/metrics"
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/psds-microservice/search-service/internal/sessionstate"
+	"github.com/psds-microservice/search-service/internal/tenant"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	topicSessionCreated  = "psds.session.created"
+	topicOperatorJoined  = "psds.session.operator_joined"
+	topicSessionEnded    = "psds.session.ended"
+	topicSessionEvents   = "psds.session.events"
+	topicTicketEvents    = "psds.ticket.events"
+	topicOperatorCreated = "psds.operator.created"
+	topicDLQ             = "psds.search.dlq"
+)
+
+// defaultRoutes — маршруты KAFKA_ROUTES по умолчанию.
+const defaultRoutes = "psds.ticket.*=ticket,psds.session.*=session,psds.operator.*=operator"
+
+// runConsumer прогоняет все сообщения broker через Consume с маршрутами по умолчанию и возвращает после их коммита.
+func runConsumer(t *testing.T, broker *MemoryBroker, svc service.SearchServicer) {
+	t.Helper()
+	runConsumerWith(t, broker, nil, defaultRoutes, svc)
+}
+
+func runConsumerWith(t *testing.T, broker *MemoryBroker, decoder *Decoder, routes string, svc service.SearchServicer) {
+	t.Helper()
+	router, err := ParseRoutes(routes)
+	if err != nil {
+		t.Fatalf("ParseRoutes: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	dlq := NewDLQWithWriter(broker, topicDLQ)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Consume(ctx, broker, decoder, router, dlq, svc)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	waitCtx, stop := context.WithTimeout(ctx, 5*time.Second)
+	defer stop()
+	if err := broker.WaitCommitted(waitCtx); err != nil {
+		t.Fatalf("messages were not committed: %v", err)
+	}
+}
+
+func newMemoryService(t *testing.T, opts ...service.Option) *service.SearchService {
+	t.Helper()
+	svc, err := service.NewSearchServiceWithIndexer(elasticsearch.NewMemory(), opts...)
+	if err != nil {
+		t.Fatalf("NewSearchServiceWithIndexer: %v", err)
+	}
+	return svc
+}
+
+func TestConsumeEventSequence(t *testing.T) {
+	broker := NewMemoryBroker(topicSessionCreated, topicOperatorJoined, topicSessionEnded, topicTicketEvents, topicOperatorCreated)
+	produce := func(topic, value string) { broker.Produce(topic, nil, []byte(value)) }
+
+	produce(topicSessionCreated, `{"event":"session.created","session_id":"s-1","client_id":"c-1","pin":"4821"}`)
+	produce(topicSessionCreated, `{"event":"session.created","session_id":"s-2","client_id":"c-2","pin":"1111"}`)
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-1","client_id":"c-1","pin":"4821","operator_id":"op-1"}`)
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-2","operator_id":"op-1"}`) // без client_id — только участник, статус не меняется
+	produce(topicSessionEnded, `{"event":"session.ended","session_id":"s-1","client_id":"c-1","pin":"4821"}`)
+	produce(topicTicketEvents, `{"event":"ticket.created","ticket_id":7,"session_id":"s-1","subject":"Cannot join","status":"open"}`)
+	produce(topicTicketEvents, `{"event":"ticket.created","ticket_id":8,"session_id":`) // malformed — в DLQ
+	produce(topicTicketEvents, `{"event":"ticket.created","session_id":"s-1"}`)         // без ticket_id — пропускается
+	produce(topicOperatorCreated, `{"event":"operator.created","user_id":"op-1","display_name":"Anna Petrova","region":"eu","role":"operator"}`)
+	produce(topicOperatorCreated, `{"event":"operator.created","user_id":"op-2"}`) // без атрибутов — пропускается
+
+	svc := newMemoryService(t)
+	runConsumer(t, broker, svc)
+	ctx := context.Background()
+
+	for topic, want := range map[string]int64{
+		topicSessionCreated:  2,
+		topicOperatorJoined:  2,
+		topicSessionEnded:    1,
+		topicTicketEvents:    3,
+		topicOperatorCreated: 2,
+	} {
+		if got := broker.Committed(topic); got != want {
+			t.Errorf("committed offset %s = %d, want %d", topic, got, want)
+		}
+	}
+
+	finished, err := svc.SearchSessions(ctx, &service.SessionFilters{Status: "finished"})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if finished.Total != 1 || finished.Sessions[0].SessionID != "s-1" {
+		t.Errorf("finished sessions = %+v, want s-1 after created → operator_joined → ended", finished.Sessions)
+	}
+	waiting, err := svc.SearchSessions(ctx, &service.SessionFilters{Status: "waiting"})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if waiting.Total != 1 || waiting.Sessions[0].SessionID != "s-2" {
+		t.Errorf("waiting sessions = %+v, want s-2 (its operator_joined has no client_id)", waiting.Sessions)
+	}
+
+	tickets, err := svc.SearchTickets(ctx, &service.TicketFilters{SessionID: "s-1"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if tickets.Total != 1 || tickets.Tickets[0].TicketID != 7 {
+		t.Errorf("tickets = %+v, want only ticket 7", tickets.Tickets)
+	}
+	operators, err := svc.SearchOperators(ctx, &service.OperatorFilters{})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if operators.Total != 1 || operators.Operators[0].UserID != "op-1" {
+		t.Errorf("operators = %+v, want only op-1", operators.Operators)
+	}
+
+	// в DLQ попадает только сообщение, которое не удалось разобрать; пропущенные — нет
+	dead := broker.Messages(topicDLQ)
+	if len(dead) != 1 {
+		t.Fatalf("dlq has %d messages, want 1", len(dead))
+	}
+	headers := headerCarrier(dead[0].Headers)
+	if headers.Get("dlq_topic") != topicTicketEvents || headers.Get("dlq_offset") != "1" || !strings.Contains(headers.Get("dlq_error"), "unmarshal") {
+		t.Errorf("dlq headers = %v", dead[0].Headers)
+	}
+}
+
+func TestConsumeTimestampsFallBackToMessageTime(t *testing.T) {
+	broker := NewMemoryBroker(topicSessionEnded)
+	sent := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	broker.WriteMessages(context.Background(),
+		kafka.Message{Topic: topicSessionEnded, Time: sent, Value: []byte(`{"event":"session.ended","session_id":"s-1","client_id":"c-1","created_at":"2024-05-06T11:00:00Z"}`)},
+		kafka.Message{Topic: topicSessionEnded, Time: sent, Value: []byte(`{"event":"session.ended","session_id":"s-2","client_id":"c-2","ended_at":"2024-05-05T23:00:00Z"}`)},
+	)
+
+	svc := newMemoryService(t)
+	runConsumer(t, broker, svc)
+
+	day := service.TimeRange{From: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)}
+	res, err := svc.SearchSessions(context.Background(), &service.SessionFilters{Ended: day, Created: service.TimeRange{From: sent.Add(-time.Hour)}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	// s-1: ended_at из времени сообщения, created_at из события; s-2: ended_at из события (вчера)
+	if res.Total != 1 || res.Sessions[0].SessionID != "s-1" {
+		t.Errorf("sessions ended on the day = %+v, want only s-1", res.Sessions)
+	}
+}
+
+func TestConsumeTenantHeader(t *testing.T) {
+	broker := NewMemoryBroker(topicSessionCreated)
+	broker.Produce(topicSessionCreated, nil, []byte(`{"session_id":"s-1","client_id":"c-1"}`), kafka.Header{Key: tenant.Header, Value: []byte("acme")})
+	broker.Produce(topicSessionCreated, nil, []byte(`{"session_id":"s-2","client_id":"c-2"}`)) // без арендатора — ошибка, в DLQ
+
+	svc := newMemoryService(t, service.WithTenancy(tenant.ModeFilter))
+	runConsumer(t, broker, svc)
+
+	res, err := svc.SearchSessions(tenant.WithTenant(context.Background(), "acme"), &service.SessionFilters{})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 1 || res.Sessions[0].SessionID != "s-1" {
+		t.Errorf("acme sessions = %+v, want only s-1", res.Sessions)
+	}
+	if got := broker.Committed(topicSessionCreated); got != 2 {
+		t.Errorf("committed offset = %d, want 2", got)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 1 || string(dead[0].Value) != `{"session_id":"s-2","client_id":"c-2"}` {
+		t.Errorf("dlq = %+v, want the message without tenant", dead)
+	}
+}
+
+func TestConsumeStopsWhenSourceClosed(t *testing.T) {
+	broker := NewMemoryBroker(topicSessionCreated)
+	router, err := ParseRoutes(defaultRoutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Consume(context.Background(), broker, nil, router, nil, newMemoryService(t))
+	}()
+	broker.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consume did not return after the source was closed")
+	}
+}
+
+// flakyService отклоняет первые fails вызовов IndexSession.
+type flakyService struct {
+	service.SearchServicer
+	mu    sync.Mutex
+	fails int
+	calls int
+}
+
+func (s *flakyService) IndexSession(ctx context.Context, in *service.IndexSessionInput) error {
+	s.mu.Lock()
+	s.calls++
+	fail := s.calls <= s.fails
+	s.mu.Unlock()
+	if fail {
+		return errors.New("elasticsearch error: 503 Service Unavailable")
+	}
+	return s.SearchServicer.IndexSession(ctx, in)
+}
+
+// flakyWriter отклоняет первые fails записей в DLQ и сообщает о каждой попытке в attempts.
+type flakyWriter struct {
+	*MemoryBroker
+	mu       sync.Mutex
+	fails    int
+	attempts chan struct{}
+}
+
+func (w *flakyWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	w.fails--
+	fail := w.fails >= 0
+	w.mu.Unlock()
+	select {
+	case w.attempts <- struct{}{}:
+	default:
+	}
+	if fail {
+		return errors.New("kafka: leader not available")
+	}
+	return w.MemoryBroker.WriteMessages(ctx, msgs...)
+}
+
+// startConsume запускает Consume с маршрутами по умолчанию; возвращённая функция останавливает его.
+func startConsume(t *testing.T, broker *MemoryBroker, dlq *DLQ, svc service.SearchServicer) (stop func()) {
+	t.Helper()
+	router, err := ParseRoutes(defaultRoutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Consume(ctx, broker, nil, router, dlq, svc)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// counts — значения метрик Kafka по одному топику.
+type counts struct {
+	indexed, failed, retries float64
+}
+
+func kafkaCounts(topic string) counts {
+	return counts{
+		indexed: counterValue(metrics.KafkaMessages.WithLabelValues(topic, metrics.ResultIndexed)),
+		failed:  counterValue(metrics.KafkaMessages.WithLabelValues(topic, metrics.ResultFailed)),
+		retries: counterValue(metrics.KafkaRetries.WithLabelValues(topic)),
+	}
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		panic(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func (c counts) sub(o counts) counts {
+	return counts{indexed: c.indexed - o.indexed, failed: c.failed - o.failed, retries: c.retries - o.retries}
+}
+
+func waitCommitted(t *testing.T, broker *MemoryBroker) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := broker.WaitCommitted(ctx); err != nil {
+		t.Fatalf("messages were not committed: %v", err)
+	}
+}
+
+func TestConsumeCommitsOnlyHandledMessages(t *testing.T) {
+	t.Run("failed without dlq is retried", func(t *testing.T) {
+		broker := NewMemoryBroker(topicSessionCreated)
+		broker.Produce(topicSessionCreated, nil, []byte(`{"event":"session.created","session_id":"s-1","client_id":"c-1"}`))
+		svc := &flakyService{SearchServicer: newMemoryService(t), fails: 1}
+		before := kafkaCounts(topicSessionCreated)
+		startConsume(t, broker, nil, svc)
+		waitCommitted(t, broker)
+
+		if svc.calls != 2 {
+			t.Errorf("IndexSession called %d times, want a retry after the failure", svc.calls)
+		}
+		if got, want := kafkaCounts(topicSessionCreated).sub(before), (counts{indexed: 1, retries: 1}); got != want {
+			t.Errorf("metrics = %+v, want %+v: a message that succeeds on retry is not failed", got, want)
+		}
+		if sessions, err := svc.SearchSessions(context.Background(), &service.SessionFilters{}); err != nil || sessions.Total != 1 {
+			t.Errorf("sessions = %+v, %v; want s-1 indexed on retry", sessions, err)
+		}
+	})
+
+	t.Run("malformed without dlq is committed", func(t *testing.T) {
+		broker := NewMemoryBroker(topicTicketEvents, topicSessionCreated)
+		broker.Produce(topicTicketEvents, nil, []byte(`{"event":"ticket.created","ticket_id":8,"session_id":`))
+		broker.Produce(topicSessionCreated, nil, []byte(`{"session_id":"s-1","client_id":"c-1"}`)) // без арендатора
+		broker.Produce(topicSessionCreated, nil, []byte(`{"session_id":"s-2","client_id":"c-2"}`), kafka.Header{Key: tenant.Header, Value: []byte("acme")})
+		svc := newMemoryService(t, service.WithTenancy(tenant.ModeFilter))
+		startConsume(t, broker, nil, svc)
+		waitCommitted(t, broker)
+
+		if got := broker.Committed(topicTicketEvents); got != 1 {
+			t.Errorf("committed offset %s = %d, want the malformed message committed", topicTicketEvents, got)
+		}
+		if got := broker.Committed(topicSessionCreated); got != 2 {
+			t.Errorf("committed offset %s = %d, want 2", topicSessionCreated, got)
+		}
+		res, err := svc.SearchSessions(tenant.WithTenant(context.Background(), "acme"), &service.SessionFilters{})
+		if err != nil || res.Total != 1 || res.Sessions[0].SessionID != "s-2" {
+			t.Errorf("acme sessions = %+v, %v; want s-2 indexed after the dropped message", res, err)
+		}
+	})
+
+	t.Run("dlq publish failure is not committed", func(t *testing.T) {
+		broker := NewMemoryBroker(topicTicketEvents)
+		broker.Produce(topicTicketEvents, nil, []byte(`{"event":"ticket.created","ticket_id":8,"session_id":`))
+		w := &flakyWriter{MemoryBroker: broker, fails: 1 << 30, attempts: make(chan struct{}, 1)}
+		stop := startConsume(t, broker, NewDLQWithWriter(w, topicDLQ), newMemoryService(t))
+		select {
+		case <-w.attempts:
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not published to the dlq")
+		}
+		stop()
+		if got := broker.Committed(topicTicketEvents); got != 0 {
+			t.Errorf("committed offset = %d after a failed dlq publish, want 0", got)
+		}
+	})
+
+	t.Run("dlq publish is retried", func(t *testing.T) {
+		broker := NewMemoryBroker(topicTicketEvents)
+		broker.Produce(topicTicketEvents, nil, []byte(`{"event":"ticket.created","ticket_id":8,"session_id":`))
+		w := &flakyWriter{MemoryBroker: broker, fails: 1, attempts: make(chan struct{}, 1)}
+		before := kafkaCounts(topicTicketEvents)
+		startConsume(t, broker, NewDLQWithWriter(w, topicDLQ), newMemoryService(t))
+		waitCommitted(t, broker)
+		if dead := broker.Messages(topicDLQ); len(dead) != 1 {
+			t.Errorf("dlq has %d messages, want 1 after the retried publish", len(dead))
+		}
+		if got, want := kafkaCounts(topicTicketEvents).sub(before), (counts{failed: 1, retries: 1}); got != want {
+			t.Errorf("metrics = %+v, want %+v: failed is counted once per message", got, want)
+		}
+	})
+}
+
+func TestConsumeTicketComments(t *testing.T) {
+	broker := NewMemoryBroker(topicTicketEvents)
+	produce := func(value string) { broker.Produce(topicTicketEvents, nil, []byte(value)) }
+
+	// комментарий к тикету 8 приходит раньше самого тикета
+	produce(`{"event":"ticket.comment_added","ticket_id":8,"comment_id":"cm-1","author":"op-1","text":"Waiting for the client to send logs"}`)
+	produce(`{"event":"ticket.created","ticket_id":7,"session_id":"s-1","subject":"Cannot join","status":"open"}`)
+	produce(`{"event":"ticket.comment_added","ticket_id":7,"comment_id":"cm-2","author":"op-1","text":"Camera driver crashes on join"}`)
+	produce(`{"event":"ticket.comment_added","ticket_id":7,"comment_id":"cm-2","author":"op-1","text":"Camera driver crashes on join"}`) // повторная доставка
+	produce(`{"event":"ticket.comment_added","ticket_id":7,"author":"c-1","text":"Reinstalled the driver, still broken"}`)               // без comment_id
+	produce(`{"event":"ticket.created","ticket_id":8,"session_id":"s-2","subject":"Logs","status":"open"}`)
+	produce(`{"event":"ticket.comment_added","ticket_id":8,"author":"op-1"}`) // без текста — пропускается
+
+	svc := newMemoryService(t)
+	runConsumer(t, broker, svc)
+	ctx := context.Background()
+
+	res, err := svc.SearchTickets(ctx, &service.TicketFilters{Text: "camera driver"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 1 || res.Tickets[0].TicketID != 7 || res.Tickets[0].MatchedCommentID != "cm-2" || res.Tickets[0].Snippet != "Camera driver crashes on join" {
+		t.Fatalf("tickets = %+v, want ticket 7 matched by comment cm-2", res.Tickets)
+	}
+	res, err = svc.SearchTickets(ctx, &service.TicketFilters{Text: "still broken"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 1 || res.Tickets[0].MatchedCommentID != topicTicketEvents+"/0/4" {
+		t.Fatalf("tickets = %+v, want the comment identified by message coordinates", res.Tickets)
+	}
+	// тикет 8, созданный после комментария, сохранил комментарий и получил поля тикета
+	res, err = svc.SearchTickets(ctx, &service.TicketFilters{Text: "logs", SessionID: "s-2"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 1 || res.Tickets[0].TicketID != 8 || res.Tickets[0].Subject != "Logs" {
+		t.Fatalf("tickets = %+v, want ticket 8", res.Tickets)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 0 {
+		t.Fatalf("dlq = %+v, want empty", dead)
+	}
+}
+
+func TestConsumeSessionParticipants(t *testing.T) {
+	broker := NewMemoryBroker(topicSessionCreated, topicOperatorJoined, topicSessionEnded)
+	produce := func(topic, value string) { broker.Produce(topic, nil, []byte(value)) }
+
+	produce(topicSessionCreated, `{"event":"session.created","session_id":"s-1","client_id":"c-1"}`)
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-1","client_id":"c-1","operator_id":"op-1"}`)
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-1","client_id":"c-1","operator_id":"op-1"}`) // повторная доставка
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-1","user_id":"op-2"}`)                       // user_id вместо operator_id
+	produce(topicOperatorJoined, `{"event":"operator_left","session_id":"s-1","operator_id":"op-1"}`)
+	produce(topicOperatorJoined, `{"event":"operator_joined","session_id":"s-2","operator_id":"op-2"}`) // сессия ещё не создана
+	produce(topicOperatorJoined, `{"event":"operator_left","session_id":"s-2"}`)                        // без оператора — пропускается
+	produce(topicSessionEnded, `{"event":"session.ended","session_id":"s-2","client_id":"c-2"}`)        // закрывает подключение op-2
+
+	es := elasticsearch.NewMemory()
+	svc, err := service.NewSearchServiceWithIndexer(es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runConsumer(t, broker, svc)
+	ctx := context.Background()
+
+	res, err := svc.SearchSessions(ctx, &service.SessionFilters{OperatorID: "op-2"})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 2 {
+		t.Fatalf("sessions of op-2 = %+v, want s-1 and s-2", res.Sessions)
+	}
+	res, err = svc.SearchSessions(ctx, &service.SessionFilters{OperatorIDs: []string{"op-1", "op-9"}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 1 {
+		t.Fatalf("sessions of op-1 = %+v, want s-1", res.Sessions)
+	}
+	if h := res.Sessions[0]; h.SessionID != "s-1" || h.Status != "active" || h.ParticipantCount != 2 || strings.Join(h.OperatorIDs, ",") != "op-1,op-2" {
+		t.Fatalf("s-1 = %+v, want active with op-1, op-2", h)
+	}
+
+	doc, err := es.GetDocument(ctx, "sessions", "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["active_participant_count"] != float64(1) {
+		t.Fatalf("s-1 = %v, want only op-2 connected", doc)
+	}
+	doc, err = es.GetDocument(ctx, "sessions", "s-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["status"] != "finished" || doc["active_participant_count"] != float64(0) || doc["participant_count"] != float64(1) {
+		t.Fatalf("s-2 = %v, want finished with its only participation closed", doc)
+	}
+}
+
+func TestConsumeSessionStatusTransitions(t *testing.T) {
+	states, err := sessionstate.Load("../../deployments/session_states.example.json")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	broker := NewMemoryBroker(topicSessionEvents)
+	produce := func(value string) { broker.Produce(topicSessionEvents, nil, []byte(value)) }
+
+	produce(`{"event":"session.created","session_id":"s-1","client_id":"c-1"}`)
+	produce(`{"event":"operator_joined","session_id":"s-1","client_id":"c-1","operator_id":"op-1"}`)
+	produce(`{"event":"session.updated","session_id":"s-1","client_id":"c-1","pin":"4821"}`) // неизвестное событие — статус не меняется
+	produce(`{"event":"session.created","session_id":"s-2","client_id":"c-2"}`)
+	produce(`{"event":"session.ended","session_id":"s-2","client_id":"c-2"}`)
+	produce(`{"event":"session.created","session_id":"s-2","client_id":"c-2"}`) // finished → waiting — аномалия
+	produce(`{"event":"session.created","session_id":"s-3","client_id":"c-3"}`)
+	produce(`{"event":"session.cancelled","session_id":"s-3","client_id":"c-3"}`)
+	produce(`{"event":"session.updated","session_id":"s-3","client_id":"c-3","status":"active"}`) // cancelled → active — аномалия
+
+	es := elasticsearch.NewMemory()
+	svc, err := service.NewSearchServiceWithIndexer(es, service.WithSessionStates(states))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runConsumer(t, broker, svc)
+	ctx := context.Background()
+
+	for id, want := range map[string]string{"s-1": "active", "s-2": "finished", "s-3": "cancelled"} {
+		doc, err := es.GetDocument(ctx, "sessions", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc["status"] != want {
+			t.Errorf("%s status = %v, want %s", id, doc["status"], want)
+		}
+		if _, ended := doc["ended_at"]; ended != (want != "active") {
+			t.Errorf("%s ended_at = %v, want it set only for terminal statuses", id, doc["ended_at"])
+		}
+	}
+	if got := broker.Committed(topicSessionEvents); got != 9 {
+		t.Errorf("committed offset = %d, want 9", got)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 0 {
+		t.Errorf("dlq has %d messages, want rejected transitions to be skipped", len(dead))
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	for _, routes := range []string{
+		"",
+		"psds.ticket.*",
+		"psds.ticket.*=tickets",
+		":ticket.*=ticket",
+		"psds.ticket.[=ticket",
+	} {
+		if _, err := ParseRoutes(routes); err == nil {
+			t.Errorf("ParseRoutes(%q) succeeded, want error", routes)
+		}
+	}
+
+	router, err := ParseRoutes(defaultRoutes + ", psds.events:ticket.*=ticket")
+	if err != nil {
+		t.Fatalf("ParseRoutes: %v", err)
+	}
+	if err := router.Validate([]string{topicTicketEvents, topicSessionCreated, "psds.events"}); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	err = router.Validate([]string{topicTicketEvents, "psds.billing.events", "ticket.events"})
+	if err == nil || !strings.Contains(err.Error(), "psds.billing.events, ticket.events") {
+		t.Errorf("Validate = %v, want both unrouted topics reported", err)
+	}
+}
+
+func TestConsumeRoutesByEventType(t *testing.T) {
+	const topicEvents = "psds.events"
+	broker := NewMemoryBroker(topicEvents)
+	produce := func(value string) { broker.Produce(topicEvents, nil, []byte(value)) }
+
+	produce(`{"event":"session.created","session_id":"s-1","client_id":"c-1"}`)
+	produce(`{"event":"ticket.created","ticket_id":7,"session_id":"s-1","subject":"Cannot join","status":"open"}`)
+	produce(`{"event":"operator.created","user_id":"op-1","display_name":"Anna Petrova","region":"eu"}`)
+	produce(`{"event":"billing.invoiced","invoice_id":"i-1"}`) // нет маршрута — пропускается
+	produce(`{"event":`)                                       // тип события не читается — в DLQ
+
+	svc := newMemoryService(t)
+	runConsumerWith(t, broker, nil, "psds.events:session.*=session,psds.events:ticket.*=ticket,psds.events:operator.*=operator", svc)
+	ctx := context.Background()
+
+	sessions, err := svc.SearchSessions(ctx, &service.SessionFilters{})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	tickets, err := svc.SearchTickets(ctx, &service.TicketFilters{})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	operators, err := svc.SearchOperators(ctx, &service.OperatorFilters{})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if sessions.Total != 1 || tickets.Total != 1 || operators.Total != 1 {
+		t.Errorf("indexed %d sessions, %d tickets, %d operators, want one of each", sessions.Total, tickets.Total, operators.Total)
+	}
+	if dead := broker.Messages(topicDLQ); len(dead) != 1 || string(dead[0].Value) != `{"event":` {
+		t.Errorf("dlq = %+v, want only the malformed message", dead)
+	}
+}
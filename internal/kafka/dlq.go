@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/metrics"
+	"github.com/segmentio/kafka-go"
+)
+
+// DLQ публикует сообщения, которые не удалось обработать, в dead letter топик.
+// Исходные ключ и значение сохраняются, источник и причина — в заголовках dlq_*.
+type DLQ struct {
+	w     MessageWriter
+	topic string
+}
+
+// MessageWriter пишет сообщения в топики, указанные в самих сообщениях: *kafka.Writer без Topic или MemoryBroker.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewDLQ создаёт publisher для топика topic. Пустой topic — DLQ выключен (nil).
+func NewDLQ(brokers []string, topic string) *DLQ {
+	if topic == "" {
+		return nil
+	}
+	return NewDLQWithWriter(&kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+	}, topic)
+}
+
+// NewDLQWithWriter создаёт publisher в топик topic поверх произвольного MessageWriter (например, MemoryBroker в тестах).
+func NewDLQWithWriter(w MessageWriter, topic string) *DLQ {
+	return &DLQ{w: w, topic: topic}
+}
+
+// Publish отправляет msg в DLQ с указанием причины cause.
+func (d *DLQ) Publish(ctx context.Context, msg kafka.Message, cause error) error {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "dlq_topic", Value: []byte(msg.Topic)},
+		kafka.Header{Key: "dlq_partition", Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: "dlq_offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: "dlq_error", Value: []byte(cause.Error())},
+	)
+	err := d.w.WriteMessages(ctx, kafka.Message{Topic: d.topic, Key: msg.Key, Value: msg.Value, Headers: headers})
+	metrics.KafkaDLQPublished.WithLabelValues(msg.Topic, metrics.Outcome(err)).Inc()
+	return err
+}
+
+// Close закрывает writer.
+func (d *DLQ) Close() error {
+	return d.w.Close()
+}
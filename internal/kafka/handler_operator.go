@@ -3,46 +3,55 @@ package kafka
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"time"
 
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/logger"
 	"github.com/psds-microservice/search-service/internal/service"
 	"github.com/segmentio/kafka-go"
 )
 
 // OperatorEvent — событие оператора из топика psds.operator.*
 type OperatorEvent struct {
-	Event       string `json:"event"`
-	UserID      string `json:"user_id"`
-	DisplayName string `json:"display_name,omitempty"`
-	Region      string `json:"region,omitempty"`
-	Role        string `json:"role,omitempty"`
+	Event       string    `json:"event"`
+	UserID      string    `json:"user_id"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Region      string    `json:"region,omitempty"`
+	Role        string    `json:"role,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"` // RFC 3339; для нового документа по умолчанию updated_at
+	UpdatedAt   time.Time `json:"updated_at,omitzero"` // RFC 3339; по умолчанию время сообщения
 }
 
 // HandleOperator обрабатывает сообщение из топика операторов и индексирует в ES.
-func HandleOperator(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) {
-	topic := msg.Topic
+// Возвращает ошибку с errSkipped для пропущенных сообщений.
+func HandleOperator(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) error {
 	var ev OperatorEvent
 	if err := json.Unmarshal(msg.Value, &ev); err != nil {
-		log.Printf("kafka: [%s] unmarshal operator event: %v", topic, err)
-		return
+		return malformed(fmt.Errorf("unmarshal operator event: %w", err))
 	}
+	cloudEventFrom(ctx).apply(&ev.Event, &ev.UserID)
 	if ev.UserID == "" {
-		log.Printf("kafka: [%s] missing user_id, skipping", topic)
-		return
+		return fmt.Errorf("%w: missing user_id", errSkipped)
 	}
 	if ev.DisplayName == "" && ev.Region == "" && ev.Role == "" {
-		log.Printf("kafka: [%s] operator %s missing display_name/region/role, skipping", topic, ev.UserID)
-		return
+		return fmt.Errorf("%w: operator %s missing display_name/region/role", errSkipped, ev.UserID)
 	}
 	in := &service.IndexOperatorInput{
 		UserID:      ev.UserID,
 		DisplayName: ev.DisplayName,
 		Region:      ev.Region,
 		Role:        ev.Role,
+		CreatedAt:   ev.CreatedAt,
+		UpdatedAt:   eventTime(ev.UpdatedAt, msg),
 	}
 	if err := searchSvc.IndexOperator(ctx, in); err != nil {
-		log.Printf("kafka: [%s] index operator %s: %v", topic, ev.UserID, err)
-		return
+		if helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+			return fmt.Errorf("%w: %v", errSkipped, err) // устаревшее событие: повтор не поможет
+		}
+		return fmt.Errorf("index operator %s: %w", ev.UserID, err)
 	}
-	log.Printf("kafka: [%s] indexed operator %s", topic, ev.UserID)
+	logger.FromContext(ctx, slog.Default()).Info("indexed operator", "user_id", ev.UserID)
+	return nil
 }
@@ -3,59 +3,96 @@ package kafka
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"time"
 
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/logger"
 	"github.com/psds-microservice/search-service/internal/service"
 	"github.com/segmentio/kafka-go"
 )
 
+// События участников сессии: operator_id (или user_id) подключился / отключился.
+const (
+	eventOperatorJoined = "operator_joined"
+	eventOperatorLeft   = "operator_left"
+)
+
 // SessionEvent — событие сессии из топика psds.session.*
 type SessionEvent struct {
-	Event      string `json:"event"`
-	SessionID  string `json:"session_id"`
-	ClientID   string `json:"client_id,omitempty"`
-	PIN        string `json:"pin,omitempty"`
-	Status     string `json:"status,omitempty"`
-	UserID     string `json:"user_id,omitempty"`
-	OperatorID string `json:"operator_id,omitempty"`
+	Event      string    `json:"event"`
+	SessionID  string    `json:"session_id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	PIN        string    `json:"pin,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	OperatorID string    `json:"operator_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitzero"` // RFC 3339; для нового документа по умолчанию updated_at
+	UpdatedAt  time.Time `json:"updated_at,omitzero"` // RFC 3339; по умолчанию время сообщения
+	EndedAt    time.Time `json:"ended_at,omitzero"`   // RFC 3339; для завершённой сессии по умолчанию время сообщения
 }
 
-// HandleSession обрабатывает сообщение из топика сессий и индексирует в ES.
-func HandleSession(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) {
-	topic := msg.Topic
+// HandleSession обрабатывает сообщение из топика сессий и индексирует в ES. operator_joined/operator_left
+// обновляют участников сессии. Статус без явного status выводит машина состояний сервиса по типу события
+// (operator_joined — только при наличии client_id); запрещённый переход пропускает сообщение.
+// Возвращает ошибку с errSkipped для пропущенных сообщений.
+func HandleSession(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) error {
 	var ev SessionEvent
 	if err := json.Unmarshal(msg.Value, &ev); err != nil {
-		log.Printf("kafka: [%s] unmarshal session event: %v", topic, err)
-		return
+		return malformed(fmt.Errorf("unmarshal session event: %w", err))
 	}
+	cloudEventFrom(ctx).apply(&ev.Event, &ev.SessionID)
 	if ev.SessionID == "" {
-		log.Printf("kafka: [%s] missing session_id, skipping", topic)
-		return
+		return fmt.Errorf("%w: missing session_id", errSkipped)
 	}
-	status := ev.Status
-	if status == "" {
-		switch ev.Event {
-		case "session.ended", "session.finished":
-			status = "finished"
-		case "operator_joined":
-			status = "active"
-		default:
-			status = "waiting"
+	tracked := false
+	if ev.Event == eventOperatorJoined || ev.Event == eventOperatorLeft {
+		operatorID := ev.OperatorID
+		if operatorID == "" {
+			operatorID = ev.UserID
+		}
+		if operatorID != "" {
+			in := &service.SessionParticipantInput{
+				SessionID:  ev.SessionID,
+				OperatorID: operatorID,
+				At:         eventTime(ev.UpdatedAt, msg),
+				Left:       ev.Event == eventOperatorLeft,
+			}
+			if err := searchSvc.TrackSessionParticipant(ctx, in); err != nil {
+				return fmt.Errorf("track operator %s in session %s: %w", operatorID, ev.SessionID, err)
+			}
+			tracked = true
+		}
+		if ev.Event == eventOperatorLeft {
+			if !tracked {
+				return fmt.Errorf("%w: session %s missing operator_id", errSkipped, ev.SessionID)
+			}
+			return nil // статус сессии не меняется
 		}
 	}
 	if ev.ClientID == "" {
-		log.Printf("kafka: [%s] session %s missing client_id, skipping", topic, ev.SessionID)
-		return
+		if tracked {
+			return nil // участник учтён, данных для индексации самой сессии нет
+		}
+		return fmt.Errorf("%w: session %s missing client_id", errSkipped, ev.SessionID)
 	}
 	in := &service.IndexSessionInput{
 		SessionID: ev.SessionID,
 		ClientID:  ev.ClientID,
 		PIN:       ev.PIN,
-		Status:    status,
+		Status:    ev.Status,
+		Event:     ev.Event,
+		CreatedAt: ev.CreatedAt,
+		UpdatedAt: eventTime(ev.UpdatedAt, msg),
+		EndedAt:   ev.EndedAt,
 	}
 	if err := searchSvc.IndexSession(ctx, in); err != nil {
-		log.Printf("kafka: [%s] index session %s: %v", topic, ev.SessionID, err)
-		return
+		if helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+			return fmt.Errorf("%w: %v", errSkipped, err) // запрещённый переход статуса или устаревшее событие: повтор не поможет
+		}
+		return fmt.Errorf("index session %s: %w", ev.SessionID, err)
 	}
-	log.Printf("kafka: [%s] indexed session %s", topic, ev.SessionID)
+	logger.FromContext(ctx, slog.Default()).Info("indexed session", "session_id", ev.SessionID)
+	return nil
 }
@@ -3,47 +3,84 @@ package kafka
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
 
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/logger"
 	"github.com/psds-microservice/search-service/internal/service"
 	"github.com/segmentio/kafka-go"
 )
 
+// eventTicketCommentAdded — событие нового комментария тикета: ticket_id, comment_id, author, text, created_at.
+const eventTicketCommentAdded = "ticket.comment_added"
+
 // TicketEvent — событие тикета из топика psds.ticket.*
 type TicketEvent struct {
-	Event      string `json:"event"`
-	TicketID   int64  `json:"ticket_id"`
-	SessionID  string `json:"session_id"`
-	ClientID   string `json:"client_id,omitempty"`
-	OperatorID string `json:"operator_id,omitempty"`
-	Subject    string `json:"subject,omitempty"`
-	Notes      string `json:"notes,omitempty"`
-	Status     string `json:"status,omitempty"`
+	Event      string    `json:"event"`
+	TicketID   int64     `json:"ticket_id"`
+	SessionID  string    `json:"session_id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	OperatorID string    `json:"operator_id,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`  // RFC 3339; для нового документа — updated_at, для комментария — время сообщения
+	UpdatedAt  time.Time `json:"updated_at,omitzero"`  // RFC 3339; по умолчанию время сообщения
+	CommentID  string    `json:"comment_id,omitempty"` // ticket.comment_added; по умолчанию координаты сообщения
+	Author     string    `json:"author,omitempty"`     // ticket.comment_added
+	Text       string    `json:"text,omitempty"`       // ticket.comment_added
 }
 
-// HandleTicket обрабатывает сообщение из топика тикетов и индексирует в ES.
-func HandleTicket(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) {
-	topic := msg.Topic
-	var raw map[string]interface{}
-	if err := json.Unmarshal(msg.Value, &raw); err != nil {
-		log.Printf("kafka: [%s] unmarshal: %v", topic, err)
-		return
+// UnmarshalJSON принимает ticket_id числом (в том числе 7.0) или строкой с числом: так его кодирует
+// protojson для int64.
+func (ev *TicketEvent) UnmarshalJSON(data []byte) error {
+	type plain TicketEvent
+	aux := struct {
+		*plain
+		TicketID json.Number `json:"ticket_id"`
+	}{plain: (*plain)(ev)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	ev.TicketID = 0
+	if aux.TicketID == "" {
+		return nil
+	}
+	id, err := aux.TicketID.Int64()
+	if err != nil {
+		f, ferr := aux.TicketID.Float64()
+		if ferr != nil || f != math.Trunc(f) {
+			return fmt.Errorf("ticket_id %s is not an integer", aux.TicketID)
+		}
+		id = int64(f)
 	}
+	ev.TicketID = id
+	return nil
+}
+
+// HandleTicket обрабатывает сообщение из топика тикетов и индексирует в ES; ticket.comment_added
+// добавляет комментарий в уже проиндексированный (или будущий) тикет.
+// Возвращает ошибку с errSkipped для пропущенных сообщений.
+func HandleTicket(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) error {
 	var ev TicketEvent
 	if err := json.Unmarshal(msg.Value, &ev); err != nil {
-		log.Printf("kafka: [%s] unmarshal ticket event: %v", topic, err)
-		return
-	}
-	if ev.TicketID == 0 && raw["ticket_id"] != nil {
-		if tid, ok := raw["ticket_id"].(float64); ok {
-			ev.TicketID = int64(tid)
-		} else if tid, ok := raw["ticket_id"].(int64); ok {
-			ev.TicketID = tid
+		return malformed(fmt.Errorf("unmarshal ticket event: %w", err))
+	}
+	if ce := cloudEventFrom(ctx); ce != nil {
+		ev.Event = ce.Type
+		if ev.TicketID == 0 {
+			ev.TicketID, _ = strconv.ParseInt(ce.Subject, 10, 64) // нечисловой subject — тикет без id, пропускается
 		}
 	}
+	if ev.Event == eventTicketCommentAdded {
+		return handleTicketComment(ctx, msg, &ev, searchSvc)
+	}
 	if ev.TicketID == 0 || ev.SessionID == "" {
-		log.Printf("kafka: [%s] missing ticket_id or session_id, skipping", topic)
-		return
+		return fmt.Errorf("%w: missing ticket_id or session_id", errSkipped)
 	}
 	in := &service.IndexTicketInput{
 		TicketID:   ev.TicketID,
@@ -53,10 +90,42 @@ func HandleTicket(ctx context.Context, msg kafka.Message, searchSvc service.Sear
 		Subject:    ev.Subject,
 		Notes:      ev.Notes,
 		Status:     ev.Status,
+		CreatedAt:  ev.CreatedAt,
+		UpdatedAt:  eventTime(ev.UpdatedAt, msg),
 	}
 	if err := searchSvc.IndexTicket(ctx, in); err != nil {
-		log.Printf("kafka: [%s] index ticket %d: %v", topic, ev.TicketID, err)
-		return
+		if helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+			return fmt.Errorf("%w: %v", errSkipped, err) // устаревшее событие: повтор не поможет
+		}
+		return fmt.Errorf("index ticket %d: %w", ev.TicketID, err)
+	}
+	logger.FromContext(ctx, slog.Default()).Info("indexed ticket", "ticket_id", ev.TicketID)
+	return nil
+}
+
+// handleTicketComment добавляет комментарий в тикет. Без comment_id идентификатором служат id CloudEvent
+// или координаты сообщения: повторная доставка того же сообщения не дублирует комментарий.
+func handleTicketComment(ctx context.Context, msg kafka.Message, ev *TicketEvent, searchSvc service.SearchServicer) error {
+	if ev.TicketID == 0 || ev.Text == "" {
+		return fmt.Errorf("%w: missing ticket_id or text", errSkipped)
+	}
+	commentID := ev.CommentID
+	if ce := cloudEventFrom(ctx); commentID == "" && ce != nil {
+		commentID = ce.ID
+	}
+	if commentID == "" {
+		commentID = fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+	}
+	in := &service.TicketCommentInput{
+		TicketID:  ev.TicketID,
+		CommentID: commentID,
+		Author:    ev.Author,
+		Text:      ev.Text,
+		CreatedAt: eventTime(ev.CreatedAt, msg),
+	}
+	if err := searchSvc.AddTicketComment(ctx, in); err != nil {
+		return fmt.Errorf("add comment to ticket %d: %w", ev.TicketID, err)
 	}
-	log.Printf("kafka: [%s] indexed ticket %d", topic, ev.TicketID)
+	logger.FromContext(ctx, slog.Default()).Info("indexed ticket comment", "ticket_id", ev.TicketID, "comment_id", commentID)
+	return nil
 }
@@ -0,0 +1,166 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// MemoryBroker — брокер в памяти вместо Kafka: Source для Consume и MessageWriter для DLQ.
+// У каждого топика одна партиция (0); сообщения подписанных топиков отдаются в порядке записи,
+// коммиты запоминаются как в Kafka — offset следующего непрочитанного сообщения.
+// Для тестов worker'а без брокера.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	changed    chan struct{} // закрывается при любом изменении состояния (ожидающие перечитывают его)
+	subscribed map[string]bool
+	topics     map[string][]kafka.Message
+	pending    []kafka.Message // сообщения подписанных топиков в порядке записи
+	next       int             // индекс следующего сообщения для FetchMessage
+	committed  map[string]int64
+	closed     bool
+}
+
+// NewMemoryBroker создаёт брокер, FetchMessage которого отдаёт сообщения топиков topics.
+func NewMemoryBroker(topics ...string) *MemoryBroker {
+	b := &MemoryBroker{
+		changed:    make(chan struct{}),
+		subscribed: make(map[string]bool, len(topics)),
+		topics:     make(map[string][]kafka.Message),
+		committed:  make(map[string]int64),
+	}
+	for _, t := range topics {
+		b.subscribed[t] = true
+	}
+	return b
+}
+
+// Produce записывает сообщение в topic и возвращает его offset.
+func (b *MemoryBroker) Produce(topic string, key, value []byte, headers ...kafka.Header) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.append(kafka.Message{Topic: topic, Key: key, Value: value, Headers: headers})
+}
+
+// WriteMessages записывает сообщения в их топики (msg.Topic обязателен).
+func (b *MemoryBroker) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return io.ErrClosedPipe
+	}
+	for _, msg := range msgs {
+		b.append(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) append(msg kafka.Message) int64 {
+	msg.Partition = 0
+	if msg.Time.IsZero() {
+		msg.Time = time.Now() // как LogAppendTime в Kafka
+	}
+	msg.Offset = int64(len(b.topics[msg.Topic]))
+	b.topics[msg.Topic] = append(b.topics[msg.Topic], msg)
+	if b.subscribed[msg.Topic] {
+		b.pending = append(b.pending, msg)
+	}
+	b.notify()
+	return msg.Offset
+}
+
+// notify будит всех ожидающих; вызывается под mu.
+func (b *MemoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// FetchMessage возвращает следующее сообщение, ожидая его появления. После Close — io.EOF, как у kafka.Reader.
+func (b *MemoryBroker) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return kafka.Message{}, io.EOF
+		}
+		if b.next < len(b.pending) {
+			msg := b.pending[b.next]
+			b.next++
+			msg.HighWaterMark = int64(len(b.topics[msg.Topic]))
+			b.mu.Unlock()
+			return msg, nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// CommitMessages фиксирует обработку сообщений: committed offset топика становится msg.Offset+1.
+func (b *MemoryBroker) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range msgs {
+		if next := msg.Offset + 1; next > b.committed[msg.Topic] {
+			b.committed[msg.Topic] = next
+		}
+	}
+	b.notify()
+	return nil
+}
+
+// Committed возвращает committed offset топика (0 — ничего не закоммичено).
+func (b *MemoryBroker) Committed(topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[topic]
+}
+
+// Messages возвращает все сообщения топика (включая неподписанные, например DLQ).
+func (b *MemoryBroker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]kafka.Message(nil), b.topics[topic]...)
+}
+
+// WaitCommitted ждёт, пока все сообщения подписанных топиков будут закоммичены.
+func (b *MemoryBroker) WaitCommitted(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		done := true
+		for topic := range b.subscribed {
+			if b.committed[topic] < int64(len(b.topics[topic])) {
+				done = false
+				break
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Close останавливает выдачу сообщений: FetchMessage возвращает io.EOF.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.notify()
+	return nil
+}
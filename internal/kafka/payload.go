@@ -0,0 +1,260 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/psds-microservice/search-service/internal/avro"
+	"github.com/psds-microservice/search-service/internal/schemaregistry"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Полезная нагрузка сообщения — JSON (по умолчанию), protobuf или Avro. Формат задаётся заголовком
+// content-type или распознаётся по magic byte Confluent wire format (0x00, id схемы big-endian,
+// для protobuf — индексы сообщения в файле схемы). Бинарное сообщение без magic byte отклоняется:
+// схема писателя известна только по id, а последняя версия subject'а может с ней не совпадать. Декодированное сообщение переводится в JSON с именами
+// полей схемы, поэтому обработчики разбирают все форматы в одни и те же TicketEvent/SessionEvent/OperatorEvent:
+// поля схемы называются как JSON-поля событий (ticket_id, session_id, ...), время — google.protobuf.Timestamp
+// или Avro timestamp-millis/timestamp-micros. Импорты в protobuf-схемах — только стандартные (google/protobuf/*).
+
+// headerContentType — заголовок с форматом полезной нагрузки.
+const headerContentType = "content-type"
+
+// magicByte — первый байт сообщения в Confluent wire format.
+const magicByte = 0x00
+
+// errNoSchemas — бинарное сообщение, а источник схем не настроен.
+var errNoSchemas = errors.New("schema registry is not configured (SCHEMA_REGISTRY_URL or SCHEMA_DIR)")
+
+// errNoSchemaID — бинарное сообщение без заголовка Confluent wire format с id схемы.
+var errNoSchemaID = errors.New("binary payload without schema id (Confluent wire format)")
+
+// Decoder приводит полезную нагрузку сообщения к JSON. Разобранные схемы кэшируются.
+// nil-Decoder принимает только JSON.
+type Decoder struct {
+	schemas schemaregistry.Source
+
+	mu     sync.Mutex
+	codecs map[int]codec
+}
+
+// codec декодирует полезную нагрузку по схеме; indexes — путь к типу сообщения protobuf.
+type codec func(payload []byte, indexes []int) ([]byte, error)
+
+// NewDecoder создаёт Decoder со схемами из schemas (реестр или локальный каталог).
+func NewDecoder(schemas schemaregistry.Source) *Decoder {
+	return &Decoder{schemas: schemas, codecs: map[int]codec{}}
+}
+
+// Decode возвращает полезную нагрузку сообщения в JSON. Ошибки разбора и отсутствующая схема помечены
+// errMalformed; временные ошибки получения схемы (реестр недоступен) — нет: такое сообщение можно обработать повторно.
+func (d *Decoder) Decode(ctx context.Context, msg kafka.Message) ([]byte, error) {
+	format, err := payloadFormat(msg.Headers)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	framed := len(msg.Value) >= 5 && msg.Value[0] == magicByte
+	if !framed && (format == "" || format == schemaregistry.TypeJSON) {
+		return msg.Value, nil
+	}
+	if !framed {
+		return nil, malformed(errNoSchemaID)
+	}
+	if d == nil {
+		return nil, malformed(errNoSchemas)
+	}
+
+	id := int(binary.BigEndian.Uint32(msg.Value[1:5]))
+	schema, err := d.schemas.ByID(ctx, id)
+	if err != nil {
+		if !schemaUnavailable(err) {
+			return nil, malformed(err)
+		}
+		return nil, err
+	}
+	payload := msg.Value[5:]
+	var indexes []int
+	if schema.Type == schemaregistry.TypeProtobuf {
+		if indexes, payload, err = messageIndexes(payload); err != nil {
+			return nil, malformed(fmt.Errorf("schema %d: %w", id, err))
+		}
+	}
+	if format != "" && format != schema.Type {
+		return nil, malformed(fmt.Errorf("content-type is %s, schema is %s", strings.ToLower(format), strings.ToLower(schema.Type)))
+	}
+
+	c, err := d.codec(schema)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	value, err := c(payload, indexes)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	return value, nil
+}
+
+// schemaUnavailable сообщает, что схему не удалось получить по временной причине: сетевая ошибка, 5xx или 429
+// реестра. Отсутствующую схему и прочие ответы 4xx повтор не исправит.
+func schemaUnavailable(err error) bool {
+	if errors.Is(err, schemaregistry.ErrNotFound) {
+		return false
+	}
+	var respErr *schemaregistry.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= http.StatusInternalServerError || respErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// codec возвращает декодер схемы, кэшированный по id схемы.
+func (d *Decoder) codec(schema *schemaregistry.Schema) (codec, error) {
+	d.mu.Lock()
+	c, ok := d.codecs[schema.ID]
+	d.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	var err error
+	switch schema.Type {
+	case schemaregistry.TypeAvro:
+		c, err = avroCodec(schema.Text)
+	case schemaregistry.TypeProtobuf:
+		c, err = protobufCodec(schema.Text)
+	case schemaregistry.TypeJSON:
+		c = func(payload []byte, _ []int) ([]byte, error) { return payload, nil }
+	default:
+		err = fmt.Errorf("unsupported schema type %q", schema.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", schema.ID, err)
+	}
+	d.mu.Lock()
+	d.codecs[schema.ID] = c
+	d.mu.Unlock()
+	return c, nil
+}
+
+func avroCodec(text string) (codec, error) {
+	schema, err := avro.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(payload []byte, _ []int) ([]byte, error) {
+		v, err := schema.Decode(payload)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}, nil
+}
+
+func protobufCodec(text string) (codec, error) {
+	const file = "schema.proto"
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{file: text}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), file)
+	if err != nil {
+		return nil, err
+	}
+	fd := files[0]
+	return func(payload []byte, indexes []int) ([]byte, error) {
+		md, err := messageDescriptor(fd, indexes)
+		if err != nil {
+			return nil, err
+		}
+		m := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(payload, m); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", md.FullName(), err)
+		}
+		return protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	}, nil
+}
+
+// messageDescriptor находит тип сообщения по индексам: первый — среди сообщений файла,
+// следующие — среди вложенных. Пустые индексы — первое сообщение файла.
+func messageDescriptor(fd protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	if len(indexes) == 0 {
+		indexes = []int{0}
+	}
+	msgs := fd.Messages()
+	var md protoreflect.MessageDescriptor
+	for _, i := range indexes {
+		if i < 0 || i >= msgs.Len() {
+			return nil, fmt.Errorf("message index %v not found in schema", indexes)
+		}
+		md = msgs.Get(i)
+		msgs = md.Messages()
+	}
+	return md, nil
+}
+
+// messageIndexes читает индексы сообщения protobuf после id схемы: число индексов и сами индексы
+// (zigzag varint); одиночный 0 — первое сообщение файла.
+func messageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, nil, errors.New("malformed message indexes")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	indexes := make([]int, 0, count)
+	for ; count > 0; count-- {
+		i, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, nil, errors.New("malformed message indexes")
+		}
+		indexes = append(indexes, int(i))
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
+
+// payloadFormat возвращает тип схемы по заголовку content-type (пусто — заголовка нет).
+func payloadFormat(headers []kafka.Header) (string, error) {
+	mediaType, err := payloadMediaType(headers)
+	if err != nil || mediaType == "" {
+		return "", err
+	}
+	switch mediaType {
+	case "application/json", "text/json":
+		return schemaregistry.TypeJSON, nil
+	case "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf":
+		return schemaregistry.TypeProtobuf, nil
+	case "application/avro", "application/x-avro", "avro/binary", "application/vnd.apache.avro+binary":
+		return schemaregistry.TypeAvro, nil
+	}
+	return "", fmt.Errorf("unsupported content-type %q", mediaType)
+}
+
+// payloadMediaType возвращает media type из заголовка content-type (пусто — заголовка нет).
+func payloadMediaType(headers []kafka.Header) (string, error) {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, headerContentType) {
+			mediaType, _, err := mime.ParseMediaType(string(h.Value))
+			if err != nil {
+				return "", fmt.Errorf("content-type %q: %w", h.Value, err)
+			}
+			return mediaType, nil
+		}
+	}
+	return "", nil
+}
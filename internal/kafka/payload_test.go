@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/psds-microservice/search-service/internal/schemaregistry"
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const ticketAvroSchema = `{
+  "type": "record", "name": "TicketEvent", "namespace": "psds.ticket",
+  "fields": [
+    {"name": "event", "type": "string"},
+    {"name": "ticket_id", "type": "long"},
+    {"name": "session_id", "type": "string"},
+    {"name": "subject", "type": ["null", "string"], "default": null},
+    {"name": "created_at", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}], "default": null}
+  ]
+}`
+
+const eventsProtoSchema = `syntax = "proto3";
+package psds;
+
+import "google/protobuf/timestamp.proto";
+
+message TicketEvent {
+  string event = 1;
+  int64 ticket_id = 2;
+  string session_id = 3;
+  string subject = 4;
+}
+
+message SessionEvent {
+  string event = 1;
+  string session_id = 2;
+  string client_id = 3;
+  google.protobuf.Timestamp created_at = 4;
+}
+`
+
+const operatorProtoSchema = `syntax = "proto3";
+package psds;
+
+message OperatorEvent {
+  string event = 1;
+  string user_id = 2;
+  string display_name = 3;
+  string region = 4;
+}
+`
+
+// framed добавляет к payload заголовок Confluent wire format; indexes — индексы сообщения protobuf.
+func framed(id uint32, indexes []byte, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{magicByte}, id)
+	return append(append(b, indexes...), payload...)
+}
+
+// protoPayload кодирует JSON-представление сообщения message схемы schema в protobuf.
+func protoPayload(t *testing.T, schema, message, value string) []byte {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+		Accessor: protocompile.SourceAccessorFromMap(map[string]string{"test.proto": schema}),
+	})}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(files[0].Messages().ByName(protoreflect.Name(message)))
+	if err := protojson.Unmarshal([]byte(value), m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestConsumeSchemaEncodedEvents(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"1.avsc":  ticketAvroSchema,
+		"2.proto": eventsProtoSchema,
+		"3.proto": operatorProtoSchema,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created := time.Date(2024, 5, 6, 8, 30, 0, 0, time.UTC)
+	avroTicket := []byte{}
+	avroTicket = appendAvroString(avroTicket, "ticket.created")
+	avroTicket = binary.AppendVarint(avroTicket, 8)
+	avroTicket = appendAvroString(avroTicket, "s-1")
+	avroTicket = appendAvroString(binary.AppendVarint(avroTicket, 1), "Cannot join")
+	avroTicket = binary.AppendVarint(binary.AppendVarint(avroTicket, 1), created.UnixMilli())
+
+	broker := NewMemoryBroker(topicTicketEvents, topicSessionCreated, topicOperatorCreated)
+	broker.Produce(topicTicketEvents, nil, framed(1, nil, avroTicket))
+	broker.Produce(topicTicketEvents, nil, framed(2, []byte{0}, protoPayload(t, eventsProtoSchema, "TicketEvent",
+		`{"event":"ticket.created","ticket_id":"9","session_id":"s-1","subject":"Logs"}`)))
+	broker.Produce(topicTicketEvents, nil, framed(99, nil, avroTicket)) // схемы нет — в DLQ
+	broker.Produce(topicSessionCreated, nil, framed(2, []byte{2, 2}, protoPayload(t, eventsProtoSchema, "SessionEvent",
+		`{"event":"session.created","session_id":"s-1","client_id":"c-1","created_at":"2024-05-06T08:30:00Z"}`)))
+	operator := protoPayload(t, operatorProtoSchema, "OperatorEvent", `{"event":"operator.created","user_id":"op-1","display_name":"Anna Petrova","region":"eu"}`)
+	broker.Produce(topicOperatorCreated, nil, framed(3, []byte{0}, operator), kafka.Header{Key: "Content-Type", Value: []byte("application/x-protobuf")})
+	broker.Produce(topicOperatorCreated, nil, operator, kafka.Header{Key: "Content-Type", Value: []byte("application/x-protobuf")}) // без id схемы — в DLQ
+	broker.Produce(topicOperatorCreated, nil, []byte(`{"event":"operator.created","user_id":"op-2","region":"us"}`))                // JSON по-прежнему принимается
+
+	svc := newMemoryService(t)
+	runConsumerWith(t, broker, NewDecoder(schemaregistry.NewDir(dir)), defaultRoutes, svc)
+	ctx := context.Background()
+
+	tickets, err := svc.SearchTickets(ctx, &service.TicketFilters{SessionID: "s-1"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if tickets.Total != 2 {
+		t.Fatalf("tickets = %+v, want the Avro ticket 8 and the protobuf ticket 9", tickets.Tickets)
+	}
+	early, err := svc.SearchTickets(ctx, &service.TicketFilters{Created: service.TimeRange{From: created, To: created.Add(time.Minute)}})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if early.Total != 1 || early.Tickets[0].TicketID != 8 {
+		t.Errorf("tickets created at %s = %+v, want ticket 8 (created_at from the Avro timestamp)", created, early.Tickets)
+	}
+	sessions, err := svc.SearchSessions(ctx, &service.SessionFilters{ClientID: "c-1"})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if sessions.Total != 1 || sessions.Sessions[0].SessionID != "s-1" {
+		t.Errorf("sessions = %+v, want s-1 decoded from the second message of the protobuf schema", sessions.Sessions)
+	}
+	operators, err := svc.SearchOperators(ctx, &service.OperatorFilters{})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if operators.Total != 2 {
+		t.Errorf("operators = %+v, want op-1 (protobuf) and op-2 (JSON)", operators.Operators)
+	}
+	dead := broker.Messages(topicDLQ)
+	if len(dead) != 2 {
+		t.Fatalf("dlq has %d messages, want the unknown schema and the payload without schema id", len(dead))
+	}
+	for _, msg := range dead {
+		headers := headerCarrier(msg.Headers)
+		switch headers.Get("dlq_topic") {
+		case topicTicketEvents:
+			if msg.Value[4] != 99 {
+				t.Errorf("dlq ticket message = %v, want the one with the unknown schema, as produced", msg.Value)
+			}
+		case topicOperatorCreated:
+			if !strings.Contains(headers.Get("dlq_error"), "without schema id") {
+				t.Errorf("dlq operator error = %q, want the payload without schema id rejected", headers.Get("dlq_error"))
+			}
+		}
+	}
+}
+
+func TestDecodeWithoutSchemas(t *testing.T) {
+	var d *Decoder
+	msg := kafka.Message{Value: []byte(`{"event":"session.created"}`)}
+	if got, err := d.Decode(context.Background(), msg); err != nil || string(got) != string(msg.Value) {
+		t.Errorf("Decode(JSON) = %s, %v", got, err)
+	}
+	msg.Headers = []kafka.Header{{Key: "content-type", Value: []byte("application/avro")}}
+	if _, err := d.Decode(context.Background(), msg); err == nil {
+		t.Error("Decode(Avro) without schemas succeeded, want error")
+	}
+	msg.Headers = []kafka.Header{{Key: "content-type", Value: []byte("text/csv")}}
+	if _, err := d.Decode(context.Background(), msg); err == nil {
+		t.Error("Decode(text/csv) succeeded, want unsupported content-type")
+	}
+}
+
+// failingSource — источник схем, который всегда возвращает err.
+type failingSource struct{ err error }
+
+func (s failingSource) ByID(context.Context, int) (*schemaregistry.Schema, error) { return nil, s.err }
+
+func TestDecodeSchemaErrors(t *testing.T) {
+	msg := kafka.Message{Value: []byte{magicByte, 0, 0, 0, 7, 0}}
+	cases := []struct {
+		name      string
+		err       error
+		malformed bool
+	}{
+		{"not found", &schemaregistry.ResponseError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, true},
+		{"missing file", fmt.Errorf("schema 7: %w", schemaregistry.ErrNotFound), true},
+		{"forbidden", &schemaregistry.ResponseError{StatusCode: http.StatusForbidden, Status: "403 Forbidden"}, true},
+		{"server error", &schemaregistry.ResponseError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}, false},
+		{"throttled", &schemaregistry.ResponseError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, false},
+		{"transport", errors.New("dial tcp: connection refused"), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewDecoder(failingSource{tc.err}).Decode(context.Background(), msg)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Decode = %v, want %v", err, tc.err)
+			}
+			if got := errors.Is(err, errMalformed); got != tc.malformed {
+				t.Errorf("malformed = %v, want %v", got, tc.malformed)
+			}
+		})
+	}
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
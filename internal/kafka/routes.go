@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// Handler индексирует одно сообщение. Ошибка с errSkipped — сообщение пропущено.
+type Handler func(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) error
+
+// handlers — обработчики, доступные в маршрутах, по имени.
+var handlers = map[string]Handler{
+	"ticket":   HandleTicket,
+	"session":  HandleSession,
+	"operator": HandleOperator,
+}
+
+// Route — маршрут сообщений: топики по шаблону Topic и (если задан) типы событий по шаблону Event
+// обрабатывает Handler. Шаблоны — в синтаксисе path.Match ("psds.ticket.*").
+type Route struct {
+	Topic   string
+	Event   string
+	Handler string
+}
+
+// Router выбирает обработчик сообщения: первый подходящий маршрут в порядке объявления.
+type Router struct {
+	routes []Route
+}
+
+// ParseRoutes разбирает таблицу маршрутов: "psds.ticket.*=ticket,psds.events:session.*=session",
+// где необязательная часть после ":" — шаблон типа события (поле event сообщения).
+func ParseRoutes(s string) (*Router, error) {
+	r := &Router{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		match, name, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("route %q: want topic[:event]=handler", item)
+		}
+		topic, event, _ := strings.Cut(strings.TrimSpace(match), ":")
+		route := Route{Topic: topic, Event: event, Handler: strings.TrimSpace(name)}
+		if route.Topic == "" {
+			return nil, fmt.Errorf("route %q: empty topic pattern", item)
+		}
+		if _, ok := handlers[route.Handler]; !ok {
+			return nil, fmt.Errorf("route %q: unknown handler %q (want ticket, session or operator)", item, route.Handler)
+		}
+		for _, pattern := range []string{route.Topic, route.Event} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %q: pattern %q: %w", item, pattern, err)
+			}
+		}
+		r.routes = append(r.routes, route)
+	}
+	if len(r.routes) == 0 {
+		return nil, fmt.Errorf("no routes")
+	}
+	return r, nil
+}
+
+// Validate проверяет, что для каждого топика подписки есть хотя бы один маршрут.
+func (r *Router) Validate(topics []string) error {
+	var missing []string
+	for _, topic := range topics {
+		if !r.routesTopic(topic) {
+			missing = append(missing, topic)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no route for topics %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (r *Router) routesTopic(topic string) bool {
+	for _, route := range r.routes {
+		if ok, _ := path.Match(route.Topic, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch передаёт сообщение обработчику первого подходящего маршрута. Тип события — type
+// CloudEvent, иначе поле event, которое читается из сообщения, только если маршрут топика его проверяет.
+func (r *Router) dispatch(ctx context.Context, msg kafka.Message, searchSvc service.SearchServicer) error {
+	event, parsed := "", false
+	if ce := cloudEventFrom(ctx); ce != nil {
+		event, parsed = ce.Type, true
+	}
+	for _, route := range r.routes {
+		if ok, _ := path.Match(route.Topic, msg.Topic); !ok {
+			continue
+		}
+		if route.Event != "" {
+			if !parsed {
+				var ev struct {
+					Event string `json:"event"`
+				}
+				if err := json.Unmarshal(msg.Value, &ev); err != nil {
+					return malformed(fmt.Errorf("unmarshal event type: %w", err))
+				}
+				event, parsed = ev.Event, true
+			}
+			if ok, _ := path.Match(route.Event, event); !ok {
+				continue
+			}
+		}
+		return handlers[route.Handler](ctx, msg, searchSvc)
+	}
+	if parsed {
+		return fmt.Errorf("%w: no route for topic %q, event %q", errSkipped, msg.Topic, event)
+	}
+	return fmt.Errorf("%w: no route for topic %q", errSkipped, msg.Topic)
+}
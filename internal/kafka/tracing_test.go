@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/psds-microservice/search-service/internal/service"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans подменяет глобальные TracerProvider и propagator на время теста и возвращает записанные спаны.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+		tp.Shutdown(context.Background())
+	})
+	return rec
+}
+
+// spanService запоминает span context, с которым вызван IndexSession.
+type spanService struct {
+	service.SearchServicer
+	mu   sync.Mutex
+	span trace.SpanContext
+}
+
+func (s *spanService) IndexSession(ctx context.Context, in *service.IndexSessionInput) error {
+	s.mu.Lock()
+	s.span = trace.SpanContextFromContext(ctx)
+	s.mu.Unlock()
+	return s.SearchServicer.IndexSession(ctx, in)
+}
+
+func TestConsumeContinuesProducerTrace(t *testing.T) {
+	rec := recordSpans(t)
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	broker := NewMemoryBroker(topicSessionCreated)
+	broker.Produce(topicSessionCreated, nil, []byte(`{"event":"session.created","session_id":"s-1","client_id":"c-1"}`),
+		kafka.Header{Key: "traceparent", Value: []byte(traceparent)})
+	svc := &spanService{SearchServicer: newMemoryService(t)}
+	startConsume(t, broker, nil, svc)
+	waitCommitted(t, broker)
+
+	var consume sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.SpanKind() == trace.SpanKindConsumer {
+			consume = s
+		}
+	}
+	if consume == nil {
+		t.Fatal("no consumer span recorded")
+	}
+	if got := consume.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("consumer span trace id = %s, want the producer's", got)
+	}
+	if got := consume.Parent().SpanID().String(); got != "00f067aa0ba902b7" || !consume.Parent().IsRemote() {
+		t.Errorf("consumer span parent = %s (remote %v), want the producer span 00f067aa0ba902b7", got, consume.Parent().IsRemote())
+	}
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.span.SpanID() != consume.SpanContext().SpanID() {
+		t.Errorf("handler span = %s, want the consumer span %s", svc.span.SpanID(), consume.SpanContext().SpanID())
+	}
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Форматы вывода (LOG_FORMAT).
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type ctxKey struct{}
+
+// New создаёт логгер с уровнем level (debug, info, warn, error) и форматом format (json, text).
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want json or text)", format)
+	}
+}
+
+// Setup создаёт логгер в stderr и делает его логгером по умолчанию (slog.Default и стандартный log).
+func Setup(level, format string) (*slog.Logger, error) {
+	l, err := New(os.Stderr, level, format)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(l.With("service", "search-service"))
+	return slog.Default(), nil
+}
+
+// Component возвращает логгер компонента (поле component) на основе логгера по умолчанию.
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
+
+// WithContext кладёт логгер в контекст (например, с request_id или topic/partition/offset).
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер из контекста или fallback, если его там нет.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// Err — атрибут с текстом ошибки.
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "search"
+
+// Результаты обработки Kafka-сообщения (label result у KafkaMessages). indexed, skipped и failed — итог
+// сообщения, считается один раз: failed — сообщение отправлено в DLQ или отброшено.
+const (
+	ResultConsumed = "consumed"
+	ResultIndexed  = "indexed"
+	ResultSkipped  = "skipped"
+	ResultFailed   = "failed"
+)
+
+// Результаты обращения к кэшу поиска (label result у CacheRequests).
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+var (
+	// GRPCRequests — количество RPC по методу и gRPC-коду ответа (включая вызовы через grpc-gateway).
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Total number of gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	// GRPCDuration — латентность RPC по методу и gRPC-коду ответа.
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC request latency by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// GRPCRateLimited — RPC, отклонённые rate limiter'ом, по методу.
+	GRPCRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "rate_limited_total",
+		Help:      "gRPC requests rejected by the rate limiter by method.",
+	}, []string{"method"})
+
+	// ESDuration — латентность вызовов Elasticsearch по операции, индексу и исходу (ok/error).
+	ESDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "elasticsearch",
+		Name:      "request_duration_seconds",
+		Help:      "Elasticsearch call latency by operation, index and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "index", "outcome"})
+
+	// CacheRequests — обращения к кэшу результатов поиска по сущности и результату (hit/miss).
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Search result cache lookups by entity and result (hit, miss).",
+	}, []string{"entity", "result"})
+
+	// KafkaMessages — сообщения worker'а по топику и результату (consumed/indexed/skipped/failed).
+	KafkaMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_total",
+		Help:      "Kafka messages by topic and result (consumed, indexed, skipped, failed).",
+	}, []string{"topic", "result"})
+
+	// KafkaRetries — повторные обработки Kafka-сообщений по топику (ошибка обработки или публикации в DLQ).
+	KafkaRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "retries_total",
+		Help:      "Kafka message processing retries by topic.",
+	}, []string{"topic"})
+
+	// KafkaLag — отставание consumer'а (high watermark - offset - 1) по топику и партиции.
+	KafkaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumer_lag",
+		Help:      "Consumer lag in messages by topic and partition.",
+	}, []string{"topic", "partition"})
+
+	// KafkaDLQPublished — публикации в DLQ по исходному топику и исходу (ok/error).
+	KafkaDLQPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "dlq_published_total",
+		Help:      "Messages published to the dead letter topic by source topic and outcome.",
+	}, []string{"topic", "outcome"})
+)
+
+// Handler отдаёт метрики в формате Prometheus (/metrics).
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Outcome возвращает label исхода операции по ошибке.
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
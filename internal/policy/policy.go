@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// Сущности, к которым применяются правила.
+const (
+	EntityTickets   = "tickets"
+	EntitySessions  = "sessions"
+	EntityOperators = "operators"
+)
+
+// Поведение для ролей, которых нет в политике.
+const (
+	DefaultAllow = "allow"
+	DefaultDeny  = "deny"
+)
+
+// Condition — поле документа должно совпадать со значением claim вызывающего (term-фильтр).
+type Condition struct {
+	Field string `json:"field"` // поле индекса сущности; регион оператора тикета — operator_region (region — регион самого тикета)
+	Claim string `json:"claim"` // sub — subject токена, иначе имя произвольного claim
+}
+
+// Rule — ограничение роли на одну сущность. Allow снимает ограничения; иначе должно выполниться
+// хотя бы одно условие AnyOf и все условия AllOf. Пустое правило (ни Allow, ни условий) — запрет.
+type Rule struct {
+	Allow bool        `json:"allow,omitempty"`
+	AnyOf []Condition `json:"any_of,omitempty"`
+	AllOf []Condition `json:"all_of,omitempty"`
+}
+
+// RolePolicy — правила роли. Unrestricted — роль видит всё (admin, service).
+type RolePolicy struct {
+	Unrestricted bool            `json:"unrestricted,omitempty"`
+	Entities     map[string]Rule `json:"entities,omitempty"`
+}
+
+// Policy — политика доступа к результатам поиска (row-level), загружается из JSON-файла.
+type Policy struct {
+	Default string                `json:"default"`
+	Roles   map[string]RolePolicy `json:"roles"`
+}
+
+// Load читает и проверяет политику из файла.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	switch p.Default {
+	case "":
+		p.Default = DefaultDeny
+	case DefaultAllow, DefaultDeny:
+	default:
+		return fmt.Errorf("default must be %q or %q", DefaultAllow, DefaultDeny)
+	}
+	for role, rp := range p.Roles {
+		for entity, rule := range rp.Entities {
+			switch entity {
+			case EntityTickets, EntitySessions, EntityOperators:
+			default:
+				return fmt.Errorf("role %s: unknown entity %q", role, entity)
+			}
+			for _, c := range append(append([]Condition{}, rule.AnyOf...), rule.AllOf...) {
+				if c.Field == "" || c.Claim == "" {
+					return fmt.Errorf("role %s, entity %s: condition needs field and claim", role, entity)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Scope возвращает обязательный фильтр для вызывающего на сущность entity.
+// nil-фильтр — ограничений нет. Ошибка с кодом PERMISSION_DENIED — доступ к сущности запрещён.
+// Без claims (аутентификация выключена) ограничения не применяются.
+func (p *Policy) Scope(claims *auth.Claims, entity string) (query.Query, error) {
+	if p == nil || claims == nil {
+		return nil, nil
+	}
+	var clauses []query.Query
+	known := false
+	for _, role := range claims.Roles {
+		rp, ok := p.Roles[role]
+		if !ok {
+			continue
+		}
+		known = true
+		if rp.Unrestricted {
+			return nil, nil
+		}
+		rule, ok := rp.Entities[entity]
+		if !ok {
+			continue
+		}
+		if rule.Allow {
+			return nil, nil
+		}
+		if clause := rule.clause(claims); clause != nil {
+			clauses = append(clauses, clause)
+		}
+	}
+	if !known && p.Default == DefaultAllow {
+		return nil, nil
+	}
+	switch len(clauses) {
+	case 0:
+		return nil, errors.New(errors.CodePermissionDenied, "access to "+entity+" is not allowed")
+	case 1:
+		return clauses[0], nil
+	default:
+		// Несколько ролей — объединение того, что разрешено каждой.
+		return &query.BoolQuery{Should: clauses, MinimumShouldMatch: 1}, nil
+	}
+}
+
+// clause строит фильтр правила; nil — правило не может выполниться (нет нужных claims).
+func (r Rule) clause(claims *auth.Claims) query.Query {
+	q := &query.BoolQuery{}
+	for _, c := range r.AllOf {
+		term := c.term(claims)
+		if term == nil {
+			return nil
+		}
+		q.Filter = append(q.Filter, term)
+	}
+	if len(r.AnyOf) > 0 {
+		for _, c := range r.AnyOf {
+			if term := c.term(claims); term != nil {
+				q.Should = append(q.Should, term)
+			}
+		}
+		if len(q.Should) == 0 {
+			return nil
+		}
+		q.MinimumShouldMatch = 1
+	}
+	if q.Empty() {
+		return nil
+	}
+	return q
+}
+
+func (c Condition) term(claims *auth.Claims) query.Query {
+	value := claims.String(c.Claim)
+	if c.Claim == "sub" {
+		value = claims.Subject
+	}
+	if value == "" {
+		return nil
+	}
+	return query.Term(c.Field, value)
+}
@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/auth"
+)
+
+func loadExample(t *testing.T) *Policy {
+	t.Helper()
+	p, err := Load(filepath.Join("..", "..", "deployments", "policy.example.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return p
+}
+
+func claims(sub string, roles []string, extra map[string]interface{}) *auth.Claims {
+	raw := jwt.MapClaims{"sub": sub}
+	for k, v := range extra {
+		raw[k] = v
+	}
+	return &auth.Claims{Subject: sub, Roles: roles, Raw: raw}
+}
+
+func TestScope(t *testing.T) {
+	p := loadExample(t)
+	cases := []struct {
+		name   string
+		claims *auth.Claims
+		entity string
+		want   string // JSON фильтра; пусто — без ограничений
+		denied bool
+	}{
+		{"unrestricted role", claims("adm-1", []string{"admin"}, nil), EntityTickets, "", false},
+		{"allow rule", claims("op-1", []string{"operator"}, nil), EntitySessions, "", false},
+		{
+			"any_of with sub and claim", claims("op-1", []string{"operator"}, map[string]interface{}{"region": "eu"}), EntityTickets,
+			`{"bool":{"minimum_should_match":1,"should":[{"term":{"operator_id":"op-1"}},{"term":{"operator_region":"eu"}}]}}`, false,
+		},
+		{
+			"any_of skips missing claims", claims("op-1", []string{"operator"}, nil), EntityTickets,
+			`{"bool":{"minimum_should_match":1,"should":[{"term":{"operator_id":"op-1"}}]}}`, false,
+		},
+		{
+			"all_of substitutes sub", claims("c-1", []string{"client"}, nil), EntityTickets,
+			`{"bool":{"filter":[{"term":{"client_id":"c-1"}}]}}`, false,
+		},
+		{"no rule for entity", claims("c-1", []string{"client"}, nil), EntityOperators, "", true},
+		{"unknown role, default deny", claims("x-1", []string{"auditor"}, nil), EntityTickets, "", true},
+		{"no roles, default deny", claims("x-1", nil, nil), EntitySessions, "", true},
+		{
+			"several roles: union", claims("u-1", []string{"client", "operator"}, nil), EntityTickets,
+			`{"bool":{"minimum_should_match":1,"should":[` +
+				`{"bool":{"filter":[{"term":{"client_id":"u-1"}}]}},` +
+				`{"bool":{"minimum_should_match":1,"should":[{"term":{"operator_id":"u-1"}}]}}]}}`, false,
+		},
+		{"any role allowing wins", claims("u-1", []string{"client", "operator"}, nil), EntitySessions, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := p.Scope(tc.claims, tc.entity)
+			if tc.denied {
+				if !errors.IsCode(err, errors.CodePermissionDenied) {
+					t.Fatalf("Scope = %v, %v; want PERMISSION_DENIED", q, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scope: %v", err)
+			}
+			got := ""
+			if q != nil {
+				data, _ := json.Marshal(q)
+				got = string(data)
+			}
+			if got != tc.want {
+				t.Errorf("Scope =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScopeWithoutPolicyOrClaims(t *testing.T) {
+	if q, err := loadExample(t).Scope(nil, EntityTickets); q != nil || err != nil {
+		t.Errorf("Scope(nil claims) = %v, %v; want no restriction (auth disabled)", q, err)
+	}
+	var p *Policy
+	if q, err := p.Scope(claims("x-1", nil, nil), EntityTickets); q != nil || err != nil {
+		t.Errorf("nil policy Scope = %v, %v; want no restriction", q, err)
+	}
+}
+
+func TestScopeDefaultAllow(t *testing.T) {
+	p := &Policy{Default: DefaultAllow, Roles: map[string]RolePolicy{
+		"client": {Entities: map[string]Rule{EntityTickets: {}}},
+	}}
+	if q, err := p.Scope(claims("x-1", []string{"auditor"}, nil), EntityTickets); q != nil || err != nil {
+		t.Errorf("unknown role Scope = %v, %v; want allowed by default", q, err)
+	}
+	// роль есть в политике: default не применяется, пустое правило — запрет
+	if _, err := p.Scope(claims("c-1", []string{"client"}, nil), EntityTickets); !errors.IsCode(err, errors.CodePermissionDenied) {
+		t.Errorf("empty rule Scope error = %v, want PERMISSION_DENIED", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"default is deny", `{"roles":{}}`, ""},
+		{"bad default", `{"default":"maybe"}`, "default must be"},
+		{"unknown entity", `{"roles":{"operator":{"entities":{"invoices":{"allow":true}}}}}`, `unknown entity "invoices"`},
+		{"condition without claim", `{"roles":{"operator":{"entities":{"tickets":{"any_of":[{"field":"operator_id"}]}}}}}`, "needs field and claim"},
+		{"not json", `roles`, "parse policy"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := Load(path)
+			if tc.err == "" {
+				if err != nil || p.Default != DefaultDeny {
+					t.Fatalf("Load = %+v, %v; want default deny", p, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Load error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
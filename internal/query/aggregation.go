@@ -0,0 +1,80 @@
+package query
+
+import "encoding/json"
+
+// Aggregation — агрегация в теле _search (ответ — SearchResponse.Aggregations[имя]).
+type Aggregation interface {
+	json.Marshaler
+	Kind() string
+}
+
+// TermsAggregation — бакеты по значениям поля; Aggs — вложенные агрегации по каждому бакету.
+type TermsAggregation struct {
+	Field string
+	Size  int // 0 — по умолчанию ES (10)
+	Aggs  map[string]Aggregation
+}
+
+func TermsAgg(field string, size int) *TermsAggregation {
+	return &TermsAggregation{Field: field, Size: size}
+}
+
+func (a *TermsAggregation) Kind() string { return "terms" }
+
+func (a *TermsAggregation) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"field": a.Field}
+	if a.Size > 0 {
+		body["size"] = a.Size
+	}
+	return aggregation(a.Kind(), body, a.Aggs)
+}
+
+// DateHistogramAggregation — бакеты по календарным интервалам (day, week, month, ...).
+type DateHistogramAggregation struct {
+	Field            string
+	CalendarInterval string
+	TimeZone         string
+	Aggs             map[string]Aggregation
+}
+
+func DateHistogramAgg(field, interval string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{Field: field, CalendarInterval: interval}
+}
+
+func (a *DateHistogramAggregation) Kind() string { return "date_histogram" }
+
+func (a *DateHistogramAggregation) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"field": a.Field, "calendar_interval": a.CalendarInterval}
+	if a.TimeZone != "" {
+		body["time_zone"] = a.TimeZone
+	}
+	return aggregation(a.Kind(), body, a.Aggs)
+}
+
+// MetricAggregation — метрика по полю: cardinality, min, max, avg, sum, value_count.
+type MetricAggregation struct {
+	Type  string
+	Field string
+}
+
+func CardinalityAgg(field string) *MetricAggregation {
+	return &MetricAggregation{Type: "cardinality", Field: field}
+}
+
+func MinAgg(field string) *MetricAggregation { return &MetricAggregation{Type: "min", Field: field} }
+
+func MaxAgg(field string) *MetricAggregation { return &MetricAggregation{Type: "max", Field: field} }
+
+func (a *MetricAggregation) Kind() string { return a.Type }
+
+func (a *MetricAggregation) MarshalJSON() ([]byte, error) {
+	return aggregation(a.Kind(), map[string]interface{}{"field": a.Field}, nil)
+}
+
+func aggregation(kind string, body map[string]interface{}, sub map[string]Aggregation) ([]byte, error) {
+	out := map[string]interface{}{kind: body}
+	if len(sub) > 0 {
+		out["aggs"] = sub
+	}
+	return json.Marshal(out)
+}
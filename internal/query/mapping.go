@@ -0,0 +1,58 @@
+package query
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Типы полей маппинга.
+const (
+	TypeKeyword = "keyword"
+	TypeText    = "text"
+	TypeLong    = "long"
+	TypeDate    = "date"
+	TypeBoolean = "boolean"
+	TypeNested  = "nested"
+)
+
+// Property — поле маппинга. Fields — мульти-поля (например, keyword-подполе у text),
+// Properties — поля вложенного объекта (nested/object).
+type Property struct {
+	Type        string              `json:"type"`
+	Analyzer    string              `json:"analyzer,omitempty"`
+	IgnoreAbove int                 `json:"ignore_above,omitempty"`
+	Format      string              `json:"format,omitempty"`
+	Fields      map[string]Property `json:"fields,omitempty"`
+	Properties  map[string]Property `json:"properties,omitempty"`
+}
+
+// Mapping — маппинг индекса; сериализуется в тело создания индекса ({"mappings": ...}).
+type Mapping struct {
+	Properties map[string]Property
+}
+
+func (m *Mapping) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": m.Properties},
+	})
+}
+
+// ExactField возвращает поле для точного совпадения (term/terms/sort/aggs): само поле, если оно
+// не анализируется, или его keyword-подполе для text-полей (display_name → display_name.keyword).
+func (m *Mapping) ExactField(field string) string {
+	p, ok := m.Properties[field]
+	if !ok || p.Type != TypeText {
+		return field
+	}
+	names := make([]string, 0, len(p.Fields))
+	for name := range p.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if p.Fields[name].Type == TypeKeyword {
+			return field + "." + name
+		}
+	}
+	return field
+}
@@ -0,0 +1,249 @@
+// Package query — типизированный Query DSL Elasticsearch: запросы, агрегации, сортировка, подсветка,
+// тело _search и маппинги индексов. Типы сериализуются в JSON ES и остаются разбираемыми (type switch)
+// для бэкендов, которые исполняют запросы сами.
+package query
+
+import "encoding/json"
+
+// Query — узел Query DSL.
+type Query interface {
+	json.Marshaler
+	// Kind возвращает имя запроса в DSL (bool, term, match, ...).
+	Kind() string
+}
+
+// clause сериализует {kind: body}.
+func clause(kind string, body interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{kind: body})
+}
+
+// MatchAllQuery — все документы.
+type MatchAllQuery struct{}
+
+func MatchAll() *MatchAllQuery { return &MatchAllQuery{} }
+
+func (q *MatchAllQuery) Kind() string { return "match_all" }
+
+func (q *MatchAllQuery) MarshalJSON() ([]byte, error) {
+	return clause(q.Kind(), struct{}{})
+}
+
+// TermQuery — точное совпадение значения. Для text-полей используйте Mapping.ExactField или MatchQuery:
+// term по анализируемому полю сравнивает значение с токенами и почти никогда не совпадает.
+type TermQuery struct {
+	Field string
+	Value interface{}
+}
+
+func Term(field string, value interface{}) *TermQuery {
+	return &TermQuery{Field: field, Value: value}
+}
+
+func (q *TermQuery) Kind() string { return "term" }
+
+func (q *TermQuery) MarshalJSON() ([]byte, error) {
+	return clause(q.Kind(), map[string]interface{}{q.Field: q.Value})
+}
+
+// TermsQuery — совпадение с любым из значений.
+type TermsQuery struct {
+	Field  string
+	Values []interface{}
+}
+
+func Terms(field string, values ...interface{}) *TermsQuery {
+	return &TermsQuery{Field: field, Values: values}
+}
+
+func (q *TermsQuery) Kind() string { return "terms" }
+
+func (q *TermsQuery) MarshalJSON() ([]byte, error) {
+	values := q.Values
+	if values == nil {
+		values = []interface{}{}
+	}
+	return clause(q.Kind(), map[string]interface{}{q.Field: values})
+}
+
+// Операторы объединения токенов в match/multi_match.
+const (
+	OperatorOr  = "or"
+	OperatorAnd = "and"
+)
+
+// MatchQuery — полнотекстовый поиск по анализируемому полю.
+type MatchQuery struct {
+	Field     string
+	Text      string
+	Operator  string // or (по умолчанию) | and
+	Fuzziness string // например, AUTO
+}
+
+func Match(field, text string) *MatchQuery {
+	return &MatchQuery{Field: field, Text: text}
+}
+
+func (q *MatchQuery) Kind() string { return "match" }
+
+func (q *MatchQuery) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"query": q.Text}
+	if q.Operator != "" {
+		body["operator"] = q.Operator
+	}
+	if q.Fuzziness != "" {
+		body["fuzziness"] = q.Fuzziness
+	}
+	return clause(q.Kind(), map[string]interface{}{q.Field: body})
+}
+
+// Типы multi_match.
+const (
+	MultiMatchBestFields   = "best_fields"
+	MultiMatchMostFields   = "most_fields"
+	MultiMatchCrossFields  = "cross_fields"
+	MultiMatchPhrasePrefix = "phrase_prefix"
+)
+
+// MultiMatchQuery — полнотекстовый поиск по нескольким полям (поле может содержать буст: "subject^2").
+type MultiMatchQuery struct {
+	Text     string
+	Fields   []string
+	Type     string
+	Operator string
+}
+
+func MultiMatch(text string, fields ...string) *MultiMatchQuery {
+	return &MultiMatchQuery{Text: text, Fields: fields}
+}
+
+func (q *MultiMatchQuery) Kind() string { return "multi_match" }
+
+func (q *MultiMatchQuery) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"query": q.Text, "fields": q.Fields}
+	if q.Type != "" {
+		body["type"] = q.Type
+	}
+	if q.Operator != "" {
+		body["operator"] = q.Operator
+	}
+	return clause(q.Kind(), body)
+}
+
+// RangeQuery — диапазон значений; незаданные (nil) границы не ограничивают.
+type RangeQuery struct {
+	Field  string
+	GT     interface{}
+	GTE    interface{}
+	LT     interface{}
+	LTE    interface{}
+	Format string // формат дат, например strict_date_optional_time
+}
+
+func Range(field string) *RangeQuery {
+	return &RangeQuery{Field: field}
+}
+
+func (q *RangeQuery) Kind() string { return "range" }
+
+func (q *RangeQuery) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+	for name, v := range map[string]interface{}{"gt": q.GT, "gte": q.GTE, "lt": q.LT, "lte": q.LTE} {
+		if v != nil {
+			body[name] = v
+		}
+	}
+	if q.Format != "" {
+		body["format"] = q.Format
+	}
+	return clause(q.Kind(), map[string]interface{}{q.Field: body})
+}
+
+// ExistsQuery — у документа есть непустое значение поля.
+type ExistsQuery struct {
+	Field string
+}
+
+func Exists(field string) *ExistsQuery {
+	return &ExistsQuery{Field: field}
+}
+
+func (q *ExistsQuery) Kind() string { return "exists" }
+
+func (q *ExistsQuery) MarshalJSON() ([]byte, error) {
+	return clause(q.Kind(), map[string]interface{}{"field": q.Field})
+}
+
+// NestedQuery — запрос по вложенным документам поля типа nested.
+type NestedQuery struct {
+	Path      string
+	Query     Query
+	ScoreMode string     // avg (по умолчанию) | max | min | sum | none
+	InnerHits *InnerHits // вернуть совпавшие вложенные документы у каждого хита
+}
+
+// InnerHits — совпавшие вложенные документы в ответе (inner_hits хита, ключ — Name или Path).
+type InnerHits struct {
+	Name      string // ключ в inner_hits ответа (по умолчанию — path запроса)
+	Size      int    // сколько вложенных документов вернуть (0 — по умолчанию ES, 3)
+	Highlight *Highlight
+}
+
+func (h *InnerHits) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+	if h.Name != "" {
+		body["name"] = h.Name
+	}
+	if h.Size > 0 {
+		body["size"] = h.Size
+	}
+	if h.Highlight != nil {
+		body["highlight"] = h.Highlight
+	}
+	return json.Marshal(body)
+}
+
+func Nested(path string, q Query) *NestedQuery {
+	return &NestedQuery{Path: path, Query: q}
+}
+
+func (q *NestedQuery) Kind() string { return "nested" }
+
+func (q *NestedQuery) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"path": q.Path, "query": q.Query}
+	if q.ScoreMode != "" {
+		body["score_mode"] = q.ScoreMode
+	}
+	if q.InnerHits != nil {
+		body["inner_hits"] = q.InnerHits
+	}
+	return clause(q.Kind(), body)
+}
+
+// BoolQuery — комбинация запросов. Filter и MustNot не влияют на score.
+type BoolQuery struct {
+	Must               []Query
+	Filter             []Query
+	Should             []Query
+	MustNot            []Query
+	MinimumShouldMatch int // 0 — по умолчанию ES
+}
+
+func (q *BoolQuery) Kind() string { return "bool" }
+
+// Empty сообщает, что в запросе нет ни одного условия.
+func (q *BoolQuery) Empty() bool {
+	return len(q.Must)+len(q.Filter)+len(q.Should)+len(q.MustNot) == 0
+}
+
+func (q *BoolQuery) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+	for name, qs := range map[string][]Query{"must": q.Must, "filter": q.Filter, "should": q.Should, "must_not": q.MustNot} {
+		if len(qs) > 0 {
+			body[name] = qs
+		}
+	}
+	if q.MinimumShouldMatch > 0 {
+		body["minimum_should_match"] = q.MinimumShouldMatch
+	}
+	return clause(q.Kind(), body)
+}
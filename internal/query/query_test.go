@@ -0,0 +1,109 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name string
+		v    json.Marshaler
+	}{
+		{"match_all", MatchAll()},
+		{"term", Term("status", "open")},
+		{"terms", Terms("region", "eu", "us")},
+		{"terms_empty", Terms("region")},
+		{"match", &MatchQuery{Field: "notes", Text: "printer offline", Operator: OperatorAnd, Fuzziness: "AUTO"}},
+		{"multi_match", &MultiMatchQuery{Text: "refund", Fields: []string{"subject^2", "notes"}, Type: MultiMatchBestFields}},
+		{"range", &RangeQuery{Field: "created_at", GTE: "2024-01-01", LT: "2024-02-01", Format: "strict_date_optional_time"}},
+		{"exists", Exists("operator_id")},
+		{"nested", &NestedQuery{Path: "comments", Query: Match("comments.body", "reset"), ScoreMode: "max"}},
+		{"nested_inner_hits", &NestedQuery{Path: "comments", Query: Match("comments.body", "reset"), InnerHits: &InnerHits{
+			Size:      1,
+			Highlight: &Highlight{Fields: []string{"comments.body"}, NumberOfFragments: 1},
+		}}},
+		{"bool", &BoolQuery{
+			Must:               []Query{Match("subject", "login")},
+			Filter:             []Query{Term("tenant_id", "acme"), Term("status", "open")},
+			Should:             []Query{Term("region", "eu"), Term("operator_id", "op-1")},
+			MustNot:            []Query{Exists("deleted_at")},
+			MinimumShouldMatch: 1,
+		}},
+		{"bool_empty", &BoolQuery{}},
+		{"aggregations", &Search{
+			Query: MatchAll(),
+			Aggs: map[string]Aggregation{
+				"by_status": &TermsAggregation{Field: "status", Size: 5, Aggs: map[string]Aggregation{
+					"operators": CardinalityAgg("operator_id"),
+				}},
+				"per_day":  &DateHistogramAggregation{Field: "created_at", CalendarInterval: "day", TimeZone: "Europe/Moscow"},
+				"earliest": MinAgg("created_at"),
+				"latest":   MaxAgg("created_at"),
+			},
+		}},
+		{"search", &Search{
+			Query:     &BoolQuery{Filter: []Query{Term("status", "open")}},
+			From:      20,
+			Size:      10,
+			Sort:      []Sort{SortBy("created_at", Desc), {Field: "ticket_id", Order: Asc, Missing: "_last"}, SortBy(FieldScore, "")},
+			Highlight: &Highlight{Fields: []string{"subject", "notes"}, PreTags: []string{"<em>"}, PostTags: []string{"</em>"}, FragmentSize: 150, NumberOfFragments: 1},
+		}},
+		{"search_nil_query", &Search{Size: 20}},
+		{"search_explain", &Search{Query: Term("pin", "1234"), Size: 1, Profile: true, Explain: true}},
+		{"mapping", &Mapping{Properties: map[string]Property{
+			"display_name": {Type: TypeText, Fields: map[string]Property{"keyword": {Type: TypeKeyword, IgnoreAbove: 256}}},
+			"created_at":   {Type: TypeDate},
+			"comments": {Type: TypeNested, Properties: map[string]Property{
+				"body": {Type: TypeText, Analyzer: "standard"},
+			}},
+		}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.MarshalIndent(tc.v, "", "  ")
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			got = append(got, '\n')
+			path := filepath.Join("testdata", tc.name+".json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run go test -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s mismatch\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestMappingExactField(t *testing.T) {
+	m := &Mapping{Properties: map[string]Property{
+		"display_name": {Type: TypeText, Fields: map[string]Property{"keyword": {Type: TypeKeyword}}},
+		"notes":        {Type: TypeText},
+		"region":       {Type: TypeKeyword},
+	}}
+	for field, want := range map[string]string{
+		"display_name": "display_name.keyword",
+		"notes":        "notes", // нет keyword-подполя: поле как есть
+		"region":       "region",
+		"unknown":      "unknown",
+	} {
+		if got := m.ExactField(field); got != want {
+			t.Errorf("ExactField(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package query
+
+import "encoding/json"
+
+// Направления сортировки.
+const (
+	Asc  = "asc"
+	Desc = "desc"
+)
+
+// FieldScore — псевдо-поле релевантности для сортировки.
+const FieldScore = "_score"
+
+// Sort — сортировка по полю.
+type Sort struct {
+	Field   string
+	Order   string // Asc или Desc (по умолчанию ES)
+	Missing string // _first | _last (по умолчанию ES)
+}
+
+func SortBy(field, order string) Sort {
+	return Sort{Field: field, Order: order}
+}
+
+func (s Sort) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+	if s.Order != "" {
+		body["order"] = s.Order
+	}
+	if s.Missing != "" {
+		body["missing"] = s.Missing
+	}
+	return json.Marshal(map[string]interface{}{s.Field: body})
+}
+
+// Highlight — подсветка совпадений (ответ — highlight у каждого хита).
+type Highlight struct {
+	Fields            []string
+	PreTags           []string
+	PostTags          []string
+	FragmentSize      int
+	NumberOfFragments int
+}
+
+func (h *Highlight) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	for _, f := range h.Fields {
+		fields[f] = struct{}{}
+	}
+	body := map[string]interface{}{"fields": fields}
+	if len(h.PreTags) > 0 {
+		body["pre_tags"] = h.PreTags
+	}
+	if len(h.PostTags) > 0 {
+		body["post_tags"] = h.PostTags
+	}
+	if h.FragmentSize > 0 {
+		body["fragment_size"] = h.FragmentSize
+	}
+	if h.NumberOfFragments > 0 {
+		body["number_of_fragments"] = h.NumberOfFragments
+	}
+	return json.Marshal(body)
+}
+
+// PointInTime — снимок индекса для последовательного постраничного чтения (search_after).
+type PointInTime struct {
+	ID        string
+	KeepAlive string // например, 1m
+}
+
+func (p *PointInTime) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"id": p.ID}
+	if p.KeepAlive != "" {
+		body["keep_alive"] = p.KeepAlive
+	}
+	return json.Marshal(body)
+}
+
+// Search — тело запроса _search.
+type Search struct {
+	Query       Query
+	From        int
+	Size        int
+	Sort        []Sort
+	PIT         *PointInTime  // поиск по снимку: индекс в URL не указывается
+	SearchAfter []interface{} // значения sort последнего хита предыдущей страницы (вместо From)
+	Aggs        map[string]Aggregation
+	Highlight   *Highlight
+	Profile     bool // profile в ответе (отладка, дорого)
+	Explain     bool // _explanation у каждого хита (отладка)
+}
+
+func (s *Search) MarshalJSON() ([]byte, error) {
+	q := s.Query
+	if q == nil {
+		q = MatchAll()
+	}
+	body := map[string]interface{}{"query": q, "from": s.From, "size": s.Size}
+	if len(s.Sort) > 0 {
+		body["sort"] = s.Sort
+	}
+	if len(s.Aggs) > 0 {
+		body["aggs"] = s.Aggs
+	}
+	if s.Highlight != nil {
+		body["highlight"] = s.Highlight
+	}
+	if s.PIT != nil {
+		body["pit"] = s.PIT
+	}
+	if len(s.SearchAfter) > 0 {
+		body["search_after"] = s.SearchAfter
+	}
+	if s.Profile {
+		body["profile"] = true
+	}
+	if s.Explain {
+		body["explain"] = true
+	}
+	return json.Marshal(body)
+}
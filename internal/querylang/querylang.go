@@ -0,0 +1,443 @@
+// Package querylang разбирает строку поиска для опытных пользователей, например
+//
+//	status:open operator:me subject:"card blocked" -status:closed created:>2026-09-01
+//
+// в типизированные фильтры сервиса. Строка — это термы [-]поле:значение через пробел; допускаются
+// только поля из белого списка сущности, значения никогда не попадают в ES как query_string. Условия строки
+// сужают результат: они пересекаются (AND) с остальными параметрами запроса.
+//
+// Значения:
+//   - keyword-поля принимают список через запятую (status:open,in_progress), повторы поля объединяются (OR);
+//   - "-" перед полем исключает значения (только там, где фильтр это поддерживает, например -status:closed);
+//   - значение в кавычках берётся как есть (subject:"card blocked", внутри допустимы \" и \\);
+//   - даты: 2026-09-01 (весь день, UTC), >2026-09-01, >=, <, <=, 2026-09-01..2026-09-30 (оба дня включительно)
+//     или метка времени RFC 3339; несколько условий по одной дате пересекаются.
+package querylang
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/psds-microservice/search-service/internal/service"
+)
+
+// Ограничения на размер строки запроса.
+const (
+	MaxLength = 1024 // символов
+	MaxTerms  = 32
+)
+
+// Error — ошибка разбора: позиция (в символах, с 0) и текст терма, на котором она возникла.
+type Error struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("query: %s at position %d", e.Msg, e.Pos)
+	}
+	return fmt.Sprintf("query: %s at position %d (%q)", e.Msg, e.Pos, e.Token)
+}
+
+// Tickets дополняет f условиями из строки запроса. me — идентификатор вызывающего для operator:me
+// (пусто — вызывающий не аутентифицирован). Списки «любой из» из строки попадают в f.Query и пересекаются
+// с фильтрами запроса, остальные условия (исключения, точные значения, текст, даты) и так их сужают.
+// Поля: status (можно исключать), operator, operator_region, client, session, subject (все слова),
+// text (все слова в subject, notes или комментарии), created, updated.
+func Tickets(input, me string, f *service.TicketFilters) error {
+	q := new(service.TicketFilters)
+	err := schema{
+		"status":          statusField(&q.Statuses, &f.ExcludeStatuses),
+		"operator":        keywordsField(&q.OperatorIDs, me),
+		"operator_region": keywordsField(&q.OperatorRegions, ""),
+		"client":          keywordsField(&q.ClientIDs, ""),
+		"session":         exactField(&f.SessionID),
+		"subject":         textField(&f.Subject),
+		"text":            textField(&f.Text),
+		"created":         dateField(&f.Created),
+		"updated":         dateField(&f.Updated),
+	}.parse(input)
+	if err != nil {
+		return err
+	}
+	if len(q.Statuses)+len(q.OperatorIDs)+len(q.OperatorRegions)+len(q.ClientIDs) > 0 {
+		f.Query = q
+	}
+	return nil
+}
+
+// Sessions дополняет f условиями из строки запроса (списки «любой из» — в f.Query, как в Tickets).
+// me — идентификатор вызывающего для operator:me.
+// Поля: status (можно исключать), client, pin, operator (участник сессии), created, updated, ended.
+func Sessions(input, me string, f *service.SessionFilters) error {
+	q := new(service.SessionFilters)
+	err := schema{
+		"status":   statusField(&q.Statuses, &f.ExcludeStatuses),
+		"client":   keywordsField(&q.ClientIDs, ""),
+		"operator": keywordsField(&q.OperatorIDs, me),
+		"pin":      exactField(&f.PIN),
+		"created":  dateField(&f.Created),
+		"updated":  dateField(&f.Updated),
+		"ended":    dateField(&f.Ended),
+	}.parse(input)
+	if err != nil {
+		return err
+	}
+	if len(q.Statuses)+len(q.ClientIDs)+len(q.OperatorIDs) > 0 {
+		f.Query = q
+	}
+	return nil
+}
+
+// Operators дополняет f условиями из строки запроса (списки «любой из» — в f.Query, как в Tickets).
+// Поля: region, role, name (точное совпадение display_name), created, updated.
+func Operators(input string, f *service.OperatorFilters) error {
+	q := new(service.OperatorFilters)
+	err := schema{
+		"region":  keywordsField(&q.Regions, ""),
+		"role":    keywordsField(&q.Roles, ""),
+		"name":    exactField(&f.DisplayName),
+		"created": dateField(&f.Created),
+		"updated": dateField(&f.Updated),
+	}.parse(input)
+	if err != nil {
+		return err
+	}
+	if len(q.Regions)+len(q.Roles) > 0 {
+		f.Query = q
+	}
+	return nil
+}
+
+// term — элемент запроса [-]field:value.
+type term struct {
+	pos    int
+	raw    string
+	neg    bool
+	field  string
+	value  string
+	quoted bool
+}
+
+func (t term) errorf(format string, args ...interface{}) *Error {
+	return &Error{Pos: t.pos, Token: t.raw, Msg: fmt.Sprintf(format, args...)}
+}
+
+// field — обработчик поля сущности; apply возвращает сообщение об ошибке без позиции.
+type field struct {
+	negatable bool
+	apply     func(t term) error
+}
+
+type schema map[string]field
+
+func (s schema) parse(input string) error {
+	terms, err := tokenize(input)
+	if err != nil {
+		return err
+	}
+	for _, t := range terms {
+		f, ok := s[t.field]
+		if !ok {
+			return t.errorf("unknown field %q (allowed: %s)", t.field, strings.Join(s.names(), ", "))
+		}
+		if t.neg && !f.negatable {
+			return t.errorf("%s cannot be negated", t.field)
+		}
+		if err := f.apply(t); err != nil {
+			return t.errorf("%s", err.Error())
+		}
+	}
+	return nil
+}
+
+func (s schema) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tokenize разбивает строку на термы.
+func tokenize(input string) ([]term, error) {
+	if n := utf8.RuneCountInString(input); n > MaxLength {
+		return nil, &Error{Pos: MaxLength, Msg: fmt.Sprintf("query is longer than %d characters", MaxLength)}
+	}
+	rs := []rune(input)
+	var terms []term
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+		start := i
+		word := func() string { return string(rs[start:wordEnd(rs, start)]) }
+		t := term{pos: start}
+		if rs[i] == '-' {
+			t.neg = true
+			i++
+		}
+		nameStart := i
+		for i < len(rs) && isNameRune(rs[i]) {
+			i++
+		}
+		t.field = strings.ToLower(string(rs[nameStart:i]))
+		if t.field == "" || i == len(rs) || rs[i] != ':' {
+			return nil, &Error{Pos: start, Token: word(), Msg: "expected field:value"}
+		}
+		i++ // ':'
+
+		if i < len(rs) && rs[i] == '"' {
+			value, next, ok := readQuoted(rs, i)
+			if !ok {
+				return nil, &Error{Pos: start, Token: string(rs[start:]), Msg: "unterminated quote"}
+			}
+			if next < len(rs) && !unicode.IsSpace(rs[next]) {
+				return nil, &Error{Pos: start, Token: word(), Msg: "expected a space after the closing quote"}
+			}
+			t.value, t.quoted, i = value, true, next
+		} else {
+			valueStart := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				if rs[i] == '"' {
+					return nil, &Error{Pos: start, Token: word(), Msg: "quotes must enclose the whole value"}
+				}
+				i++
+			}
+			t.value = string(rs[valueStart:i])
+		}
+		t.raw = string(rs[start:i])
+		if strings.TrimSpace(t.value) == "" {
+			return nil, t.errorf("missing value")
+		}
+		if len(terms) == MaxTerms {
+			return nil, t.errorf("more than %d terms", MaxTerms)
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func wordEnd(rs []rune, i int) int {
+	for i < len(rs) && !unicode.IsSpace(rs[i]) {
+		i++
+	}
+	return i
+}
+
+// readQuoted читает значение в кавычках, начиная с открывающей кавычки rs[i]; возвращает позицию после закрывающей.
+func readQuoted(rs []rune, i int) (string, int, bool) {
+	var b strings.Builder
+	for i++; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			if i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+				i++
+			}
+		case '"':
+			return b.String(), i + 1, true
+		}
+		b.WriteRune(rs[i])
+	}
+	return "", i, false
+}
+
+// values — значения терма: список через запятую, значение в кавычках — одно.
+func values(t term) ([]string, error) {
+	if t.quoted {
+		return []string{t.value}, nil
+	}
+	parts := strings.Split(t.value, ",")
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("empty value in list")
+		}
+	}
+	return parts, nil
+}
+
+// keywordsField — keyword-фильтр «любой из»; если me не пусто, значение me заменяется идентификатором вызывающего.
+func keywordsField(dst *[]string, me string) field {
+	return field{apply: func(t term) error {
+		vals, err := values(t)
+		if err != nil {
+			return err
+		}
+		for _, v := range vals {
+			if v == "me" && !t.quoted {
+				if me == "" {
+					return fmt.Errorf("%s:me requires an authenticated caller", t.field)
+				}
+				v = me
+			}
+			*dst = append(*dst, v)
+		}
+		return nil
+	}}
+}
+
+// statusField — keyword-фильтр «любой из» с исключением через -.
+func statusField(include, exclude *[]string) field {
+	return field{negatable: true, apply: func(t term) error {
+		vals, err := values(t)
+		if err != nil {
+			return err
+		}
+		if t.neg {
+			*exclude = append(*exclude, vals...)
+		} else {
+			*include = append(*include, vals...)
+		}
+		return nil
+	}}
+}
+
+// exactField — фильтр по одному точному значению.
+func exactField(dst *string) field {
+	return field{apply: func(t term) error {
+		if *dst != "" && *dst != t.value {
+			return fmt.Errorf("%s is already set to %q", t.field, *dst)
+		}
+		*dst = t.value
+		return nil
+	}}
+}
+
+// textField — полнотекстовый фильтр; слова из повторов поля добавляются.
+func textField(dst *string) field {
+	return field{apply: func(t term) error {
+		*dst = strings.TrimSpace(*dst + " " + t.value)
+		return nil
+	}}
+}
+
+// dateField — диапазон дат; пересекается с уже заданным.
+func dateField(dst *service.TimeRange) field {
+	return field{apply: func(t term) error {
+		r, err := parseTimeRange(t.value)
+		if err != nil {
+			return err
+		}
+		switch {
+		case r.From.IsZero():
+		case dst.From.IsZero() || r.From.After(dst.From):
+			dst.From, dst.FromExclusive = r.From, r.FromExclusive
+		case r.From.Equal(dst.From):
+			dst.FromExclusive = dst.FromExclusive || r.FromExclusive
+		}
+		switch {
+		case r.To.IsZero():
+		case dst.To.IsZero() || r.To.Before(dst.To):
+			dst.To, dst.ToInclusive = r.To, r.ToInclusive
+		case r.To.Equal(dst.To):
+			dst.ToInclusive = dst.ToInclusive && r.ToInclusive
+		}
+		if empty(*dst) {
+			return fmt.Errorf("date range is empty")
+		}
+		return nil
+	}}
+}
+
+// parseTimeRange разбирает условие на дату в диапазон. Дата — весь день: её верхняя граница — начало
+// следующего дня; у метки времени граница берётся как есть, включительно или нет.
+func parseTimeRange(v string) (service.TimeRange, error) {
+	var r service.TimeRange
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(v, op) {
+			continue
+		}
+		t, day, err := parseTime(v[len(op):])
+		if err != nil {
+			return r, err
+		}
+		switch {
+		case op == ">=":
+			r.From = t
+		case op == ">" && day:
+			r.From = nextDay(t)
+		case op == ">":
+			r.From, r.FromExclusive = t, true
+		case op == "<=" && day:
+			r.To = nextDay(t)
+		case op == "<=":
+			r.To, r.ToInclusive = t, true
+		case op == "<":
+			r.To = t
+		}
+		return r, nil
+	}
+	if from, to, ok := strings.Cut(v, ".."); ok {
+		if from == "" && to == "" {
+			return r, fmt.Errorf("date range needs at least one bound")
+		}
+		if from != "" {
+			t, _, err := parseTime(from)
+			if err != nil {
+				return r, err
+			}
+			r.From = t
+		}
+		if to != "" {
+			t, day, err := parseTime(to)
+			if err != nil {
+				return r, err
+			}
+			if day {
+				r.To = nextDay(t)
+			} else {
+				r.To, r.ToInclusive = t, true
+			}
+		}
+		if empty(r) {
+			return r, fmt.Errorf("date range is empty")
+		}
+		return r, nil
+	}
+	t, day, err := parseTime(v)
+	if err != nil {
+		return r, err
+	}
+	if day {
+		return service.TimeRange{From: t, To: nextDay(t)}, nil
+	}
+	return service.TimeRange{From: t, To: t, ToInclusive: true}, nil
+}
+
+// parseTime разбирает дату (YYYY-MM-DD, UTC; day=true) или метку времени RFC 3339.
+func parseTime(s string) (t time.Time, day bool, err error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", s)
+}
+
+func nextDay(t time.Time) time.Time {
+	return t.AddDate(0, 0, 1)
+}
+
+// empty сообщает, что диапазон с обеими границами не содержит ни одного момента.
+func empty(r service.TimeRange) bool {
+	if r.From.IsZero() || r.To.IsZero() {
+		return false
+	}
+	if r.From.Equal(r.To) {
+		return r.FromExclusive || !r.ToInclusive
+	}
+	return r.From.After(r.To)
+}
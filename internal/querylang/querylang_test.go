@@ -0,0 +1,128 @@
+package querylang
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/service"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func ts(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestTickets(t *testing.T) {
+	var f service.TicketFilters
+	err := Tickets(`status:open operator:me subject:"card blocked" -status:closed created:>2026-09-01 text:toner operator_region:north-west`, "op-7", &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := service.TicketFilters{
+		ExcludeStatuses: []string{"closed"},
+		Subject:         "card blocked",
+		Text:            "toner",
+		Created:         service.TimeRange{From: day("2026-09-02")},
+		Query: &service.TicketFilters{
+			Statuses:        []string{"open"},
+			OperatorIDs:     []string{"op-7"},
+			OperatorRegions: []string{"north-west"},
+		},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Fatalf("got %+v\nwant %+v", f, want)
+	}
+}
+
+func TestValuesAndDates(t *testing.T) {
+	cases := []struct {
+		input string
+		want  service.SessionFilters
+	}{
+		{"status:waiting,active status:finished", service.SessionFilters{Query: &service.SessionFilters{Statuses: []string{"waiting", "active", "finished"}}}},
+		{`client:"c 1" PIN:4821`, service.SessionFilters{PIN: "4821", Query: &service.SessionFilters{ClientIDs: []string{"c 1"}}}},
+		{`client:"say \"hi\" \\"`, service.SessionFilters{Query: &service.SessionFilters{ClientIDs: []string{`say "hi" \`}}}},
+		{"ended:2026-09-01", service.SessionFilters{Ended: service.TimeRange{From: day("2026-09-01"), To: day("2026-09-02")}}},
+		{"ended:2026-09-01..2026-09-30", service.SessionFilters{Ended: service.TimeRange{From: day("2026-09-01"), To: day("2026-10-01")}}},
+		{"ended:..2026-09-30", service.SessionFilters{Ended: service.TimeRange{To: day("2026-10-01")}}},
+		{"created:>=2026-09-01 created:<2026-09-10 created:<=2026-09-05", service.SessionFilters{Created: service.TimeRange{From: day("2026-09-01"), To: day("2026-09-06")}}},
+		{"updated:>2026-09-01T10:00:00Z", service.SessionFilters{Updated: service.TimeRange{From: ts("2026-09-01T10:00:00Z"), FromExclusive: true}}},
+		{"updated:<=2026-09-01T10:00:00Z", service.SessionFilters{Updated: service.TimeRange{To: ts("2026-09-01T10:00:00Z"), ToInclusive: true}}},
+		{"updated:2026-09-01T10:00:00Z", service.SessionFilters{Updated: service.TimeRange{From: ts("2026-09-01T10:00:00Z"), To: ts("2026-09-01T10:00:00Z"), ToInclusive: true}}},
+		{"updated:2026-09-01T10:00:00Z..2026-09-01T11:00:00Z updated:<2026-09-01T11:00:00Z", service.SessionFilters{Updated: service.TimeRange{From: ts("2026-09-01T10:00:00Z"), To: ts("2026-09-01T11:00:00Z")}}},
+		{"updated:>=2026-09-01T10:00:00Z updated:>2026-09-01T10:00:00Z", service.SessionFilters{Updated: service.TimeRange{From: ts("2026-09-01T10:00:00Z"), FromExclusive: true}}},
+		{"operator:me,op-2", service.SessionFilters{Query: &service.SessionFilters{OperatorIDs: []string{"op-7", "op-2"}}}},
+		{"  ", service.SessionFilters{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			var f service.SessionFilters
+			if err := Sessions(tc.input, "op-7", &f); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f, tc.want) {
+				t.Fatalf("got %+v\nwant %+v", f, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		me    string
+		pos   int
+		token string
+		msg   string
+	}{
+		{"status:open foo:bar", "", 12, "foo:bar", `unknown field "foo" (allowed: client, created, operator, operator_region, session, status, subject, text, updated)`},
+		{"status:open blocked", "", 12, "blocked", "expected field:value"},
+		{"-operator:op-1", "", 0, "-operator:op-1", "operator cannot be negated"},
+		{"operator:me", "", 0, "operator:me", "operator:me requires an authenticated caller"},
+		{`subject:"card blocked`, "", 0, `subject:"card blocked`, "unterminated quote"},
+		{`subject:card"blocked"`, "", 0, `subject:card"blocked"`, "quotes must enclose the whole value"},
+		{`subject:"card"x`, "", 0, `subject:"card"x`, "expected a space after the closing quote"},
+		{"status:", "", 0, "status:", "missing value"},
+		{"status:open,,closed", "", 0, "status:open,,closed", "empty value in list"},
+		{"created:>yesterday", "", 0, "created:>yesterday", `invalid date "yesterday" (want YYYY-MM-DD or RFC 3339)`},
+		{"created:>2026-09-10 created:<2026-09-01", "", 20, "created:<2026-09-01", "date range is empty"},
+		{"updated:>2026-09-01T10:00:00Z updated:<=2026-09-01T10:00:00Z", "", 30, "updated:<=2026-09-01T10:00:00Z", "date range is empty"},
+		{"session:a session:b", "", 10, "session:b", `session is already set to "a"`},
+		{"статус:open", "", 0, "статус:open", "expected field:value"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			err := Tickets(tc.input, tc.me, &service.TicketFilters{})
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			if perr.Pos != tc.pos || perr.Token != tc.token || perr.Msg != tc.msg {
+				t.Fatalf("got pos=%d token=%q msg=%q\nwant pos=%d token=%q msg=%q", perr.Pos, perr.Token, perr.Msg, tc.pos, tc.token, tc.msg)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if err := Operators(strings.Repeat("x", MaxLength+1), &service.OperatorFilters{}); err == nil {
+		t.Fatal("long query: want error")
+	}
+	if err := Operators(strings.Repeat("role:a ", MaxTerms+1), &service.OperatorFilters{}); err == nil || !strings.Contains(err.Error(), "more than 32 terms") {
+		t.Fatalf("too many terms: err = %v", err)
+	}
+}
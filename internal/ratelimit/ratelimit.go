@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Группы бюджетов по умолчанию.
+const (
+	ClassSearch = "search"
+	ClassIndex  = "index"
+)
+
+// maxKeys ограничивает число бакетов: сверх него вытесняется бакет, к которому дольше всех не обращались
+// (его вызывающий при следующем запросе получит полный бюджет).
+const maxKeys = 10000
+
+// Budget — параметры token bucket: RPS — скорость пополнения, Burst — ёмкость.
+type Budget struct {
+	RPS   float64
+	Burst int
+}
+
+// ParseBudget разбирает бюджет в формате "rps:burst" (например, "20:40").
+func ParseBudget(s string) (Budget, error) {
+	rpsStr, burstStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Budget{}, fmt.Errorf("budget %q: want rps:burst", s)
+	}
+	rps, err := strconv.ParseFloat(rpsStr, 64)
+	if err != nil || rps <= 0 {
+		return Budget{}, fmt.Errorf("budget %q: rps must be a positive number", s)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil || burst <= 0 {
+		return Budget{}, fmt.Errorf("budget %q: burst must be a positive integer", s)
+	}
+	return Budget{RPS: rps, Burst: burst}, nil
+}
+
+// ParseRoutes разбирает переопределения по маршрутам: "SearchSessions=5:10,IndexTicket=50:100".
+func ParseRoutes(s string) (map[string]Budget, error) {
+	routes := map[string]Budget{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		route, budget, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("route %q: want Method=rps:burst", item)
+		}
+		b, err := ParseBudget(budget)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		routes[strings.TrimSpace(route)] = b
+	}
+	return routes, nil
+}
+
+// Limiter — token bucket на каждую пару (бюджет, вызывающий). Бюджет выбирается по маршруту
+// (если для него задано переопределение), иначе по группе search/index.
+type Limiter struct {
+	classes map[string]Budget
+	routes  map[string]Budget
+
+	mu      sync.Mutex
+	order   *list.List // фронт — бакеты с самыми свежими обращениями
+	buckets map[string]*list.Element
+}
+
+type bucket struct {
+	key string
+	lim *rate.Limiter
+}
+
+// New создаёт Limiter с бюджетами групп search/index и переопределениями по маршрутам (имя RPC без сервиса).
+func New(search, index Budget, routes map[string]Budget) *Limiter {
+	return &Limiter{
+		classes: map[string]Budget{ClassSearch: search, ClassIndex: index},
+		routes:  routes,
+		order:   list.New(),
+		buckets: map[string]*list.Element{},
+	}
+}
+
+// Allow списывает токен для вызова route (например, "SearchTickets") вызывающим caller.
+// При исчерпании бюджета возвращает false и время, через которое стоит повторить.
+func (l *Limiter) Allow(route, caller string) (bool, time.Duration) {
+	budgetKey, budget := l.budgetFor(route)
+	key := budgetKey + "|" + caller
+	now := time.Now()
+
+	l.mu.Lock()
+	el, ok := l.buckets[key]
+	if ok {
+		l.order.MoveToFront(el)
+	} else {
+		el = l.order.PushFront(&bucket{key: key, lim: rate.NewLimiter(rate.Limit(budget.RPS), budget.Burst)})
+		l.buckets[key] = el
+		for l.order.Len() > maxKeys {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*bucket).key)
+		}
+	}
+	b := el.Value.(*bucket)
+	l.mu.Unlock()
+
+	r := b.lim.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+func (l *Limiter) budgetFor(route string) (string, Budget) {
+	if b, ok := l.routes[route]; ok {
+		return route, b
+	}
+	class := ClassSearch
+	if strings.HasPrefix(route, "Index") {
+		class = ClassIndex
+	}
+	return class, l.classes[class]
+}
+
+// RetryAfterSeconds округляет задержку вверх до целых секунд (для заголовка Retry-After).
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
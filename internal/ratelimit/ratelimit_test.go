@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBudget(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Budget
+		ok   bool
+	}{
+		{"20:40", Budget{RPS: 20, Burst: 40}, true},
+		{" 0.5:1 ", Budget{RPS: 0.5, Burst: 1}, true},
+		{"20", Budget{}, false},
+		{"0:10", Budget{}, false},
+		{"-1:10", Budget{}, false},
+		{"10:0", Budget{}, false},
+		{"10:1.5", Budget{}, false},
+	}
+	for _, tc := range cases {
+		got, err := ParseBudget(tc.in)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("ParseBudget(%q) = %+v, %v; want %+v, ok=%v", tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	got, err := ParseRoutes(" SearchSessions=5:10, ,IndexTicket=50:100")
+	if err != nil {
+		t.Fatalf("ParseRoutes: %v", err)
+	}
+	want := map[string]Budget{"SearchSessions": {RPS: 5, Burst: 10}, "IndexTicket": {RPS: 50, Burst: 100}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRoutes = %+v, want %+v", got, want)
+	}
+	for _, bad := range []string{"SearchSessions", "SearchSessions=5"} {
+		if _, err := ParseRoutes(bad); err == nil {
+			t.Errorf("ParseRoutes(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestAllowExhaustionAndRefill(t *testing.T) {
+	l := New(Budget{RPS: 50, Burst: 2}, Budget{RPS: 1, Burst: 1}, nil)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("SearchTickets", "sub:op-1"); !ok {
+			t.Fatalf("call %d within burst was limited", i+1)
+		}
+	}
+	ok, retryAfter := l.Allow("SearchTickets", "sub:op-1")
+	if ok || retryAfter <= 0 || retryAfter > 20*time.Millisecond {
+		t.Fatalf("Allow after burst = %v, %s; want limited with a retry within one token (20ms)", ok, retryAfter)
+	}
+	// отказ не расходует токен: после паузы retryAfter вызов проходит
+	time.Sleep(retryAfter + 5*time.Millisecond)
+	if ok, _ := l.Allow("SearchTickets", "sub:op-1"); !ok {
+		t.Error("call after retry-after was limited, want the bucket refilled")
+	}
+}
+
+func TestAllowKeys(t *testing.T) {
+	l := New(Budget{RPS: 1, Burst: 1}, Budget{RPS: 1, Burst: 1}, map[string]Budget{"SearchSessions": {RPS: 1, Burst: 1}})
+	calls := []struct {
+		route, caller string
+		ok            bool
+	}{
+		{"SearchTickets", "sub:op-1", true},
+		{"SearchOperators", "sub:op-1", false}, // тот же бюджет search
+		{"SearchTickets", "sub:op-2", true},    // другой вызывающий — свой бакет
+		{"SearchTickets", "ip:10.0.0.1", true}, // анонимный вызывающий по IP
+		{"SearchSessions", "sub:op-1", true},   // переопределение маршрута — отдельный бюджет
+		{"SearchSessions", "sub:op-1", false},
+		{"IndexTicket", "sub:op-1", true}, // группа index
+		{"IndexSession", "sub:op-1", false},
+	}
+	for _, c := range calls {
+		if ok, _ := l.Allow(c.route, c.caller); ok != c.ok {
+			t.Errorf("Allow(%s, %s) = %v, want %v", c.route, c.caller, ok, c.ok)
+		}
+	}
+}
+
+func TestBucketsAreCapped(t *testing.T) {
+	l := New(Budget{RPS: 1, Burst: 1}, Budget{RPS: 1, Burst: 1}, nil)
+	l.Allow("SearchTickets", "sub:op-1")
+	for i := range maxKeys {
+		if i == maxKeys/2 {
+			l.Allow("SearchTickets", "sub:op-1") // свежее обращение защищает от вытеснения
+		}
+		l.Allow("SearchTickets", fmt.Sprintf("ip:10.0.%d.%d", i/256, i%256))
+	}
+	if n := len(l.buckets); n != maxKeys {
+		t.Fatalf("%d buckets, want the cap %d", n, maxKeys)
+	}
+	if _, ok := l.buckets["search|ip:10.0.0.0"]; ok {
+		t.Error("the least recently used bucket was kept")
+	}
+	if ok, _ := l.Allow("SearchTickets", "sub:op-1"); ok {
+		t.Error("recently used bucket was evicted: its budget was reset")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]int{
+		10 * time.Millisecond:   1,
+		time.Second:             1,
+		1001 * time.Millisecond: 2,
+		0:                       1,
+	} {
+		if got := RetryAfterSeconds(d); got != want {
+			t.Errorf("RetryAfterSeconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Типы схем (schemaType реестра; пустой тип в ответе реестра — AVRO).
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Schema — схема из реестра.
+type Schema struct {
+	ID   int    `json:"id"`
+	Type string `json:"schemaType"`
+	Text string `json:"schema"`
+}
+
+// ErrNotFound — схемы с таким id нет в источнике.
+var ErrNotFound = errors.New("schema not found")
+
+// ResponseError — неуспешный ответ реестра: HTTP-статус и сообщение реестра. Ответ 404 — ErrNotFound.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Message    string // пусто, если тело ответа — не JSON-ошибка реестра
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("registry: %s", e.Status)
+	}
+	return fmt.Sprintf("registry: %s: %s", e.Status, e.Message)
+}
+
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Source разрешает схемы по id (из заголовка Confluent wire format).
+type Source interface {
+	ByID(ctx context.Context, id int) (*Schema, error)
+}
+
+// Client — клиент Confluent-совместимого Schema Registry (REST API) с кэшем схем.
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+
+	mu  sync.Mutex
+	ids map[int]*Schema // id схем неизменяемы и кэшируются навсегда
+}
+
+// NewClient создаёт клиент реестра; username и password — basic auth (опционально).
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 10 * time.Second},
+		ids:      map[int]*Schema{},
+	}
+}
+
+// ByID возвращает схему по глобальному id (GET /schemas/ids/{id}).
+func (c *Client) ByID(ctx context.Context, id int) (*Schema, error) {
+	c.mu.Lock()
+	s, ok := c.ids[id]
+	c.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+	s = &Schema{}
+	if err := c.get(ctx, "/schemas/ids/"+strconv.Itoa(id), s); err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	s.ID = id
+	s.normalize()
+	c.mu.Lock()
+	c.ids[id] = s
+	c.mu.Unlock()
+	return s, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return &ResponseError{StatusCode: resp.StatusCode, Status: resp.Status, Message: apiErr.Message}
+	}
+	return json.Unmarshal(body, out)
+}
+
+func (s *Schema) normalize() {
+	if s.Type == "" {
+		s.Type = TypeAvro
+	}
+}
+
+// Dir — схемы из локального каталога для работы без реестра: <id>.avsc / <id>.proto для схем,
+// на которые ссылается заголовок сообщения.
+type Dir struct {
+	path string
+}
+
+// NewDir создаёт источник схем из каталога path.
+func NewDir(path string) *Dir {
+	return &Dir{path: path}
+}
+
+// ByID читает схему <id>.avsc или <id>.proto.
+func (d *Dir) ByID(_ context.Context, id int) (*Schema, error) {
+	s, err := d.read(strconv.Itoa(id))
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	s.ID = id
+	return s, nil
+}
+
+func (d *Dir) read(name string) (*Schema, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid schema name %q", name)
+	}
+	for _, f := range []struct{ ext, typ string }{{".avsc", TypeAvro}, {".proto", TypeProtobuf}} {
+		data, err := os.ReadFile(filepath.Join(d.path, name+f.ext))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: f.typ, Text: string(data)}, nil
+	}
+	return nil, fmt.Errorf("no %s.avsc or %s.proto in %s: %w", name, name, d.path, ErrNotFound)
+}
@@ -0,0 +1,47 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		if user, pass, _ := r.BasicAuth(); user != "search" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			w.Write([]byte(`{"schema":"{\"type\":\"string\"}"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/", "search", "secret")
+	ctx := context.Background()
+	for range 2 {
+		s, err := c.ByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("ByID: %v", err)
+		}
+		if s.ID != 1 || s.Type != TypeAvro || s.Text != `{"type":"string"}` {
+			t.Fatalf("ByID = %+v, want Avro schema 1", s)
+		}
+	}
+	if calls["/schemas/ids/1"] != 1 {
+		t.Errorf("schema 1 fetched %d times, want it cached", calls["/schemas/ids/1"])
+	}
+	if _, err := c.ByID(ctx, 2); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "Schema not found") {
+		t.Errorf("ByID(2) = %v, want ErrNotFound with the registry message", err)
+	}
+}
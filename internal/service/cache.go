@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/cache"
+	"github.com/psds-microservice/search-service/internal/metrics"
+)
+
+// WithCache включает кэширование результатов поиска на ttl. Записи индекса сбрасываются, когда этот же
+// процесс индексирует в него документ; изменения из других процессов видны не позже чем через ttl.
+func WithCache(c cache.Cache, ttl time.Duration) Option {
+	return func(s *SearchService) {
+		s.cache = c
+		s.cacheTTL = ttl
+	}
+}
+
+// cacheKey строит ключ по индексу, обязательным фильтрам вызывающего и телу запроса (с пагинацией).
+// Фильтры входят в ключ явно: вызывающие с разными арендаторами или правами не делят записи,
+// даже если тело запроса у них совпадает.
+func cacheKey(p *searchPlan) (string, error) {
+	scope, err := json.Marshal(p.scope)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(p.req) // json.Marshal сортирует ключи map — ключ стабилен
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s", p.index, scope, body)))
+	return p.index + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// cachedSearch возвращает результат из кэша или выполняет search и кладёт результат в кэш.
+func cachedSearch[R any](s *SearchService, ctx context.Context, entity string, p *searchPlan,
+	search func(ctx context.Context, p *searchPlan) (*R, error)) (*R, error) {
+	if s.cache == nil {
+		return search(ctx, p)
+	}
+	key, err := cacheKey(p)
+	if err != nil {
+		return search(ctx, p)
+	}
+	if data, ok := s.cache.Get(ctx, key); ok {
+		var cached R
+		if err := json.Unmarshal(data, &cached); err == nil {
+			metrics.CacheRequests.WithLabelValues(entity, metrics.CacheHit).Inc()
+			return &cached, nil
+		}
+	}
+	metrics.CacheRequests.WithLabelValues(entity, metrics.CacheMiss).Inc()
+	result, err := search(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(result); err == nil {
+		s.cache.Set(ctx, key, data, s.cacheTTL, p.index)
+	}
+	return result, nil
+}
+
+// invalidate сбрасывает закэшированные результаты по индексу после записи в него.
+func (s *SearchService) invalidate(ctx context.Context, index string) {
+	if s.cache != nil {
+		s.cache.InvalidateTag(ctx, index)
+	}
+}
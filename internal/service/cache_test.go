@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/cache"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/policy"
+	"github.com/psds-microservice/search-service/internal/query"
+	"github.com/psds-microservice/search-service/internal/tenant"
+)
+
+func TestCachedSearchInvalidation(t *testing.T) {
+	ctx := context.Background()
+	es := elasticsearch.NewMemory()
+	svc, err := NewSearchServiceWithIndexer(es, WithCache(cache.NewLRU(100), time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, Subject: "printer", Status: "open"}); err != nil {
+		t.Fatalf("IndexTicket: %v", err)
+	}
+	search := func() int64 {
+		t.Helper()
+		res, err := svc.SearchTickets(ctx, &TicketFilters{Status: "open"})
+		if err != nil {
+			t.Fatalf("SearchTickets: %v", err)
+		}
+		return res.Total
+	}
+	if got := search(); got != 1 {
+		t.Fatalf("total = %d, want 1", got)
+	}
+	// запись в обход сервиса кэш не сбрасывает: виден закэшированный результат
+	if err := es.UpdateDocument(ctx, indexTickets, "2", map[string]interface{}{"ticket_id": 2, "status": "open"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(); got != 1 {
+		t.Fatalf("total = %d, want the cached 1", got)
+	}
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 3, Subject: "scanner", Status: "open"}); err != nil {
+		t.Fatalf("IndexTicket: %v", err)
+	}
+	if got := search(); got != 3 {
+		t.Errorf("total after indexing = %d, want 3 (tickets entries invalidated)", got)
+	}
+}
+
+func TestCacheKeySeparatesScopes(t *testing.T) {
+	p, err := policy.Load(filepath.Join("..", "..", "deployments", "policy.example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestService(t, WithTenancy(tenant.ModeFilter), WithPolicy(p))
+	caller := func(tenantID, sub string, roles ...string) context.Context {
+		ctx := tenant.WithTenant(context.Background(), tenantID)
+		return auth.WithClaims(ctx, &auth.Claims{Subject: sub, Roles: roles, Raw: jwt.MapClaims{"sub": sub}})
+	}
+	key := func(ctx context.Context) string {
+		t.Helper()
+		plan, err := svc.planTickets(ctx, &TicketFilters{Status: "open"})
+		if err != nil {
+			t.Fatalf("planTickets: %v", err)
+		}
+		k, err := cacheKey(plan)
+		if err != nil {
+			t.Fatalf("cacheKey: %v", err)
+		}
+		return k
+	}
+
+	base := key(caller("acme", "op-1", "operator"))
+	if got := key(caller("acme", "op-1", "operator")); got != base {
+		t.Errorf("same caller: key %s != %s, want equal", got, base)
+	}
+	for name, ctx := range map[string]context.Context{
+		"other tenant":   caller("globex", "op-1", "operator"),
+		"other operator": caller("acme", "op-2", "operator"),
+		"other role":     caller("acme", "op-1", "admin"),
+	} {
+		if got := key(ctx); got == base {
+			t.Errorf("%s shares the cache key %s, want a separate entry", name, got)
+		}
+	}
+
+	// scope входит в ключ сам по себе, а не только через тело запроса
+	req := &query.Search{Query: query.MatchAll(), Size: 20}
+	a, _ := cacheKey(&searchPlan{index: indexTickets, req: req, scope: []query.Query{query.Term("operator_id", "op-1")}})
+	b, _ := cacheKey(&searchPlan{index: indexTickets, req: req, scope: []query.Query{query.Term("operator_id", "op-2")}})
+	if a == b {
+		t.Error("plans differing only in scope share a cache key")
+	}
+}
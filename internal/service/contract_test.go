@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/tenant"
+)
+
+// Контрактные тесты: сервис поверх настоящего HTTP-клиента против fakeES с записанными обменами
+// (testdata/contract). Тела запросов сравниваются точно — изменение генерируемого запроса ES ломает тест;
+// после намеренного изменения перезапишите фикстуры: go test ./internal/service -run Contract -update
+
+// recordedAt — фиксированное время для created_at/updated_at: без него сервис подставляет время индексации.
+var recordedAt = time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+
+func newContractService(t *testing.T, fixtures []string, opts ...Option) *SearchService {
+	t.Helper()
+	es, _ := newFakeES(t, fixtures...)
+	svc, err := NewSearchService(es, opts...)
+	if err != nil {
+		t.Fatalf("NewSearchService: %v", err)
+	}
+	return svc
+}
+
+func TestContractEnsureIndices(t *testing.T) {
+	t.Run("existing", func(t *testing.T) {
+		newContractService(t, []string{"indices_exist"})
+	})
+	t.Run("created with mappings", func(t *testing.T) {
+		newContractService(t, []string{"indices_create"})
+	})
+	t.Run("forbidden", func(t *testing.T) {
+		es, _ := newFakeES(t, "indices_create_forbidden")
+		_, err := NewSearchService(es)
+		if err == nil || !strings.Contains(err.Error(), "ensure tickets index") || !strings.Contains(err.Error(), "security_exception") {
+			t.Fatalf("err = %v, want ensure tickets index: ... security_exception", err)
+		}
+	})
+	t.Run("mapping conflict", func(t *testing.T) {
+		es, _ := newFakeES(t, "indices_mapping_conflict")
+		_, err := NewSearchService(es)
+		if !errors.Is(err, elasticsearch.ErrMappingConflict) || !strings.Contains(err.Error(), "ensure tickets index") {
+			t.Fatalf("err = %v, want ensure tickets index: ... ErrMappingConflict", err)
+		}
+	})
+	t.Run("per tenant on first write", func(t *testing.T) {
+		svc := newContractService(t, []string{"index_ticket_tenant_index"}, WithTenancy(tenant.ModeIndex))
+		ctx := tenant.WithTenant(context.Background(), "acme")
+		for id := int64(1); id <= 2; id++ {
+			if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: id, Status: "open", CreatedAt: recordedAt, UpdatedAt: recordedAt}); err != nil {
+				t.Fatalf("IndexTicket %d: %v", id, err)
+			}
+		}
+	})
+}
+
+func TestContractSearchTickets(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_tickets"})
+	res, err := svc.SearchTickets(context.Background(), &TicketFilters{
+		Status:     "open",
+		SessionID:  "7f1c2a9e-0b1d-4c55-9a61-3c1e2b7d9f10",
+		ClientID:   "c-42",
+		OperatorID: "op-7",
+		Created:    TimeRange{From: time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		Limit:      2,
+		Offset:     4,
+	})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 7 || !res.HasMore || len(res.Tickets) != 2 {
+		t.Fatalf("total=%d hasMore=%v hits=%d, want 7/true/2", res.Total, res.HasMore, len(res.Tickets))
+	}
+	if h := res.Tickets[1]; h.TicketID != 106 || h.Subject != "Audio drops" || h.SessionID != "7f1c2a9e-0b1d-4c55-9a61-3c1e2b7d9f10" {
+		t.Fatalf("hit = %+v", h)
+	}
+}
+
+func TestContractSearchTicketsMultiValue(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_tickets_multi"})
+	// одиночное поле — алиас: status сливается со statuses без повторов, одно значение остаётся term
+	res, err := svc.SearchTickets(context.Background(), &TicketFilters{
+		Status:          "open",
+		Statuses:        []string{"open", "in_progress"},
+		ExcludeStatuses: []string{"closed"},
+		OperatorIDs:     []string{"op-7", "op-9"},
+		ClientID:        "c-42",
+	})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 2 || res.Tickets[1].TicketID != 111 {
+		t.Fatalf("got %+v", res)
+	}
+}
+
+func TestContractSearchTicketsDefaults(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_tickets_defaults"})
+	// без фильтров — match_all; лимит ограничивается сверху, отрицательное смещение сбрасывается в 0
+	res, err := svc.SearchTickets(context.Background(), &TicketFilters{Limit: 1000, Offset: -5})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 0 || res.HasMore || len(res.Tickets) != 0 {
+		t.Fatalf("got %+v, want empty result", res)
+	}
+}
+
+func TestContractSearchSessions(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_sessions"})
+	res, err := svc.SearchSessions(context.Background(), &SessionFilters{Status: "active", PIN: "4821", Updated: TimeRange{From: recordedAt}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 1 || res.HasMore || res.Sessions[0].PIN != "4821" || res.Sessions[0].Status != "active" {
+		t.Fatalf("got %+v", res)
+	}
+}
+
+func TestContractSearchSessionsTenantFilter(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_sessions_tenant_filter"}, WithTenancy(tenant.ModeFilter))
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	if _, err := svc.SearchSessions(ctx, &SessionFilters{ClientID: "c-42"}); err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+}
+
+func TestContractSearchOperators(t *testing.T) {
+	svc := newContractService(t, []string{"indices_exist", "search_operators"})
+	res, err := svc.SearchOperators(context.Background(), &OperatorFilters{Region: "eu", DisplayName: "Anna Petrova", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if res.Total != 1 || res.Operators[0].UserID != "op-7" || res.Operators[0].DisplayName != "Anna Petrova" {
+		t.Fatalf("got %+v", res)
+	}
+}
+
+func TestContractIndex(t *testing.T) {
+	ctx := context.Background()
+	t.Run("ticket", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "index_ticket"})
+		err := svc.IndexTicket(ctx, &IndexTicketInput{
+			TicketID:   105,
+			SessionID:  "7f1c2a9e-0b1d-4c55-9a61-3c1e2b7d9f10",
+			ClientID:   "c-42",
+			OperatorID: "op-7",
+			Subject:    "Cannot join video call",
+			Status:     "open",
+			CreatedAt:  recordedAt.Add(-time.Hour),
+			UpdatedAt:  recordedAt,
+		})
+		if err != nil {
+			t.Fatalf("IndexTicket: %v", err)
+		}
+	})
+	t.Run("session", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "index_session"})
+		err := svc.IndexSession(ctx, &IndexSessionInput{
+			SessionID: "7f1c2a9e-0b1d-4c55-9a61-3c1e2b7d9f10",
+			ClientID:  "c-42",
+			PIN:       "4821",
+			Status:    "finished",
+			CreatedAt: recordedAt.Add(-time.Hour),
+			EndedAt:   recordedAt,
+			UpdatedAt: recordedAt,
+		})
+		if err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+	})
+	t.Run("operator", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "index_operator"})
+		err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-7", DisplayName: "Anna Petrova", Region: "eu", Role: "operator", UpdatedAt: recordedAt})
+		if err != nil {
+			t.Fatalf("IndexOperator: %v", err)
+		}
+	})
+	t.Run("operator unchanged", func(t *testing.T) {
+		// имя и регион те же, что у прошлого события: копии в тикетах не переписываются
+		svc := newContractService(t, []string{"indices_exist", "index_operator_unchanged"})
+		err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-7", DisplayName: "Anna Petrova", Region: "eu", Role: "operator", UpdatedAt: recordedAt})
+		if err != nil {
+			t.Fatalf("IndexOperator: %v", err)
+		}
+	})
+}
+
+func TestContractErrors(t *testing.T) {
+	ctx := context.Background()
+	t.Run("search rejected", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "search_error"})
+		_, err := svc.SearchTickets(ctx, &TicketFilters{OperatorID: "abc"})
+		if err == nil || !strings.Contains(err.Error(), "400 Bad Request - search_phase_execution_exception: all shards failed") {
+			t.Fatalf("err = %v", err)
+		}
+	})
+	t.Run("index rejected", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "index_rejected"})
+		err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 105, Status: "open", UpdatedAt: recordedAt})
+		if err == nil || !strings.Contains(err.Error(), "429 Too Many Requests - es_rejected_execution_exception") {
+			t.Fatalf("err = %v", err)
+		}
+	})
+	t.Run("malformed response", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "search_malformed_response"})
+		_, err := svc.SearchOperators(ctx, &OperatorFilters{Role: "supervisor"})
+		if err == nil || !strings.Contains(err.Error(), "decode response") {
+			t.Fatalf("err = %v", err)
+		}
+	})
+	t.Run("missing tenant sends nothing", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist"}, WithTenancy(tenant.ModeFilter))
+		if _, err := svc.SearchTickets(ctx, &TicketFilters{}); err == nil {
+			t.Fatal("want error without tenant")
+		}
+	})
+}
+
+func TestContractTicketComments(t *testing.T) {
+	ctx := context.Background()
+	t.Run("add comment", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "index_ticket_comment"})
+		err := svc.AddTicketComment(ctx, &TicketCommentInput{
+			TicketID:  105,
+			CommentID: "cm-3",
+			Author:    "op-7",
+			Text:      "Client reinstalled the camera driver, video works again",
+			CreatedAt: recordedAt.Add(-20 * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("AddTicketComment: %v", err)
+		}
+	})
+	t.Run("search text", func(t *testing.T) {
+		svc := newContractService(t, []string{"indices_exist", "search_tickets_text"})
+		res, err := svc.SearchTickets(ctx, &TicketFilters{Text: "camera driver", Status: "open"})
+		if err != nil {
+			t.Fatalf("SearchTickets: %v", err)
+		}
+		if res.Total != 2 {
+			t.Fatalf("total = %d, want 2", res.Total)
+		}
+		// 105 совпал комментарием: сниппет из подсветки inner_hits; 108 — по subject, без комментария
+		if h := res.Tickets[0]; h.MatchedCommentID != "cm-3" || h.Snippet != "Client reinstalled the <em>camera</em> <em>driver</em>, video works again" {
+			t.Fatalf("hit 105 = %+v", h)
+		}
+		if h := res.Tickets[1]; h.MatchedCommentID != "" || h.Snippet != "" {
+			t.Fatalf("hit 108 = %+v, want no comment match", h)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/query"
+)
+
+// Денормализация: тикет хранит копию атрибутов оператора и сессии, чтобы SearchTickets фильтровал
+// и показывал их без поисков по другим индексам. Копии заполняются при индексации тикета и обновляются
+// через update_by_query при изменении оператора или сессии. Согласованность — в конечном счёте:
+// update_by_query не видит тикет до refresh индекса — такой тикет учтёт следующее событие. Событие, не
+// изменившее копируемых полей, тикеты не трогает (unchanged).
+
+// Поля тикета, скопированные из индексов операторов и сессий.
+const (
+	fieldOperatorName   = "operator_display_name"
+	fieldOperatorRegion = "operator_region"
+	fieldSessionStatus  = "session_status"
+	fieldSessionPIN     = "session_pin"
+)
+
+// enrichTicket копирует в doc атрибуты оператора и сессии тикета. Поля ещё не проиндексированных
+// оператора или сессии очищаются (иначе после смены operator_id остались бы копии прежнего) — их
+// допишет propagate. Без operator_id или session_id во входных данных соответствующие поля не меняются.
+func (s *SearchService) enrichTicket(ctx context.Context, doc map[string]interface{}, in *IndexTicketInput, tenantID string) error {
+	if in.OperatorID != "" {
+		op, err := s.lookup(ctx, indexOperators, in.OperatorID, tenantID)
+		if err != nil {
+			return fmt.Errorf("lookup operator %s: %w", in.OperatorID, err)
+		}
+		doc[fieldOperatorName] = op["display_name"]
+		doc[fieldOperatorRegion] = op["region"]
+	}
+	if in.SessionID != "" {
+		ses, err := s.lookup(ctx, indexSessions, in.SessionID, tenantID)
+		if err != nil {
+			return fmt.Errorf("lookup session %s: %w", in.SessionID, err)
+		}
+		doc[fieldSessionStatus] = ses["status"]
+		doc[fieldSessionPIN] = ses["pin"]
+	}
+	return nil
+}
+
+// lookup возвращает _source документа id индекса base арендатора (nil — документа нет). Realtime get
+// видит документ сразу после записи, в отличие от поиска до refresh индекса.
+func (s *SearchService) lookup(ctx context.Context, base, id, tenantID string) (map[string]interface{}, error) {
+	return s.getDocument(ctx, s.indexName(base, tenantID), tenantID, id)
+}
+
+// propagate записывает fields во все тикеты арендатора, у которых field = value.
+func (s *SearchService) propagate(ctx context.Context, tenantID, field, value string, fields map[string]interface{}) error {
+	index := s.indexName(indexTickets, tenantID)
+	filter := append(s.tenantScope(tenantID), query.Term(field, value))
+	if _, err := s.es.UpdateByQuery(ctx, index, &query.BoolQuery{Filter: filter}, fields); err != nil {
+		return fmt.Errorf("update tickets with %s %s: %w", field, value, err)
+	}
+	s.invalidate(ctx, index)
+	return nil
+}
+
+// unchanged сообщает, что прочитанный до записи документ doc уже содержит values (поле → значение), а событие
+// в at новее последнего применённого к нему: копии в тикетах не меняются, propagate не нужен. Повтор уже
+// применённого события (тот же at — например, после сбоя propagate) и события без времени распространяются
+// всегда: иначе тикеты, не обновлённые прошлой попыткой, остались бы со старыми копиями.
+func unchanged(doc map[string]interface{}, at time.Time, values map[string]interface{}) bool {
+	stored, ok := doc[elasticsearch.FieldEventAt].(float64)
+	if !ok || at.IsZero() || int64(stored) >= at.UnixMilli() {
+		return false
+	}
+	for field, v := range values {
+		if doc[field] != v {
+			return false
+		}
+	}
+	return true
+}
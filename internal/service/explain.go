@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/logger"
+)
+
+// WithSlowQueryLog включает лог поисковых запросов, выполнявшихся дольше threshold (тело запроса и took).
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(s *SearchService) { s.slowQuery = threshold }
+}
+
+// logSlowQuery пишет в лог запрос, выполнявшийся дольше порога. elapsed — время вызова с учётом сети,
+// took — время выполнения на стороне ES.
+func (s *SearchService) logSlowQuery(ctx context.Context, p *searchPlan, elapsed time.Duration, took int64) {
+	if s.slowQuery <= 0 || elapsed < s.slowQuery {
+		return
+	}
+	body, _ := json.Marshal(p.req)
+	logger.FromContext(ctx, slog.Default()).Warn("slow search query",
+		slog.String("index", p.index),
+		slog.Int64("took_ms", took),
+		slog.Int64("elapsed_ms", elapsed.Milliseconds()),
+		slog.String("query", string(body)),
+	)
+}
+
+// Explanation — разбор поиска для отладки: запрос ES, profile и объяснение score по каждому хиту.
+type Explanation struct {
+	Index   string
+	Query   json.RawMessage // тело запроса к ES, включая обязательные фильтры (арендатор, политика)
+	Profile json.RawMessage
+	TookMs  int64
+	Total   int64
+	Hits    []HitExplanation
+}
+
+type HitExplanation struct {
+	ID          string
+	Score       float64
+	Explanation json.RawMessage
+}
+
+func (s *SearchService) ExplainTickets(ctx context.Context, filters *TicketFilters) (*Explanation, error) {
+	p, err := s.planTickets(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.explain(ctx, p)
+}
+
+func (s *SearchService) ExplainSessions(ctx context.Context, filters *SessionFilters) (*Explanation, error) {
+	p, err := s.planSessions(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.explain(ctx, p)
+}
+
+func (s *SearchService) ExplainOperators(ctx context.Context, filters *OperatorFilters) (*Explanation, error) {
+	p, err := s.planOperators(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.explain(ctx, p)
+}
+
+// explain выполняет запрос с profile/explain в обход кэша.
+func (s *SearchService) explain(ctx context.Context, p *searchPlan) (*Explanation, error) {
+	body, err := json.Marshal(p.req)
+	if err != nil {
+		return nil, err
+	}
+	req := *p.req
+	req.Profile, req.Explain = true, true
+	resp, err := s.es.Search(ctx, p.index, &req)
+	if err != nil {
+		return nil, err
+	}
+	out := &Explanation{
+		Index:   p.index,
+		Query:   body,
+		Profile: resp.Profile,
+		TookMs:  resp.Took,
+		Total:   resp.Hits.Total.Value,
+		Hits:    make([]HitExplanation, 0, len(resp.Hits.Hits)),
+	}
+	for _, h := range resp.Hits.Hits {
+		out.Hits = append(out.Hits, HitExplanation{ID: h.ID, Score: h.Score, Explanation: h.Explanation})
+	}
+	return out, nil
+}
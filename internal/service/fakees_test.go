@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+)
+
+var update = flag.Bool("update", false, "re-record request bodies in testdata/contract from what the service sends")
+
+// exchange — одна записанная пара запрос/ответ Elasticsearch.
+type exchange struct {
+	Request struct {
+		Method string          `json:"method"`
+		Path   string          `json:"path"`
+		Body   json.RawMessage `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status int             `json:"status"`
+		Body   json.RawMessage `json:"body,omitempty"`
+	} `json:"response"`
+}
+
+// fakeES воспроизводит записанные обмены из testdata/contract строго по порядку и проверяет, что
+// сервис отправляет ровно ожидаемые метод, путь и JSON тела. С -update тела запросов перезаписываются
+// фактическими (ответы остаются записанными).
+type fakeES struct {
+	t     *testing.T
+	files []string
+	steps [][]exchange // по файлам фикстур
+
+	mu   sync.Mutex
+	file int
+	next int
+}
+
+// newFakeES поднимает фейковый ES по фикстурам files (testdata/contract/<name>.json), воспроизводимым подряд.
+// В конце теста проверяет, что все обмены были использованы.
+func newFakeES(t *testing.T, files ...string) (*elasticsearch.Client, *fakeES) {
+	t.Helper()
+	f := &fakeES{t: t}
+	for _, name := range files {
+		path := filepath.Join("testdata", "contract", name+".json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		var steps []exchange
+		if err := json.Unmarshal(data, &steps); err != nil {
+			t.Fatalf("parse fixture %s: %v", path, err)
+		}
+		f.files = append(f.files, path)
+		f.steps = append(f.steps, steps)
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(func() {
+		srv.Close()
+		f.finish()
+	})
+	return elasticsearch.NewClient(srv.URL, false, "", "", elasticsearch.WithFlavor(elasticsearch.FlavorElasticsearch)), f
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.file < len(f.steps) && f.next >= len(f.steps[f.file]) {
+		f.file, f.next = f.file+1, 0
+	}
+	if f.file == len(f.steps) {
+		f.t.Errorf("unexpected request %s %s %s", r.Method, r.URL.RequestURI(), body)
+		http.Error(w, `{"error":{"type":"unexpected_request","reason":"no more recorded exchanges"}}`, http.StatusInternalServerError)
+		return
+	}
+	ex := &f.steps[f.file][f.next]
+	f.next++
+
+	if got := r.Method + " " + r.URL.RequestURI(); got != ex.Request.Method+" "+ex.Request.Path {
+		f.t.Errorf("%s step %d: request %s, want %s %s", f.files[f.file], f.next, got, ex.Request.Method, ex.Request.Path)
+	}
+	if *update {
+		ex.Request.Body = nil
+		if len(body) > 0 {
+			ex.Request.Body = body
+		}
+	} else if diff := jsonDiff(ex.Request.Body, body); diff != "" {
+		f.t.Errorf("%s step %d: %s %s body mismatch\n%s", f.files[f.file], f.next, r.Method, r.URL.Path, diff)
+	}
+
+	if len(ex.Response.Body) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(ex.Response.Status)
+	w.Write(ex.Response.Body)
+}
+
+func (f *fakeES) finish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if *update {
+		for i, steps := range f.steps {
+			data, err := json.MarshalIndent(steps, "", "  ")
+			if err != nil {
+				f.t.Fatalf("marshal fixture: %v", err)
+			}
+			if err := os.WriteFile(f.files[i], append(data, '\n'), 0o644); err != nil {
+				f.t.Fatalf("write fixture: %v", err)
+			}
+		}
+		return
+	}
+	for i := f.file; i < len(f.steps); i++ {
+		from := 0
+		if i == f.file {
+			from = f.next
+		}
+		for _, ex := range f.steps[i][from:] {
+			f.t.Errorf("%s: expected request %s %s was not sent", f.files[i], ex.Request.Method, ex.Request.Path)
+		}
+	}
+}
+
+// jsonDiff сравнивает JSON-тела без учёта форматирования и порядка ключей; пусто — совпадают.
+func jsonDiff(want, got []byte) string {
+	if len(bytes.TrimSpace(want)) == 0 && len(bytes.TrimSpace(got)) == 0 {
+		return ""
+	}
+	var w, g interface{}
+	if err := json.Unmarshal(want, &w); err != nil {
+		return fmt.Sprintf("recorded body is not JSON: %v", err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		return fmt.Sprintf("sent body is not JSON: %v: %s", err, got)
+	}
+	if reflect.DeepEqual(w, g) {
+		return ""
+	}
+	wi, _ := json.MarshalIndent(w, "", "  ")
+	gi, _ := json.MarshalIndent(g, "", "  ")
+	return fmt.Sprintf("want:\n%s\ngot:\n%s", wi, gi)
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/tenant"
+)
+
+// MigrateIndices приводит маппинги индексов сервиса к текущим (elasticsearch.Client.MigrateIndex), при
+// несовместимых изменениях — с переиндексацией. В режиме tenant.ModeIndex переносятся индексы арендаторов
+// tenants; в остальных режимах tenants игнорируются. Запускать при остановленных api и worker.
+func MigrateIndices(ctx context.Context, es *elasticsearch.Client, tenancy string, tenants []string) error {
+	s := &SearchService{tenancy: tenancy}
+	if tenancy != tenant.ModeIndex {
+		tenants = []string{""}
+	} else if len(tenants) == 0 {
+		return fmt.Errorf("tenancy mode %q: tenants to migrate are required", tenancy)
+	}
+	for _, tenantID := range tenants {
+		for _, idx := range indices {
+			if err := es.MigrateIndex(ctx, s.indexName(idx.base, tenantID), idx.mapping()); err != nil {
+				return fmt.Errorf("migrate %s index: %w", idx.base, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+)
+
+// Порядок записей: события могут прийти не в том порядке, в каком произошли (повтор после сбоя,
+// несколько источников), и более старое не должно затирать более новое. Запись с UpdatedAt раньше
+// последнего применённого к документу события не применяется и отклоняется с кодом FAILED_PRECONDITION.
+// Сравнение и запись атомарно выполняет UpdateDocument (elasticsearch.UpdateOptions.EventAt): отдельного
+// чтения нет, параллельные worker'ы и gRPC-вызовы не обгоняют друг друга. Сравниваются только времена
+// событий; нулевой UpdatedAt (время индексации) не упорядочивается.
+
+// staleError переводит elasticsearch.ErrStale в ошибку FAILED_PRECONDITION, прочие ошибки возвращает как есть.
+func staleError(err error, entity, id string, updated time.Time) error {
+	if !errors.Is(err, elasticsearch.ErrStale) {
+		return err
+	}
+	return helpyerrors.New(helpyerrors.CodeFailedPrecondition,
+		fmt.Sprintf("%s %s: update at %s is older than the last applied event", entity, id, formatTime(updated)))
+}
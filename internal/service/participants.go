@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+)
+
+// Участники сессии: каждое подключение оператора — элемент participants (operator_id, joined_at, left_at).
+// Из них пересчитываются operator_ids (операторы в порядке первого подключения), participant_count
+// (число операторов) и active_participant_count (подключения без left_at). Обновление — чтение документа
+// и частичная запись при неизменных с чтения participants (UpdateOptions.Expect); параллельная запись
+// участников или закрытие сессии приводят к повторному чтению, а не к потере подключения.
+
+// SessionParticipantInput — подключение или отключение оператора.
+type SessionParticipantInput struct {
+	SessionID  string    `json:"session_id"`
+	OperatorID string    `json:"operator_id"`
+	At         time.Time `json:"at"`   // время события; нулевое — время индексации
+	Left       bool      `json:"left"` // оператор отключился (иначе подключился)
+}
+
+// participation — одно подключение оператора к сессии.
+type participation struct {
+	OperatorID string `json:"operator_id"`
+	JoinedAt   string `json:"joined_at"`
+	LeftAt     string `json:"left_at,omitempty"`
+}
+
+// TrackSessionParticipant отмечает подключение или отключение оператора. Повторное подключение без
+// отключения и отключение без подключения ничего не меняют (повторная доставка события безопасна).
+// Сессия может быть ещё не проиндексирована: тогда создаётся документ с session_id и участниками.
+func (s *SearchService) TrackSessionParticipant(ctx context.Context, in *SessionParticipantInput) error {
+	index, tenantID, err := s.resolveIndex(ctx, indexSessions)
+	if err != nil {
+		return err
+	}
+	at := in.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return s.updateParticipants(ctx, index, tenantID, in.SessionID, func(ps []participation) ([]participation, bool) {
+		open := openParticipation(ps, in.OperatorID)
+		switch {
+		case !in.Left && open < 0:
+			return append(ps, participation{OperatorID: in.OperatorID, JoinedAt: formatTime(at)}), true
+		case in.Left && open >= 0:
+			ps[open].LeftAt = formatTime(at)
+			return ps, true
+		}
+		return ps, false
+	})
+}
+
+// closeParticipants завершает все открытые подключения сессии временем at (сессия завершена).
+func (s *SearchService) closeParticipants(ctx context.Context, index, tenantID, sessionID string, at time.Time) error {
+	return s.updateParticipants(ctx, index, tenantID, sessionID, func(ps []participation) ([]participation, bool) {
+		changed := false
+		for i := range ps {
+			if ps[i].LeftAt == "" {
+				ps[i].LeftAt = formatTime(at)
+				changed = true
+			}
+		}
+		return ps, changed
+	})
+}
+
+// updateParticipants применяет fn к участникам сессии и, если они изменились, записывает их вместе
+// с производными полями. Если участники изменились после чтения, документ перечитывается.
+func (s *SearchService) updateParticipants(ctx context.Context, index, tenantID, sessionID string, fn func([]participation) ([]participation, bool)) error {
+	for attempt := 1; ; attempt++ {
+		changed, err := s.writeParticipants(ctx, index, tenantID, sessionID, fn)
+		if errors.Is(err, elasticsearch.ErrConflict) {
+			if attempt == sessionUpdateAttempts {
+				return fmt.Errorf("session %s participants: %w", sessionID, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if changed {
+			s.invalidate(ctx, index)
+		}
+		return nil
+	}
+}
+
+// writeParticipants — одна попытка updateParticipants: запись применяется, только если participants
+// в документе те же, что были прочитаны (иначе elasticsearch.ErrConflict).
+func (s *SearchService) writeParticipants(ctx context.Context, index, tenantID, sessionID string, fn func([]participation) ([]participation, bool)) (bool, error) {
+	doc, err := s.getDocument(ctx, index, tenantID, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("get session %s: %w", sessionID, err)
+	}
+	var ps []participation
+	if raw, ok := doc["participants"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return false, fmt.Errorf("session %s participants: %w", sessionID, err)
+		}
+		if err := json.Unmarshal(data, &ps); err != nil {
+			return false, fmt.Errorf("session %s participants: %w", sessionID, err)
+		}
+	}
+	ps, changed := fn(ps)
+	if !changed {
+		return false, nil
+	}
+	fields := participantFields(ps)
+	fields["session_id"] = sessionID
+	opts := &elasticsearch.UpdateOptions{Expect: map[string]interface{}{"participants": doc["participants"]}}
+	if err := s.es.UpdateDocument(ctx, index, s.docID(tenantID, sessionID), s.withTenantField(fields, tenantID), opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// openParticipation возвращает индекс незавершённого подключения оператора или -1.
+func openParticipation(ps []participation, operatorID string) int {
+	for i := len(ps) - 1; i >= 0; i-- {
+		if ps[i].OperatorID == operatorID && ps[i].LeftAt == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// participantFields — участники и производные от них поля документа сессии.
+func participantFields(ps []participation) map[string]interface{} {
+	ids := make([]string, 0, len(ps))
+	seen := make(map[string]bool, len(ps))
+	active := 0
+	for _, p := range ps {
+		if !seen[p.OperatorID] {
+			seen[p.OperatorID] = true
+			ids = append(ids, p.OperatorID)
+		}
+		if p.LeftAt == "" {
+			active++
+		}
+	}
+	return map[string]interface{}{
+		"participants":             ps,
+		"operator_ids":             ids,
+		"participant_count":        len(ids),
+		"active_participant_count": active,
+	}
+}
@@ -2,10 +2,20 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/psds-microservice/helpy/limit"
+	"github.com/psds-microservice/search-service/internal/cache"
 	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/policy"
+	"github.com/psds-microservice/search-service/internal/query"
+	"github.com/psds-microservice/search-service/internal/sessionstate"
+	"github.com/psds-microservice/search-service/internal/tenant"
 )
 
 // SearchServicer — интерфейс для gRPC Deps (Dependency Inversion).
@@ -16,6 +26,11 @@ type SearchServicer interface {
 	IndexTicket(ctx context.Context, in *IndexTicketInput) error
 	IndexSession(ctx context.Context, in *IndexSessionInput) error
 	IndexOperator(ctx context.Context, in *IndexOperatorInput) error
+	AddTicketComment(ctx context.Context, in *TicketCommentInput) error
+	TrackSessionParticipant(ctx context.Context, in *SessionParticipantInput) error
+	ExplainTickets(ctx context.Context, filters *TicketFilters) (*Explanation, error)
+	ExplainSessions(ctx context.Context, filters *SessionFilters) (*Explanation, error)
+	ExplainOperators(ctx context.Context, filters *OperatorFilters) (*Explanation, error)
 }
 
 type TicketsSearchResult struct {
@@ -36,29 +51,72 @@ type OperatorsSearchResult struct {
 	HasMore   bool
 }
 
+// TimeRange — фильтр по дате: From включительно, To не включительно (FromExclusive и ToInclusive меняют
+// это для своей границы); нулевая граница не ограничивает.
+type TimeRange struct {
+	From          time.Time
+	To            time.Time
+	FromExclusive bool // From не включается (> вместо >=)
+	ToInclusive   bool // To включается (<= вместо <)
+}
+
+// IsZero сообщает, что диапазон не задан.
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// В фильтрах одиночное поле (Status) — алиас списка (Statuses): значения объединяются, совпадение с любым (OR).
+// Query — условия строки поиска (пакет querylang): они пересекаются с остальными фильтрами (AND) и сужают,
+// а не расширяют результат.
+
 type TicketFilters struct {
-	Status     string // фильтр по status
-	SessionID  string // фильтр по session_id
-	ClientID   string // фильтр по client_id
-	OperatorID string // фильтр по operator_id
-	Limit      int    // лимит результатов (по умолчанию 20)
-	Offset     int    // смещение для пагинации (по умолчанию 0)
+	Status          string         // фильтр по status
+	Statuses        []string       // фильтр по status: любой из
+	ExcludeStatuses []string       // исключить status
+	SessionID       string         // фильтр по session_id
+	ClientID        string         // фильтр по client_id
+	ClientIDs       []string       // фильтр по client_id: любой из
+	OperatorID      string         // фильтр по operator_id
+	OperatorIDs     []string       // фильтр по operator_id: любой из
+	OperatorRegion  string         // фильтр по региону оператора тикета
+	OperatorRegions []string       // фильтр по региону оператора тикета: любой из
+	Subject         string         // полнотекстовый поиск по subject (все слова)
+	Text            string         // полнотекстовый поиск по subject, notes и комментариям (все слова в одном из них)
+	Created         TimeRange      // фильтр по created_at
+	Updated         TimeRange      // фильтр по updated_at
+	Query           *TicketFilters // условия строки поиска: пересекаются с остальными фильтрами (AND)
+	Limit           int            // лимит результатов (по умолчанию 20)
+	Offset          int            // смещение для пагинации (по умолчанию 0)
 }
 
 type SessionFilters struct {
-	Status   string // фильтр по status
-	ClientID string // фильтр по client_id
-	PIN      string // фильтр по pin
-	Limit    int    // лимит результатов (по умолчанию 20)
-	Offset   int    // смещение для пагинации (по умолчанию 0)
+	Status          string          // фильтр по status
+	Statuses        []string        // фильтр по status: любой из
+	ExcludeStatuses []string        // исключить status
+	ClientID        string          // фильтр по client_id
+	ClientIDs       []string        // фильтр по client_id: любой из
+	PIN             string          // фильтр по pin
+	OperatorID      string          // фильтр по участнику: сессии, к которым подключался оператор
+	OperatorIDs     []string        // фильтр по участнику: любой из
+	Created         TimeRange       // фильтр по created_at
+	Updated         TimeRange       // фильтр по updated_at
+	Ended           TimeRange       // фильтр по ended_at
+	Query           *SessionFilters // условия строки поиска: пересекаются с остальными фильтрами (AND)
+	Limit           int             // лимит результатов (по умолчанию 20)
+	Offset          int             // смещение для пагинации (по умолчанию 0)
 }
 
 type OperatorFilters struct {
-	Region      string // фильтр по region
-	Role        string // фильтр по role
-	DisplayName string // фильтр по display_name (точное совпадение)
-	Limit       int    // лимит результатов (по умолчанию 20)
-	Offset      int    // смещение для пагинации (по умолчанию 0)
+	Region      string           // фильтр по region
+	Regions     []string         // фильтр по region: любой из
+	Role        string           // фильтр по role
+	Roles       []string         // фильтр по role: любой из
+	DisplayName string           // фильтр по display_name (точное совпадение)
+	Created     TimeRange        // фильтр по created_at
+	Updated     TimeRange        // фильтр по updated_at
+	Query       *OperatorFilters // условия строки поиска: пересекаются с остальными фильтрами (AND)
+	Limit       int              // лимит результатов (по умолчанию 20)
+	Offset      int              // смещение для пагинации (по умолчанию 0)
 }
 
 const (
@@ -67,67 +125,141 @@ const (
 	indexOperators = "operators"
 )
 
+// indices — индексы сервиса и их маппинги.
+var indices = []struct {
+	base    string
+	mapping func() *query.Mapping
+}{
+	{indexTickets, elasticsearch.TicketsMapping},
+	{indexSessions, elasticsearch.SessionsMapping},
+	{indexOperators, elasticsearch.OperatorsMapping},
+}
+
 type SearchService struct {
-	es elasticsearch.IndexSearcher
+	es      elasticsearch.IndexSearcher
+	policy  *policy.Policy
+	states  *sessionstate.Machine
+	tenancy string
+	ensured sync.Map // tenant → индексы созданы (режим tenant.ModeIndex)
+
+	cache    cache.Cache
+	cacheTTL time.Duration
+
+	slowQuery time.Duration // порог медленного запроса (0 — не логировать)
 }
 
-func NewSearchService(esURL string, skipTLSVerify bool, username, password string) (*SearchService, error) {
-	es := elasticsearch.NewClient(esURL, skipTLSVerify, username, password)
-	return NewSearchServiceWithIndexer(es)
+// Option настраивает SearchService.
+type Option func(*SearchService)
+
+// WithPolicy включает row-level политику: в запросы поиска добавляются обязательные фильтры по claims вызывающего.
+func WithPolicy(p *policy.Policy) Option {
+	return func(s *SearchService) { s.policy = p }
+}
+
+// NewSearchService создаёт SearchService поверх клиента Elasticsearch/OpenSearch (с метриками и трейсингом).
+func NewSearchService(es *elasticsearch.Client, opts ...Option) (*SearchService, error) {
+	return NewSearchServiceWithIndexer(elasticsearch.NewInstrumented(es), opts...)
 }
 
 // NewSearchServiceWithIndexer builds SearchService with a given IndexSearcher (e.g. for tests).
-func NewSearchServiceWithIndexer(es elasticsearch.IndexSearcher) (*SearchService, error) {
-	svc := &SearchService{es: es}
+func NewSearchServiceWithIndexer(es elasticsearch.IndexSearcher, opts ...Option) (*SearchService, error) {
+	svc := &SearchService{es: es, states: sessionstate.Default()}
+	for _, opt := range opts {
+		opt(svc)
+	}
 
-	// Ensure indices exist with mappings
-	ctx := context.Background()
-	if err := svc.ensureIndices(ctx); err != nil {
-		return nil, fmt.Errorf("ensure indices: %w", err)
+	// Ensure indices exist with mappings (per-tenant indices are created on the tenant's first request)
+	if svc.tenancy != tenant.ModeIndex {
+		ctx := context.Background()
+		if err := svc.ensureIndices(ctx, ""); err != nil {
+			return nil, fmt.Errorf("ensure indices: %w", err)
+		}
 	}
 
 	return svc, nil
 }
 
-func (s *SearchService) ensureIndices(ctx context.Context) error {
-	if err := s.es.EnsureIndex(ctx, indexTickets, elasticsearch.TicketsMapping()); err != nil {
-		return fmt.Errorf("ensure tickets index: %w", err)
-	}
-	if err := s.es.EnsureIndex(ctx, indexSessions, elasticsearch.SessionsMapping()); err != nil {
-		return fmt.Errorf("ensure sessions index: %w", err)
-	}
-	if err := s.es.EnsureIndex(ctx, indexOperators, elasticsearch.OperatorsMapping()); err != nil {
-		return fmt.Errorf("ensure operators index: %w", err)
+func (s *SearchService) ensureIndices(ctx context.Context, tenantID string) error {
+	for _, idx := range indices {
+		if err := s.es.EnsureIndex(ctx, s.indexName(idx.base, tenantID), idx.mapping()); err != nil {
+			return fmt.Errorf("ensure %s index: %w", idx.base, err)
+		}
 	}
-
 	return nil
 }
 
+// Индексация записывает все поля входных данных (пустое значение очищает поле, например снятый operator_id);
+// поля, которые ведут другие вызовы (comments, участники сессии), сохраняются.
+// Нулевой UpdatedAt означает время индексации. Ненулевой CreatedAt записывается как есть (время создания
+// из источника); нулевой заменяется на UpdatedAt только в новом документе — обновление created_at не трогает.
+
 type IndexTicketInput struct {
-	TicketID   int64  `json:"ticket_id"`
-	SessionID  string `json:"session_id"`
-	ClientID   string `json:"client_id"`
-	OperatorID string `json:"operator_id"`
-	Subject    string `json:"subject"`
-	Notes      string `json:"notes"`
-	Status     string `json:"status"`
+	TicketID   int64     `json:"ticket_id"`
+	SessionID  string    `json:"session_id"`
+	ClientID   string    `json:"client_id"`
+	OperatorID string    `json:"operator_id"`
+	Subject    string    `json:"subject"`
+	Notes      string    `json:"notes"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TicketCommentInput — комментарий тикета; повтор с тем же CommentID заменяет комментарий.
+type TicketCommentInput struct {
+	TicketID  int64     `json:"ticket_id"`
+	CommentID string    `json:"comment_id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"` // нулевой — время индексации
 }
 
 type IndexSessionInput struct {
-	SessionID string `json:"session_id"`
-	ClientID  string `json:"client_id"`
-	PIN       string `json:"pin"`
-	Status    string `json:"status"`
+	SessionID string    `json:"session_id"`
+	ClientID  string    `json:"client_id"`
+	PIN       string    `json:"pin"`
+	Status    string    `json:"status"` // пусто — статус по Event из машины состояний
+	Event     string    `json:"event"`  // тип события, вызвавшего индексацию (пусто — статус задан явно)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	EndedAt   time.Time `json:"ended_at"` // нулевой — сессия не завершена (поле не индексируется)
 }
 
 type IndexOperatorInput struct {
-	UserID      string `json:"user_id"`
-	DisplayName string `json:"display_name"`
-	Region      string `json:"region"`
-	Role        string `json:"role"`
+	UserID      string    `json:"user_id"`
+	DisplayName string    `json:"display_name"`
+	Region      string    `json:"region"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// withTimestamps добавляет в документ updated_at и заданный created_at (RFC 3339, UTC) и возвращает
+// параметры UpdateDocument: умолчание created_at (пишется, только если его в документе нет) и время
+// события для порядка записей — только заданный UpdatedAt, время индексации не упорядочивается.
+func withTimestamps(doc map[string]interface{}, created, updated time.Time) *elasticsearch.UpdateOptions {
+	opts := &elasticsearch.UpdateOptions{EventAt: updated}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	doc["updated_at"] = formatTime(updated)
+	if !created.IsZero() {
+		doc["created_at"] = formatTime(created)
+	} else {
+		opts.Defaults = map[string]interface{}{"created_at": formatTime(updated)}
+	}
+	return opts
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
 }
 
 func (s *SearchService) IndexTicket(ctx context.Context, in *IndexTicketInput) error {
+	index, tenantID, err := s.resolveIndex(ctx, indexTickets)
+	if err != nil {
+		return err
+	}
 	doc := map[string]interface{}{
 		"ticket_id":   in.TicketID,
 		"session_id":  in.SessionID,
@@ -137,41 +269,165 @@ func (s *SearchService) IndexTicket(ctx context.Context, in *IndexTicketInput) e
 		"notes":       in.Notes,
 		"status":      in.Status,
 	}
-	return s.es.IndexDocument(ctx, indexTickets, fmt.Sprintf("%d", in.TicketID), doc)
+	opts := withTimestamps(doc, in.CreatedAt, in.UpdatedAt)
+	if err := s.enrichTicket(ctx, doc, in, tenantID); err != nil {
+		return err
+	}
+	// частичное обновление: comments ведёт AddTicketComment, переиндексация тикета их не стирает
+	if err := s.es.UpdateDocument(ctx, index, s.docID(tenantID, fmt.Sprintf("%d", in.TicketID)), s.withTenantField(doc, tenantID), opts); err != nil {
+		return staleError(err, "ticket", fmt.Sprintf("%d", in.TicketID), in.UpdatedAt)
+	}
+	s.invalidate(ctx, index)
+	return nil
+}
+
+// AddTicketComment добавляет комментарий в nested-поле comments тикета. Комментарий может прийти раньше
+// самого тикета: тогда создаётся документ только с ticket_id, остальные поля допишет IndexTicket.
+func (s *SearchService) AddTicketComment(ctx context.Context, in *TicketCommentInput) error {
+	index, tenantID, err := s.resolveIndex(ctx, indexTickets)
+	if err != nil {
+		return err
+	}
+	created := in.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	comment := map[string]interface{}{
+		"comment_id": in.CommentID,
+		"author":     in.Author,
+		"text":       in.Text,
+		"created_at": formatTime(created),
+	}
+	doc := s.withTenantField(map[string]interface{}{"ticket_id": in.TicketID}, tenantID)
+	if err := s.es.UpsertNested(ctx, index, s.docID(tenantID, fmt.Sprintf("%d", in.TicketID)), fieldComments, "comment_id", comment, doc); err != nil {
+		return err
+	}
+	s.invalidate(ctx, index)
+	return nil
 }
 
 func (s *SearchService) IndexSession(ctx context.Context, in *IndexSessionInput) error {
+	index, tenantID, err := s.resolveIndex(ctx, indexSessions)
+	if err != nil {
+		return err
+	}
+	var current map[string]interface{}
+	var status string
+	var endedAt time.Time
+	// переход статуса вычисляется по прочитанному документу; запись применяется, только если статус
+	// не изменился с чтения (UpdateOptions.Expect), иначе документ перечитывается
+	for attempt := 1; ; attempt++ {
+		current, status, endedAt, err = s.updateSession(ctx, index, tenantID, in)
+		if !errors.Is(err, elasticsearch.ErrConflict) {
+			break
+		}
+		if attempt == sessionUpdateAttempts {
+			return fmt.Errorf("session %s: %w", in.SessionID, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, index)
+	if !endedAt.IsZero() {
+		if err := s.closeParticipants(ctx, index, tenantID, in.SessionID, endedAt); err != nil {
+			return err
+		}
+	}
+	if unchanged(current, in.UpdatedAt, map[string]interface{}{"status": status, "pin": in.PIN}) {
+		return nil
+	}
+	return s.propagate(ctx, tenantID, "session_id", in.SessionID, map[string]interface{}{
+		fieldSessionStatus: status,
+		fieldSessionPIN:    in.PIN,
+	})
+}
+
+// sessionUpdateAttempts — сколько раз статус или участники сессии перечитываются, если сессию изменила
+// параллельная запись между чтением и записью.
+const sessionUpdateAttempts = 3
+
+// updateSession читает сессию, вычисляет переход статуса и записывает его, если статус с чтения
+// не изменился (иначе elasticsearch.ErrConflict). Возвращает прочитанный документ, записанные статус
+// и время завершения.
+func (s *SearchService) updateSession(ctx context.Context, index, tenantID string, in *IndexSessionInput) (map[string]interface{}, string, time.Time, error) {
+	current, err := s.getDocument(ctx, index, tenantID, in.SessionID)
+	if err != nil {
+		return nil, "", time.Time{}, fmt.Errorf("get session %s: %w", in.SessionID, err)
+	}
+	if (&elasticsearch.UpdateOptions{EventAt: in.UpdatedAt}).Stale(current) {
+		// не вычислять переход статуса по устаревшему событию; запись всё равно проверит UpdateDocument
+		return nil, "", time.Time{}, staleError(elasticsearch.ErrStale, "session", in.SessionID, in.UpdatedAt)
+	}
+	status, endedAt, err := s.sessionStatus(ctx, current, in)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
 	doc := map[string]interface{}{
 		"session_id": in.SessionID,
 		"client_id":  in.ClientID,
 		"pin":        in.PIN,
-		"status":     in.Status,
+		"status":     status,
+	}
+	opts := withTimestamps(doc, in.CreatedAt, in.UpdatedAt)
+	opts.Expect = map[string]interface{}{"status": current["status"]}
+	if !endedAt.IsZero() {
+		doc["ended_at"] = formatTime(endedAt)
 	}
-	return s.es.IndexDocument(ctx, indexSessions, in.SessionID, doc)
+	// частичное обновление: участников ведёт TrackSessionParticipant
+	if err := s.es.UpdateDocument(ctx, index, s.docID(tenantID, in.SessionID), s.withTenantField(doc, tenantID), opts); err != nil {
+		return nil, "", time.Time{}, staleError(err, "session", in.SessionID, in.UpdatedAt)
+	}
+	return current, status, endedAt, nil
 }
 
 func (s *SearchService) IndexOperator(ctx context.Context, in *IndexOperatorInput) error {
+	index, tenantID, err := s.resolveIndex(ctx, indexOperators)
+	if err != nil {
+		return err
+	}
 	doc := map[string]interface{}{
 		"user_id":      in.UserID,
 		"display_name": in.DisplayName,
 		"region":       in.Region,
 		"role":         in.Role,
 	}
-	return s.es.IndexDocument(ctx, indexOperators, in.UserID, doc)
+	opts := withTimestamps(doc, in.CreatedAt, in.UpdatedAt)
+	current, err := s.getDocument(ctx, index, tenantID, in.UserID)
+	if err != nil {
+		return fmt.Errorf("get operator %s: %w", in.UserID, err)
+	}
+	if err := s.es.UpdateDocument(ctx, index, s.docID(tenantID, in.UserID), s.withTenantField(doc, tenantID), opts); err != nil {
+		return staleError(err, "operator", in.UserID, in.UpdatedAt)
+	}
+	s.invalidate(ctx, index)
+	if unchanged(current, in.UpdatedAt, map[string]interface{}{"display_name": in.DisplayName, "region": in.Region}) {
+		return nil
+	}
+	return s.propagate(ctx, tenantID, "operator_id", in.UserID, map[string]interface{}{
+		fieldOperatorName:   in.DisplayName,
+		fieldOperatorRegion: in.Region,
+	})
 }
 
 type TicketHit struct {
-	TicketID  int64  `json:"ticket_id"`
-	SessionID string `json:"session_id"`
-	Subject   string `json:"subject"`
-	Snippet   string `json:"snippet,omitempty"`
+	TicketID         int64  `json:"ticket_id"`
+	SessionID        string `json:"session_id"`
+	Subject          string `json:"subject"`
+	Snippet          string `json:"snippet,omitempty"`
+	MatchedCommentID string `json:"matched_comment_id,omitempty"` // комментарий, совпавший с TicketFilters.Text
+	OperatorName     string `json:"operator_display_name,omitempty"`
+	OperatorRegion   string `json:"operator_region,omitempty"`
+	SessionStatus    string `json:"session_status,omitempty"`
 }
 
 type SessionHit struct {
-	SessionID string `json:"session_id"`
-	PIN       string `json:"pin"`
-	Status    string `json:"status"`
-	Snippet   string `json:"snippet,omitempty"`
+	SessionID        string   `json:"session_id"`
+	PIN              string   `json:"pin"`
+	Status           string   `json:"status"`
+	Snippet          string   `json:"snippet,omitempty"`
+	OperatorIDs      []string `json:"operator_ids,omitempty"`
+	ParticipantCount int      `json:"participant_count,omitempty"`
 }
 
 type OperatorHit struct {
@@ -181,18 +437,20 @@ type OperatorHit struct {
 	Snippet     string `json:"snippet,omitempty"`
 }
 
-// searchIndex runs ES search and maps each hit source to T. Shared by searchTickets/Sessions/Operators.
-func searchIndex[T any](es elasticsearch.IndexSearcher, ctx context.Context, index string, query map[string]interface{}, limit, offset int, mapHit func(map[string]interface{}) T) ([]T, int64, bool, error) {
-	resp, err := es.Search(ctx, index, query, limit, offset)
+// searchIndex runs ES search and maps each hit to T. Shared by searchTickets/Sessions/Operators.
+func searchIndex[T any](s *SearchService, ctx context.Context, p *searchPlan, mapHit func(elasticsearch.SearchHit) T) ([]T, int64, bool, error) {
+	began := time.Now()
+	resp, err := s.es.Search(ctx, p.index, p.req)
 	if err != nil {
 		return nil, 0, false, err
 	}
+	s.logSlowQuery(ctx, p, time.Since(began), resp.Took)
 	hits := make([]T, 0, len(resp.Hits.Hits))
 	for _, h := range resp.Hits.Hits {
-		hits = append(hits, mapHit(h.Source))
+		hits = append(hits, mapHit(h))
 	}
 	total := resp.Hits.Total.Value
-	hasMore := int64(offset+len(hits)) < total
+	hasMore := int64(p.req.From+len(hits)) < total
 	return hits, total, hasMore, nil
 }
 
@@ -207,9 +465,54 @@ func sourceToTicketHit(src map[string]interface{}) TicketHit {
 	if v, ok := src["subject"].(string); ok {
 		h.Subject = v
 	}
+	if v, ok := src[fieldOperatorName].(string); ok {
+		h.OperatorName = v
+	}
+	if v, ok := src[fieldOperatorRegion].(string); ok {
+		h.OperatorRegion = v
+	}
+	if v, ok := src[fieldSessionStatus].(string); ok {
+		h.SessionStatus = v
+	}
 	return h
 }
 
+// fromSource адаптирует отображение _source к searchIndex.
+func fromSource[T any](f func(map[string]interface{}) T) func(elasticsearch.SearchHit) T {
+	return func(h elasticsearch.SearchHit) T { return f(h.Source) }
+}
+
+// hitToTicket дополняет тикет совпавшим комментарием (inner_hits поиска по Text): его id и фрагмент текста.
+func hitToTicket(h elasticsearch.SearchHit) TicketHit {
+	t := sourceToTicketHit(h.Source)
+	inner := h.InnerHits[fieldComments].Hits.Hits
+	if len(inner) == 0 {
+		return t
+	}
+	c := inner[0]
+	if v, ok := c.Source["comment_id"].(string); ok {
+		t.MatchedCommentID = v
+	}
+	if fragments := c.Highlight[fieldComments+".text"]; len(fragments) > 0 {
+		t.Snippet = fragments[0]
+	} else if v, ok := c.Source["text"].(string); ok {
+		t.Snippet = truncate(v, snippetLength)
+	}
+	return t
+}
+
+// snippetLength — длина фрагмента совпавшего текста, символов.
+const snippetLength = 150
+
+// truncate обрезает s до n символов, отмечая обрезку многоточием.
+func truncate(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return string(rs[:n]) + "…"
+}
+
 func sourceToSessionHit(src map[string]interface{}) SessionHit {
 	h := SessionHit{}
 	if v, ok := src["session_id"].(string); ok {
@@ -221,6 +524,16 @@ func sourceToSessionHit(src map[string]interface{}) SessionHit {
 	if v, ok := src["status"].(string); ok {
 		h.Status = v
 	}
+	if ids, ok := src["operator_ids"].([]interface{}); ok {
+		for _, id := range ids {
+			if v, ok := id.(string); ok {
+				h.OperatorIDs = append(h.OperatorIDs, v)
+			}
+		}
+	}
+	if v, ok := src["participant_count"].(float64); ok {
+		h.ParticipantCount = int(v)
+	}
 	return h
 }
 
@@ -238,24 +551,24 @@ func sourceToOperatorHit(src map[string]interface{}) OperatorHit {
 	return h
 }
 
-func (s *SearchService) searchTickets(ctx context.Context, query map[string]interface{}, limit int, offset int) (*TicketsSearchResult, error) {
-	hits, total, hasMore, err := searchIndex(s.es, ctx, indexTickets, query, limit, offset, sourceToTicketHit)
+func (s *SearchService) searchTickets(ctx context.Context, p *searchPlan) (*TicketsSearchResult, error) {
+	hits, total, hasMore, err := searchIndex(s, ctx, p, hitToTicket)
 	if err != nil {
 		return nil, err
 	}
 	return &TicketsSearchResult{Tickets: hits, Total: total, HasMore: hasMore}, nil
 }
 
-func (s *SearchService) searchSessions(ctx context.Context, query map[string]interface{}, limit int, offset int) (*SessionsSearchResult, error) {
-	hits, total, hasMore, err := searchIndex(s.es, ctx, indexSessions, query, limit, offset, sourceToSessionHit)
+func (s *SearchService) searchSessions(ctx context.Context, p *searchPlan) (*SessionsSearchResult, error) {
+	hits, total, hasMore, err := searchIndex(s, ctx, p, fromSource(sourceToSessionHit))
 	if err != nil {
 		return nil, err
 	}
 	return &SessionsSearchResult{Sessions: hits, Total: total, HasMore: hasMore}, nil
 }
 
-func (s *SearchService) searchOperators(ctx context.Context, query map[string]interface{}, limit int, offset int) (*OperatorsSearchResult, error) {
-	hits, total, hasMore, err := searchIndex(s.es, ctx, indexOperators, query, limit, offset, sourceToOperatorHit)
+func (s *SearchService) searchOperators(ctx context.Context, p *searchPlan) (*OperatorsSearchResult, error) {
+	hits, total, hasMore, err := searchIndex(s, ctx, p, fromSource(sourceToOperatorHit))
 	if err != nil {
 		return nil, err
 	}
@@ -263,84 +576,268 @@ func (s *SearchService) searchOperators(ctx context.Context, query map[string]in
 }
 
 func (s *SearchService) SearchTickets(ctx context.Context, filters *TicketFilters) (*TicketsSearchResult, error) {
+	p, err := s.planTickets(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return cachedSearch(s, ctx, policy.EntityTickets, p, s.searchTickets)
+}
+
+func (s *SearchService) planTickets(ctx context.Context, filters *TicketFilters) (*searchPlan, error) {
 	if filters == nil {
 		filters = &TicketFilters{}
 	}
-	lim := limit.ClampLimit(filters.Limit, 20, 100)
-	offset := filters.Offset
-	if offset < 0 {
-		offset = 0
-	}
-	query := s.buildTicketQuery(filters)
-	return s.searchTickets(ctx, query, lim, offset)
+	return s.plan(ctx, indexTickets, policy.EntityTickets, filters.Limit, filters.Offset, func(scope []query.Query) query.Query {
+		return s.buildTicketQuery(filters, scope)
+	})
 }
 
 func (s *SearchService) SearchSessions(ctx context.Context, filters *SessionFilters) (*SessionsSearchResult, error) {
+	p, err := s.planSessions(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return cachedSearch(s, ctx, policy.EntitySessions, p, s.searchSessions)
+}
+
+func (s *SearchService) planSessions(ctx context.Context, filters *SessionFilters) (*searchPlan, error) {
 	if filters == nil {
 		filters = &SessionFilters{}
 	}
-	lim := limit.ClampLimit(filters.Limit, 20, 100)
-	offset := filters.Offset
-	if offset < 0 {
-		offset = 0
-	}
-	query := s.buildSessionQuery(filters)
-	return s.searchSessions(ctx, query, lim, offset)
+	return s.plan(ctx, indexSessions, policy.EntitySessions, filters.Limit, filters.Offset, func(scope []query.Query) query.Query {
+		return s.buildSessionQuery(filters, scope)
+	})
 }
 
 func (s *SearchService) SearchOperators(ctx context.Context, filters *OperatorFilters) (*OperatorsSearchResult, error) {
+	p, err := s.planOperators(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return cachedSearch(s, ctx, policy.EntityOperators, p, s.searchOperators)
+}
+
+func (s *SearchService) planOperators(ctx context.Context, filters *OperatorFilters) (*searchPlan, error) {
 	if filters == nil {
 		filters = &OperatorFilters{}
 	}
-	lim := limit.ClampLimit(filters.Limit, 20, 100)
-	offset := filters.Offset
+	return s.plan(ctx, indexOperators, policy.EntityOperators, filters.Limit, filters.Offset, func(scope []query.Query) query.Query {
+		return s.buildOperatorQuery(filters, scope)
+	})
+}
+
+// searchPlan — индекс и тело запроса поиска (общая часть Search* и Explain*).
+type searchPlan struct {
+	index string
+	scope []query.Query // обязательные фильтры вызывающего (арендатор, политика), уже включённые в req
+	req   *query.Search
+}
+
+// plan нормализует пагинацию, выбирает индекс арендатора и строит запрос build с обязательными фильтрами.
+func (s *SearchService) plan(ctx context.Context, base, entity string, lim, offset int, build func(scope []query.Query) query.Query) (*searchPlan, error) {
+	lim = limit.ClampLimit(lim, 20, 100)
 	if offset < 0 {
 		offset = 0
 	}
-	query := s.buildOperatorQuery(filters)
-	return s.searchOperators(ctx, query, lim, offset)
-}
-
-// buildBoolTermQuery builds an ES bool query from field->value map; empty values are skipped.
-func buildBoolTermQuery(fields map[string]string) map[string]interface{} {
-	var filter []map[string]interface{}
-	for field, value := range fields {
-		if value == "" {
+	index, tenantID, err := s.resolveIndex(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.mandatoryFilters(ctx, entity, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &searchPlan{index: index, scope: scope, req: &query.Search{Query: build(scope), From: offset, Size: lim}}, nil
+}
+
+// filterSpec — условия поиска по полям индекса (без учёта обязательных фильтров).
+type filterSpec struct {
+	include map[string][]string  // поле → допустимые значения (OR); пустой список не ограничивает
+	exclude map[string][]string  // поле → исключаемые значения
+	ranges  map[string]TimeRange // поле даты → диапазон (границы — см. TimeRange); пустой диапазон не ограничивает
+	match   map[string]string    // текстовое поле → слова, которые должны встретиться все (влияет на score)
+	must    []query.Query        // готовые условия, влияющие на score (например, поиск по вложенным документам)
+}
+
+// buildBoolTermQuery builds a bool query from spec: a term (single value) or terms (several values) filter
+// per included field, a must_not clause per excluded field, a range filter per date field and
+// a scoring match (all words) per full-text field, followed by the prebuilt scoring clauses of spec.must.
+// Fields are resolved to their exact-match variant via the index mapping, so a term on a text field
+// (display_name) targets its keyword subfield instead of analyzed tokens.
+// scope holds mandatory clauses (tenant, access policy); they are added to the filter regardless of request parameters.
+func buildBoolTermQuery(mapping *query.Mapping, spec filterSpec, scope []query.Query) query.Query {
+	filter := append([]query.Query{}, scope...)
+	var must, mustNot []query.Query
+	// стабильный порядок условий: запрос — ещё и ключ кэша
+	for _, field := range sortedKeys(spec.include) {
+		if q := termsClause(mapping.ExactField(field), spec.include[field]); q != nil {
+			filter = append(filter, q)
+		}
+	}
+	for _, field := range sortedKeys(spec.exclude) {
+		if q := termsClause(mapping.ExactField(field), spec.exclude[field]); q != nil {
+			mustNot = append(mustNot, q)
+		}
+	}
+	for _, field := range sortedKeys(spec.ranges) {
+		r := spec.ranges[field]
+		if r.IsZero() {
 			continue
 		}
-		filter = append(filter, map[string]interface{}{
-			"term": map[string]interface{}{field: value},
-		})
+		q := query.Range(field)
+		switch {
+		case r.From.IsZero():
+		case r.FromExclusive:
+			q.GT = formatTime(r.From)
+		default:
+			q.GTE = formatTime(r.From)
+		}
+		switch {
+		case r.To.IsZero():
+		case r.ToInclusive:
+			q.LTE = formatTime(r.To)
+		default:
+			q.LT = formatTime(r.To)
+		}
+		filter = append(filter, q)
 	}
-	if len(filter) == 0 {
-		return map[string]interface{}{"match_all": map[string]interface{}{}}
+	for _, field := range sortedKeys(spec.match) {
+		if text := spec.match[field]; text != "" {
+			must = append(must, &query.MatchQuery{Field: field, Text: text, Operator: query.OperatorAnd})
+		}
 	}
-	return map[string]interface{}{
-		"bool": map[string]interface{}{"filter": filter},
+	must = append(must, spec.must...)
+	if len(must) == 0 && len(filter) == 0 && len(mustNot) == 0 {
+		return query.MatchAll()
 	}
+	return &query.BoolQuery{Must: must, Filter: filter, MustNot: mustNot}
 }
 
-func (s *SearchService) buildTicketQuery(filters *TicketFilters) map[string]interface{} {
-	return buildBoolTermQuery(map[string]string{
-		"status":       filters.Status,
-		"session_id":   filters.SessionID,
-		"client_id":    filters.ClientID,
-		"operator_id":  filters.OperatorID,
-	})
+// termsClause — term для одного значения, terms для нескольких, nil для пустого списка.
+func termsClause(field string, values []string) query.Query {
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return query.Term(field, values[0])
+	}
+	vals := make([]interface{}, len(values))
+	for i, v := range values {
+		vals[i] = v
+	}
+	return query.Terms(field, vals...)
 }
 
-func (s *SearchService) buildSessionQuery(filters *SessionFilters) map[string]interface{} {
-	return buildBoolTermQuery(map[string]string{
-		"status":     filters.Status,
-		"client_id":  filters.ClientID,
-		"pin":        filters.PIN,
-	})
+// anyOf объединяет значение-алиас single со списком multi: пустые и повторяющиеся значения отбрасываются.
+func anyOf(single string, multi []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(multi)+1)
+	for _, v := range append([]string{single}, multi...) {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
 }
 
-func (s *SearchService) buildOperatorQuery(filters *OperatorFilters) map[string]interface{} {
-	return buildBoolTermQuery(map[string]string{
-		"region":        filters.Region,
-		"role":          filters.Role,
-		"display_name":  filters.DisplayName,
-	})
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *SearchService) buildTicketQuery(filters *TicketFilters, scope []query.Query) query.Query {
+	if filters.Query != nil {
+		scope = append(slices.Clip(scope), s.buildTicketQuery(filters.Query, nil))
+	}
+	return buildBoolTermQuery(elasticsearch.TicketsMapping(), filterSpec{
+		include: map[string][]string{
+			"status":            anyOf(filters.Status, filters.Statuses),
+			"session_id":        anyOf(filters.SessionID, nil),
+			"client_id":         anyOf(filters.ClientID, filters.ClientIDs),
+			"operator_id":       anyOf(filters.OperatorID, filters.OperatorIDs),
+			fieldOperatorRegion: anyOf(filters.OperatorRegion, filters.OperatorRegions),
+		},
+		exclude: map[string][]string{
+			"status": anyOf("", filters.ExcludeStatuses),
+		},
+		ranges: map[string]TimeRange{
+			"created_at": filters.Created,
+			"updated_at": filters.Updated,
+		},
+		match: map[string]string{
+			"subject": filters.Subject,
+		},
+		must: textClauses(filters.Text),
+	}, scope)
+}
+
+// fieldComments — nested-поле комментариев тикета.
+const fieldComments = "comments"
+
+// textClauses ищет все слова text в subject/notes или в одном комментарии; inner_hits возвращает
+// лучший совпавший комментарий с подсветкой — из него строится сниппет хита.
+func textClauses(text string) []query.Query {
+	if text == "" {
+		return nil
+	}
+	return []query.Query{&query.BoolQuery{
+		Should: []query.Query{
+			&query.MultiMatchQuery{Text: text, Fields: []string{"subject^2", "notes"}, Operator: query.OperatorAnd},
+			&query.NestedQuery{
+				Path:      fieldComments,
+				Query:     &query.MatchQuery{Field: fieldComments + ".text", Text: text, Operator: query.OperatorAnd},
+				ScoreMode: "max",
+				InnerHits: &query.InnerHits{
+					Size:      1,
+					Highlight: &query.Highlight{Fields: []string{fieldComments + ".text"}, FragmentSize: snippetLength, NumberOfFragments: 1},
+				},
+			},
+		},
+		MinimumShouldMatch: 1,
+	}}
+}
+
+func (s *SearchService) buildSessionQuery(filters *SessionFilters, scope []query.Query) query.Query {
+	if filters.Query != nil {
+		scope = append(slices.Clip(scope), s.buildSessionQuery(filters.Query, nil))
+	}
+	return buildBoolTermQuery(elasticsearch.SessionsMapping(), filterSpec{
+		include: map[string][]string{
+			"status":       anyOf(filters.Status, filters.Statuses),
+			"client_id":    anyOf(filters.ClientID, filters.ClientIDs),
+			"pin":          anyOf(filters.PIN, nil),
+			"operator_ids": anyOf(filters.OperatorID, filters.OperatorIDs),
+		},
+		exclude: map[string][]string{
+			"status": anyOf("", filters.ExcludeStatuses),
+		},
+		ranges: map[string]TimeRange{
+			"created_at": filters.Created,
+			"updated_at": filters.Updated,
+			"ended_at":   filters.Ended,
+		},
+	}, scope)
+}
+
+func (s *SearchService) buildOperatorQuery(filters *OperatorFilters, scope []query.Query) query.Query {
+	if filters.Query != nil {
+		scope = append(slices.Clip(scope), s.buildOperatorQuery(filters.Query, nil))
+	}
+	return buildBoolTermQuery(elasticsearch.OperatorsMapping(), filterSpec{
+		include: map[string][]string{
+			"region":       anyOf(filters.Region, filters.Regions),
+			"role":         anyOf(filters.Role, filters.Roles),
+			"display_name": anyOf(filters.DisplayName, nil),
+		},
+		ranges: map[string]TimeRange{
+			"created_at": filters.Created,
+			"updated_at": filters.Updated,
+		},
+	}, scope)
 }
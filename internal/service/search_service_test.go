@@ -0,0 +1,556 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/elasticsearch"
+	"github.com/psds-microservice/search-service/internal/policy"
+	"github.com/psds-microservice/search-service/internal/tenant"
+)
+
+func newTestService(t *testing.T, opts ...Option) *SearchService {
+	t.Helper()
+	svc, err := NewSearchServiceWithIndexer(elasticsearch.NewMemory(), opts...)
+	if err != nil {
+		t.Fatalf("NewSearchServiceWithIndexer: %v", err)
+	}
+	return svc
+}
+
+func TestSearchTicketsFiltersAndPagination(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	for i, status := range []string{"open", "closed", "open", "open"} {
+		in := &IndexTicketInput{TicketID: int64(i + 1), SessionID: "s-1", ClientID: "c-1", Subject: "ticket", Status: status}
+		if err := svc.IndexTicket(ctx, in); err != nil {
+			t.Fatalf("IndexTicket: %v", err)
+		}
+	}
+
+	res, err := svc.SearchTickets(ctx, &TicketFilters{Status: "open", Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 3 || len(res.Tickets) != 2 || !res.HasMore {
+		t.Fatalf("page 1: total=%d hits=%d hasMore=%v, want 3/2/true", res.Total, len(res.Tickets), res.HasMore)
+	}
+	res, err = svc.SearchTickets(ctx, &TicketFilters{Status: "open", Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if len(res.Tickets) != 1 || res.HasMore || res.Tickets[0].TicketID != 4 {
+		t.Fatalf("page 2: %+v hasMore=%v, want ticket 4 only", res.Tickets, res.HasMore)
+	}
+}
+
+func TestSearchTicketsBySubjectMatchesAllWords(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	for i, subject := range []string{"Card blocked after payment", "Card delivery", "Account blocked"} {
+		in := &IndexTicketInput{TicketID: int64(i + 1), SessionID: "s-1", Subject: subject, Status: "open"}
+		if err := svc.IndexTicket(ctx, in); err != nil {
+			t.Fatalf("IndexTicket: %v", err)
+		}
+	}
+
+	res, err := svc.SearchTickets(ctx, &TicketFilters{Subject: "card BLOCKED", ExcludeStatuses: []string{"closed"}})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 1 || res.Tickets[0].TicketID != 1 {
+		t.Fatalf("got %+v, want only ticket 1", res.Tickets)
+	}
+}
+
+func TestTicketEnrichmentFollowsOperatorAndSession(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// тикет 1 проиндексирован раньше оператора и сессии, тикет 2 — после
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, SessionID: "s-1", OperatorID: "op-1", Status: "open"}))
+	must(svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", DisplayName: "Anna", Region: "north-west"}))
+	must(svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", PIN: "4821", Status: "active"}))
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 2, SessionID: "s-1", OperatorID: "op-1", Status: "open"}))
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 3, SessionID: "s-2", OperatorID: "op-2", Status: "open"}))
+
+	res, err := svc.SearchTickets(ctx, &TicketFilters{OperatorRegion: "north-west"})
+	must(err)
+	if res.Total != 2 {
+		t.Fatalf("north-west tickets = %+v, want 1 and 2", res.Tickets)
+	}
+	for _, h := range res.Tickets {
+		if h.OperatorName != "Anna" || h.OperatorRegion != "north-west" || h.SessionStatus != "active" {
+			t.Fatalf("hit = %+v, want operator and session copied", h)
+		}
+	}
+
+	// изменения оператора и сессии доходят до уже проиндексированных тикетов
+	must(svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", DisplayName: "Anna", Region: "south"}))
+	must(svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", PIN: "4821", Status: "finished"}))
+	res, err = svc.SearchTickets(ctx, &TicketFilters{OperatorRegions: []string{"south", "east"}})
+	must(err)
+	if res.Total != 2 || res.Tickets[0].SessionStatus != "finished" || res.Tickets[1].SessionStatus != "finished" {
+		t.Fatalf("south tickets = %+v, want 1 and 2 with finished session", res.Tickets)
+	}
+
+	// переназначение на ещё не проиндексированных оператора и сессию очищает копии прежних
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 2, SessionID: "s-9", OperatorID: "op-9", Status: "open"}))
+	res, err = svc.SearchTickets(ctx, &TicketFilters{OperatorID: "op-9"})
+	must(err)
+	if res.Total != 1 || res.Tickets[0].OperatorName != "" || res.Tickets[0].OperatorRegion != "" || res.Tickets[0].SessionStatus != "" {
+		t.Fatalf("reassigned ticket = %+v, want no copies of op-1 and s-1", res.Tickets)
+	}
+	doc, err := svc.es.GetDocument(ctx, indexTickets, "2")
+	must(err)
+	if doc[fieldSessionPIN] != nil {
+		t.Errorf("session_pin = %v after reassignment, want cleared", doc[fieldSessionPIN])
+	}
+}
+
+func TestPropagationSkipsUnchangedEvents(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	operator := &IndexOperatorInput{UserID: "op-1", DisplayName: "Anna", Region: "south", UpdatedAt: at}
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, OperatorID: "op-1", Status: "open"}))
+	must(svc.IndexOperator(ctx, operator))
+	region := func() interface{} {
+		t.Helper()
+		doc, err := svc.es.GetDocument(ctx, indexTickets, "1")
+		must(err)
+		return doc[fieldOperatorRegion]
+	}
+	// копия, которую не дописала прерванная попытка propagate
+	stale := map[string]interface{}{fieldOperatorRegion: "north"}
+	must(svc.es.UpdateDocument(ctx, indexTickets, "1", stale, nil))
+
+	// повтор того же события распространяется снова
+	must(svc.IndexOperator(ctx, operator))
+	if got := region(); got != "south" {
+		t.Fatalf("operator_region after redelivery = %v, want south", got)
+	}
+
+	// новое событие без изменений имени и региона тикеты не трогает
+	must(svc.es.UpdateDocument(ctx, indexTickets, "1", stale, nil))
+	must(svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", DisplayName: "Anna", Region: "south", Role: "lead", UpdatedAt: at.Add(time.Minute)}))
+	if got := region(); got != "north" {
+		t.Fatalf("operator_region = %v, want tickets untouched by an unchanged operator", got)
+	}
+}
+
+func TestExamplePolicyScopesOperatorsByOperatorRegion(t *testing.T) {
+	p, err := policy.Load(filepath.Join("..", "..", "deployments", "policy.example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestService(t, WithPolicy(p))
+	ctx := context.Background()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-2", DisplayName: "Boris", Region: "eu"}))
+	must(svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-3", DisplayName: "Carl", Region: "us"}))
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, SessionID: "s-1", OperatorID: "op-1", Status: "open"}))
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 2, SessionID: "s-2", OperatorID: "op-2", Status: "open"}))
+	must(svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 3, SessionID: "s-3", OperatorID: "op-3", Status: "open"}))
+
+	// op-1 из региона eu видит свой тикет и тикет оператора из eu
+	caller := auth.WithClaims(ctx, &auth.Claims{Subject: "op-1", Roles: []string{"operator"}, Raw: jwt.MapClaims{"sub": "op-1", "region": "eu"}})
+	res, err := svc.SearchTickets(caller, &TicketFilters{})
+	must(err)
+	var got []int64
+	for _, h := range res.Tickets {
+		got = append(got, h.TicketID)
+	}
+	slices.Sort(got)
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("tickets visible to op-1 = %v, want [1 2]", got)
+	}
+}
+
+func TestSearchSessionsMultiValueStatus(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	for i, status := range []string{"waiting", "active", "finished", "active"} {
+		in := &IndexSessionInput{SessionID: fmt.Sprintf("s-%d", i+1), ClientID: "c-1", Status: status}
+		if err := svc.IndexSession(ctx, in); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+	}
+
+	res, err := svc.SearchSessions(ctx, &SessionFilters{Statuses: []string{"waiting", "active"}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 3 {
+		t.Fatalf("waiting or active: total=%d, want 3", res.Total)
+	}
+	res, err = svc.SearchSessions(ctx, &SessionFilters{ExcludeStatuses: []string{"active"}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 2 || res.Sessions[0].SessionID != "s-1" || res.Sessions[1].SessionID != "s-3" {
+		t.Fatalf("not active = %+v, want s-1 and s-3", res.Sessions)
+	}
+}
+
+func TestSearchOperatorsByDisplayNameIsExact(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	for _, op := range []*IndexOperatorInput{
+		{UserID: "u-1", DisplayName: "Anna Petrova", Region: "eu"},
+		{UserID: "u-2", DisplayName: "Anna", Region: "eu"},
+	} {
+		if err := svc.IndexOperator(ctx, op); err != nil {
+			t.Fatalf("IndexOperator: %v", err)
+		}
+	}
+
+	res, err := svc.SearchOperators(ctx, &OperatorFilters{DisplayName: "Anna Petrova"})
+	if err != nil {
+		t.Fatalf("SearchOperators: %v", err)
+	}
+	if res.Total != 1 || res.Operators[0].UserID != "u-1" {
+		t.Fatalf("got %+v, want only u-1", res.Operators)
+	}
+}
+
+func TestSearchSessionsByDateRange(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	for _, in := range []*IndexSessionInput{
+		{SessionID: "s-1", Status: "finished", CreatedAt: day.Add(-48 * time.Hour), EndedAt: day.Add(-time.Minute)},
+		{SessionID: "s-2", Status: "finished", CreatedAt: day.Add(-time.Hour), EndedAt: day.Add(9 * time.Hour)},
+		{SessionID: "s-3", Status: "active", CreatedAt: day.Add(8 * time.Hour)},
+	} {
+		if err := svc.IndexSession(ctx, in); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+	}
+
+	ended, err := svc.SearchSessions(ctx, &SessionFilters{Ended: TimeRange{From: day, To: day.Add(24 * time.Hour)}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if ended.Total != 1 || ended.Sessions[0].SessionID != "s-2" {
+		t.Fatalf("ended today = %+v, want only s-2", ended.Sessions)
+	}
+	created, err := svc.SearchSessions(ctx, &SessionFilters{Created: TimeRange{To: day}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if created.Total != 2 {
+		t.Fatalf("created before the day: total=%d, want 2", created.Total)
+	}
+	end := day.Add(9 * time.Hour)
+	at, err := svc.SearchSessions(ctx, &SessionFilters{Ended: TimeRange{From: end, To: end, ToInclusive: true}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if at.Total != 1 || at.Sessions[0].SessionID != "s-2" {
+		t.Fatalf("ended exactly at %s = %+v, want only s-2", end, at.Sessions)
+	}
+	after, err := svc.SearchSessions(ctx, &SessionFilters{Ended: TimeRange{From: end, FromExclusive: true}})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if after.Total != 0 {
+		t.Fatalf("ended after %s: total=%d, want 0", end, after.Total)
+	}
+}
+
+func TestUpdatesKeepCreatedAtAndReplaceFields(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	created := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	later := created.Add(2 * time.Hour)
+
+	if err := svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", ClientID: "c-1", PIN: "4821", Status: "waiting", UpdatedAt: created}); err != nil {
+		t.Fatalf("IndexSession: %v", err)
+	}
+	if err := svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", ClientID: "c-1", Status: "active", UpdatedAt: later}); err != nil {
+		t.Fatalf("IndexSession: %v", err)
+	}
+	if err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", DisplayName: "Anna Petrova", Region: "north", UpdatedAt: created}); err != nil {
+		t.Fatalf("IndexOperator: %v", err)
+	}
+	if err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", Region: "eu", UpdatedAt: later}); err != nil {
+		t.Fatalf("IndexOperator: %v", err)
+	}
+
+	for _, tc := range []struct {
+		index, id string
+		want      map[string]interface{}
+	}{
+		{indexSessions, "s-1", map[string]interface{}{"status": "active", "pin": ""}},
+		{indexOperators, "op-1", map[string]interface{}{"display_name": "", "region": "eu"}},
+	} {
+		doc, err := svc.es.GetDocument(ctx, tc.index, tc.id)
+		if err != nil {
+			t.Fatalf("GetDocument %s: %v", tc.id, err)
+		}
+		if doc["created_at"] != formatTime(created) || doc["updated_at"] != formatTime(later) {
+			t.Errorf("%s created_at=%v updated_at=%v, want created_at from the first write and updated_at from the last", tc.id, doc["created_at"], doc["updated_at"])
+		}
+		for field, want := range tc.want {
+			if doc[field] != want {
+				t.Errorf("%s %s = %v, want %v", tc.id, field, doc[field], want)
+			}
+		}
+	}
+}
+
+func TestIndexTicketWithEmptyOperatorClearsIt(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	assigned := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, OperatorID: "op-1", Notes: "call back", Status: "open", UpdatedAt: assigned}); err != nil {
+		t.Fatalf("IndexTicket: %v", err)
+	}
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 1, Status: "open", UpdatedAt: assigned.Add(time.Hour)}); err != nil {
+		t.Fatalf("IndexTicket: %v", err)
+	}
+
+	doc, err := svc.es.GetDocument(ctx, indexTickets, "1")
+	if err != nil {
+		t.Fatalf("GetDocument: %v", err)
+	}
+	if doc["operator_id"] != "" || doc["notes"] != "" {
+		t.Fatalf("operator_id=%v notes=%v, want both cleared", doc["operator_id"], doc["notes"])
+	}
+	res, err := svc.SearchTickets(ctx, &TicketFilters{OperatorID: "op-1"})
+	if err != nil {
+		t.Fatalf("SearchTickets: %v", err)
+	}
+	if res.Total != 0 {
+		t.Fatalf("tickets of op-1 = %d, want 0 after unassigning", res.Total)
+	}
+}
+
+func TestStaleUpdatesAreRejected(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	newer := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	older := newer.Add(-time.Hour)
+
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 7, SessionID: "s-1", Status: "closed", UpdatedAt: newer}); err != nil {
+		t.Fatalf("IndexTicket: %v", err)
+	}
+	err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 7, SessionID: "s-1", Status: "open", UpdatedAt: older})
+	if !helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+		t.Fatalf("IndexTicket(older) error = %v, want FAILED_PRECONDITION", err)
+	}
+	if err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", Region: "eu", UpdatedAt: newer}); err != nil {
+		t.Fatalf("IndexOperator: %v", err)
+	}
+	if err := svc.IndexOperator(ctx, &IndexOperatorInput{UserID: "op-1", Region: "south", UpdatedAt: older}); !helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+		t.Fatalf("IndexOperator(older) error = %v, want FAILED_PRECONDITION", err)
+	}
+	if err := svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", ClientID: "c-1", Status: "active", UpdatedAt: newer}); err != nil {
+		t.Fatalf("IndexSession: %v", err)
+	}
+	if err := svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", ClientID: "c-1", Status: "waiting", UpdatedAt: older}); !helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+		t.Fatalf("IndexSession(older) error = %v, want FAILED_PRECONDITION", err)
+	}
+
+	for index, want := range map[string]map[string]interface{}{
+		indexTickets:   {"_id": "7", "status": "closed"},
+		indexOperators: {"_id": "op-1", "region": "eu"},
+		indexSessions:  {"_id": "s-1", "status": "active"},
+	} {
+		doc, err := svc.es.GetDocument(ctx, index, want["_id"].(string))
+		if err != nil {
+			t.Fatalf("GetDocument: %v", err)
+		}
+		for field, value := range want {
+			if field != "_id" && doc[field] != value {
+				t.Errorf("%s %s = %v, want %v from the newer write", index, field, doc[field], value)
+			}
+		}
+	}
+
+	// нулевой UpdatedAt — время индексации: порядок не проверяется, и время сервера не становится
+	// временем события — следующее событие сравнивается с последним событием, а не с моментом записи
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 7, SessionID: "s-1", Status: "open"}); err != nil {
+		t.Errorf("IndexTicket without UpdatedAt: %v", err)
+	}
+	if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 7, SessionID: "s-1", Status: "closed", UpdatedAt: newer.Add(time.Minute)}); err != nil {
+		t.Errorf("IndexTicket after a write without UpdatedAt: %v", err)
+	}
+}
+
+// racingSessions перед первой записью сессии, проверяющей поле field (UpdateOptions.Expect), применяет
+// параллельную запись concurrent — как если бы она пришла между чтением и записью.
+type racingSessions struct {
+	*elasticsearch.Memory
+	field      string
+	concurrent map[string]interface{}
+	raced      bool
+}
+
+func (r *racingSessions) UpdateDocument(ctx context.Context, index, id string, doc interface{}, opts *elasticsearch.UpdateOptions) error {
+	if _, ok := opts.Expect[r.field]; ok && index == indexSessions && !r.raced {
+		r.raced = true
+		if err := r.Memory.UpdateDocument(ctx, index, id, r.concurrent, nil); err != nil {
+			return err
+		}
+	}
+	return r.Memory.UpdateDocument(ctx, index, id, doc, opts)
+}
+
+func TestSessionTransitionRereadsConcurrentChange(t *testing.T) {
+	ctx := context.Background()
+	es := &racingSessions{Memory: elasticsearch.NewMemory(), field: "status", concurrent: map[string]interface{}{"status": "finished"}}
+	if err := es.Memory.UpdateDocument(ctx, indexSessions, "s-1", map[string]interface{}{"session_id": "s-1", "status": "active"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := NewSearchServiceWithIndexer(es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// active → waiting разрешён, но сессия уже завершена: переход вычисляется заново и отклоняется
+	err = svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", Status: "waiting"})
+	if !helpyerrors.IsCode(err, helpyerrors.CodeFailedPrecondition) {
+		t.Fatalf("IndexSession error = %v, want FAILED_PRECONDITION", err)
+	}
+	doc, err := es.GetDocument(ctx, indexSessions, "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["status"] != "finished" {
+		t.Fatalf("status = %v, want finished kept", doc["status"])
+	}
+}
+
+func TestConcurrentParticipantsAreKept(t *testing.T) {
+	ctx := context.Background()
+	joined := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	// op-2 подключился, пока TrackSessionParticipant для op-1 читал сессию
+	es := &racingSessions{Memory: elasticsearch.NewMemory(), field: "participants", concurrent: participantFields([]participation{
+		{OperatorID: "op-2", JoinedAt: formatTime(joined)},
+	})}
+	svc, err := NewSearchServiceWithIndexer(es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.TrackSessionParticipant(ctx, &SessionParticipantInput{SessionID: "s-1", OperatorID: "op-1", At: joined}); err != nil {
+		t.Fatalf("TrackSessionParticipant: %v", err)
+	}
+	doc, err := es.GetDocument(ctx, indexSessions, "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := fmt.Sprint(doc["operator_ids"]); ids != "[op-2 op-1]" || doc["active_participant_count"] != float64(2) {
+		t.Fatalf("operator_ids = %s, active = %v; want both operators kept", ids, doc["active_participant_count"])
+	}
+}
+
+func TestTenancyFilterIsolatesTenants(t *testing.T) {
+	svc := newTestService(t, WithTenancy(tenant.ModeFilter))
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+	if err := svc.IndexSession(acme, &IndexSessionInput{SessionID: "s-1", Status: "active"}); err != nil {
+		t.Fatalf("IndexSession: %v", err)
+	}
+	if err := svc.IndexSession(globex, &IndexSessionInput{SessionID: "s-2", Status: "active"}); err != nil {
+		t.Fatalf("IndexSession: %v", err)
+	}
+
+	res, err := svc.SearchSessions(acme, &SessionFilters{Status: "active"})
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if res.Total != 1 || res.Sessions[0].SessionID != "s-1" {
+		t.Fatalf("acme sees %+v, want only s-1", res.Sessions)
+	}
+	if _, err := svc.SearchSessions(context.Background(), &SessionFilters{}); err == nil {
+		t.Fatal("search without tenant: want error")
+	}
+}
+
+func TestTenancyFilterSameIDs(t *testing.T) {
+	svc := newTestService(t, WithTenancy(tenant.ModeFilter))
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+	for _, ctx := range []context.Context{acme, globex} {
+		name := tenant.FromContext(ctx)
+		if err := svc.IndexTicket(ctx, &IndexTicketInput{TicketID: 42, SessionID: "s-1", Subject: name, Status: "open"}); err != nil {
+			t.Fatalf("IndexTicket(%s): %v", name, err)
+		}
+		if err := svc.IndexSession(ctx, &IndexSessionInput{SessionID: "s-1", PIN: name, Event: "session.created"}); err != nil {
+			t.Fatalf("IndexSession(%s): %v", name, err)
+		}
+	}
+	// только acme завершает сессию: у globex s-1 остаётся ожидающей
+	if err := svc.IndexSession(acme, &IndexSessionInput{SessionID: "s-1", PIN: "acme", Event: "session.ended"}); err != nil {
+		t.Fatalf("IndexSession(acme, ended): %v", err)
+	}
+	if err := svc.TrackSessionParticipant(globex, &SessionParticipantInput{SessionID: "s-1", OperatorID: "op-1"}); err != nil {
+		t.Fatalf("TrackSessionParticipant(globex): %v", err)
+	}
+
+	for _, tc := range []struct {
+		ctx       context.Context
+		status    string
+		operators int
+	}{
+		{acme, "finished", 0},
+		{globex, "waiting", 1},
+	} {
+		name := tenant.FromContext(tc.ctx)
+		tickets, err := svc.SearchTickets(tc.ctx, &TicketFilters{})
+		if err != nil {
+			t.Fatalf("SearchTickets(%s): %v", name, err)
+		}
+		if tickets.Total != 1 || tickets.Tickets[0].TicketID != 42 || tickets.Tickets[0].Subject != name {
+			t.Errorf("%s tickets = %+v, want its own ticket 42", name, tickets.Tickets)
+		}
+		sessions, err := svc.SearchSessions(tc.ctx, &SessionFilters{})
+		if err != nil {
+			t.Fatalf("SearchSessions(%s): %v", name, err)
+		}
+		if sessions.Total != 1 || sessions.Sessions[0].PIN != name || sessions.Sessions[0].Status != tc.status || len(sessions.Sessions[0].OperatorIDs) != tc.operators {
+			t.Errorf("%s sessions = %+v, want its own s-1 (%s, %d operators)", name, sessions.Sessions, tc.status, tc.operators)
+		}
+	}
+}
+
+func TestTenancyFilterRejectsForeignDocument(t *testing.T) {
+	es := elasticsearch.NewMemory()
+	svc, err := NewSearchServiceWithIndexer(es, WithTenancy(tenant.ModeFilter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// документ под _id арендатора acme, но с чужим tenant_id (например, записанный в обход сервиса)
+	if err := es.UpdateDocument(context.Background(), indexSessions, "acme:s-1", map[string]interface{}{"session_id": "s-1", "status": "active", tenant.Field: "globex"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = svc.IndexSession(tenant.WithTenant(context.Background(), "acme"), &IndexSessionInput{SessionID: "s-1", Event: "session.ended"})
+	if err == nil {
+		t.Fatal("IndexSession over another tenant's document succeeded, want error")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	helpyerrors "github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/logger"
+	"github.com/psds-microservice/search-service/internal/sessionstate"
+)
+
+// WithSessionStates задаёт машину состояний статуса сессии (по умолчанию sessionstate.Default).
+func WithSessionStates(m *sessionstate.Machine) Option {
+	return func(s *SearchService) { s.states = m }
+}
+
+// sessionStatus вычисляет статус сессии после индексации по текущему документу doc (nil — сессии ещё нет)
+// и машине состояний и время завершения: переход в конечный статус без EndedAt завершает сессию временем UpdatedAt.
+// Запрещённый переход пишется в лог как аномалия и возвращается с кодом FAILED_PRECONDITION.
+func (s *SearchService) sessionStatus(ctx context.Context, doc map[string]interface{}, in *IndexSessionInput) (string, time.Time, error) {
+	current, _ := doc["status"].(string)
+	status, err := s.states.Next(current, in.Event, in.Status)
+	if err != nil {
+		var terr *sessionstate.TransitionError
+		if errors.As(err, &terr) {
+			logger.FromContext(ctx, slog.Default()).Warn("session status anomaly",
+				slog.String("session_id", in.SessionID),
+				slog.String("from", terr.From),
+				slog.String("to", terr.To),
+				slog.String("event", terr.Event),
+			)
+		}
+		return "", time.Time{}, helpyerrors.New(helpyerrors.CodeFailedPrecondition, "session "+in.SessionID+": "+err.Error())
+	}
+	endedAt := in.EndedAt
+	if endedAt.IsZero() && status != current && s.states.Terminal(status) {
+		endedAt = in.UpdatedAt
+		if endedAt.IsZero() {
+			endedAt = time.Now()
+		}
+	}
+	return status, endedAt, nil
+}
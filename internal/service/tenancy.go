@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/psds-microservice/helpy/errors"
+	"github.com/psds-microservice/search-service/internal/auth"
+	"github.com/psds-microservice/search-service/internal/query"
+	"github.com/psds-microservice/search-service/internal/tenant"
+)
+
+// WithTenancy задаёт режим изоляции арендаторов: tenant.ModeNone (по умолчанию), tenant.ModeIndex
+// (индексы tickets-<tenant> и т.д.) или tenant.ModeFilter (общие индексы + обязательный фильтр tenant_id).
+func WithTenancy(mode string) Option {
+	return func(s *SearchService) { s.tenancy = mode }
+}
+
+// indexName возвращает имя индекса base для арендатора tenantID в текущем режиме.
+func (s *SearchService) indexName(base, tenantID string) string {
+	if s.tenancy == tenant.ModeIndex {
+		return base + "-" + tenantID
+	}
+	return base
+}
+
+// tenantFromContext возвращает арендатора запроса/события. В режиме none всегда пусто;
+// в остальных режимах арендатор обязателен.
+func (s *SearchService) tenantFromContext(ctx context.Context) (string, error) {
+	if s.tenancy == "" || s.tenancy == tenant.ModeNone {
+		return "", nil
+	}
+	id := tenant.FromContext(ctx)
+	if id == "" {
+		return "", errors.New(errors.CodeInvalidArgument, "tenant is required ("+tenant.Header+")")
+	}
+	if err := tenant.Validate(id); err != nil {
+		return "", errors.New(errors.CodeInvalidArgument, err.Error())
+	}
+	return id, nil
+}
+
+// resolveIndex возвращает индекс сущности для арендатора из контекста; в режиме index при первом
+// обращении арендатора создаёт его индексы.
+func (s *SearchService) resolveIndex(ctx context.Context, base string) (index, tenantID string, err error) {
+	tenantID, err = s.tenantFromContext(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if s.tenancy == tenant.ModeIndex {
+		if _, ok := s.ensured.Load(tenantID); !ok {
+			if err := s.ensureIndices(ctx, tenantID); err != nil {
+				return "", "", fmt.Errorf("ensure indices for tenant %s: %w", tenantID, err)
+			}
+			s.ensured.Store(tenantID, struct{}{})
+		}
+	}
+	return s.indexName(base, tenantID), tenantID, nil
+}
+
+// docID возвращает _id документа сущности id. В режиме filter индексы общие, а id сущностей разных
+// арендаторов могут совпадать, поэтому _id включает арендатора (acme:42).
+func (s *SearchService) docID(tenantID, id string) string {
+	if s.tenancy == tenant.ModeFilter {
+		return tenantID + ":" + id
+	}
+	return id
+}
+
+// getDocument читает документ сущности id арендатора (nil — документа нет). В режиме filter документ
+// без tenant_id арендатора не возвращается, а считается ошибкой.
+func (s *SearchService) getDocument(ctx context.Context, index, tenantID, id string) (map[string]interface{}, error) {
+	doc, err := s.es.GetDocument(ctx, index, s.docID(tenantID, id))
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	if s.tenancy == tenant.ModeFilter {
+		if owner, _ := doc[tenant.Field].(string); owner != tenantID {
+			return nil, fmt.Errorf("%s %s: document belongs to another tenant", index, id)
+		}
+	}
+	return doc, nil
+}
+
+// withTenantField добавляет tenant_id в документ (режим filter).
+func (s *SearchService) withTenantField(doc map[string]interface{}, tenantID string) map[string]interface{} {
+	if s.tenancy == tenant.ModeFilter {
+		doc[tenant.Field] = tenantID
+	}
+	return doc
+}
+
+// tenantScope — ограничение запроса документами арендатора (только режим filter).
+func (s *SearchService) tenantScope(tenantID string) []query.Query {
+	if s.tenancy == tenant.ModeFilter {
+		return []query.Query{query.Term(tenant.Field, tenantID)}
+	}
+	return nil
+}
+
+// mandatoryFilters — фильтры, которые добавляются к любому поиску независимо от параметров запроса:
+// ограничение арендатора (режим filter) и row-level политика вызывающего.
+func (s *SearchService) mandatoryFilters(ctx context.Context, entity, tenantID string) ([]query.Query, error) {
+	filters := s.tenantScope(tenantID)
+	scope, err := s.policy.Scope(auth.ClaimsFromContext(ctx), entity)
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil {
+		filters = append(filters, scope)
+	}
+	return filters, nil
+}
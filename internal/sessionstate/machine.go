@@ -0,0 +1,126 @@
+package sessionstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Встроенные статусы сессии (машина по умолчанию).
+const (
+	StatusWaiting  = "waiting"
+	StatusActive   = "active"
+	StatusFinished = "finished"
+)
+
+// Machine — переходы статуса сессии по типам событий, загружается из JSON-файла.
+// Events сопоставляет типу события целевой статус; Transitions перечисляет для каждого статуса
+// статусы, в которые из него можно перейти. Событие без целевого статуса статус не меняет.
+type Machine struct {
+	Initial     string              `json:"initial"`     // статус новой сессии, если событие его не задаёт
+	Events      map[string]string   `json:"events"`      // тип события → статус
+	Transitions map[string][]string `json:"transitions"` // статус → допустимые следующие статусы
+}
+
+// TransitionError — переход, запрещённый машиной (например, finished → waiting).
+type TransitionError struct {
+	From  string
+	To    string
+	Event string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("session status transition %s → %s (event %q) is not allowed", e.From, e.To, e.Event)
+}
+
+// Default — машина, совпадающая с прежними правилами worker'а: session.ended/session.finished
+// завершают сессию, operator_joined делает её активной; из finished выхода нет.
+func Default() *Machine {
+	return &Machine{
+		Initial: StatusWaiting,
+		Events: map[string]string{
+			"session.created":  StatusWaiting,
+			"operator_joined":  StatusActive,
+			"session.ended":    StatusFinished,
+			"session.finished": StatusFinished,
+		},
+		Transitions: map[string][]string{
+			StatusWaiting:  {StatusActive, StatusFinished},
+			StatusActive:   {StatusWaiting, StatusFinished},
+			StatusFinished: {},
+		},
+	}
+}
+
+// Load читает и проверяет машину состояний из файла.
+func Load(path string) (*Machine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read session states: %w", err)
+	}
+	var m Machine
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse session states %s: %w", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("session states %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Terminal сообщает, что из статуса нет переходов (сессия завершена).
+func (m *Machine) Terminal(status string) bool {
+	to, ok := m.Transitions[status]
+	return ok && len(to) == 0
+}
+
+// validate проверяет, что все упомянутые статусы объявлены в Transitions.
+func (m *Machine) validate() error {
+	if len(m.Transitions) == 0 {
+		return fmt.Errorf("transitions must declare at least one status")
+	}
+	if _, ok := m.Transitions[m.Initial]; !ok {
+		return fmt.Errorf("initial status %q is not declared in transitions", m.Initial)
+	}
+	for event, status := range m.Events {
+		if _, ok := m.Transitions[status]; !ok {
+			return fmt.Errorf("event %s: status %q is not declared in transitions", event, status)
+		}
+	}
+	for from, to := range m.Transitions {
+		for _, status := range to {
+			if _, ok := m.Transitions[status]; !ok {
+				return fmt.Errorf("transition %s → %s: status %q is not declared", from, status, status)
+			}
+		}
+	}
+	return nil
+}
+
+// Next возвращает статус сессии после события. current — текущий статус (пусто — сессии ещё нет),
+// status — статус, явно переданный в событии (пусто — берётся из Events). Неизвестное событие
+// оставляет текущий статус. Переход в необъявленный статус или не перечисленный в Transitions —
+// *TransitionError; из необъявленного статуса (документ записан до смены машины) переход разрешён.
+func (m *Machine) Next(current, event, status string) (string, error) {
+	target := status
+	if target == "" {
+		target = m.Events[event]
+	}
+	switch {
+	case target == "" && current == "":
+		return m.Initial, nil
+	case target == "" || target == current:
+		return current, nil
+	}
+	if _, ok := m.Transitions[target]; !ok {
+		return "", &TransitionError{From: current, To: target, Event: event}
+	}
+	if current == "" {
+		return target, nil
+	}
+	if allowed, ok := m.Transitions[current]; ok && !slices.Contains(allowed, target) {
+		return "", &TransitionError{From: current, To: target, Event: event}
+	}
+	return target, nil
+}
@@ -0,0 +1,102 @@
+package sessionstate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	m, err := Load(filepath.Join("..", "..", "deployments", "session_states.example.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cases := []struct {
+		name                   string
+		current, event, status string
+		want                   string // пусто — переход запрещён
+	}{
+		{"new session without target", "", "session.updated", "", "waiting"},
+		{"new session by event", "", "operator_joined", "", "active"},
+		{"new session with explicit status", "", "", "finished", "finished"},
+		{"allowed by event", "waiting", "operator.assigned", "", "active"},
+		{"allowed back", "active", "", "waiting", "waiting"},
+		{"explicit status wins over event", "waiting", "session.ended", "active", "active"},
+		{"same status", "active", "operator_joined", "", "active"},
+		{"unknown event keeps status", "active", "session.pinged", "", "active"},
+		{"unknown event keeps terminal status", "finished", "session.pinged", "", "finished"},
+		{"out of terminal", "finished", "operator_joined", "", ""},
+		{"not listed", "active", "session.cancelled", "", ""},
+		{"undeclared target", "waiting", "", "archived", ""},
+		{"undeclared target for new session", "", "", "archived", ""},
+		{"from undeclared status", "paused", "session.ended", "", "finished"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := m.Next(tc.current, tc.event, tc.status)
+			if tc.want != "" {
+				if err != nil || got != tc.want {
+					t.Fatalf("Next = %q, %v; want %q", got, err, tc.want)
+				}
+				return
+			}
+			var terr *TransitionError
+			if !errors.As(err, &terr) {
+				t.Fatalf("Next = %q, %v; want *TransitionError", got, err)
+			}
+			if terr.From != tc.current || terr.Event != tc.event {
+				t.Errorf("TransitionError = %+v, want from %q on %q", terr, tc.current, tc.event)
+			}
+		})
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	m := Default()
+	for status, want := range map[string]bool{
+		StatusWaiting:  false,
+		StatusActive:   false,
+		StatusFinished: true,
+		"paused":       false, // необъявленный статус не считается завершённым
+	} {
+		if got := m.Terminal(status); got != want {
+			t.Errorf("Terminal(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().validate(); err != nil {
+		t.Fatalf("Default().validate: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"no transitions", `{"initial":"waiting"}`, "at least one status"},
+		{"undeclared initial", `{"initial":"new","transitions":{"waiting":[]}}`, `initial status "new"`},
+		{"undeclared event status", `{"initial":"waiting","events":{"session.ended":"done"},"transitions":{"waiting":[]}}`, `event session.ended: status "done"`},
+		{"undeclared transition target", `{"initial":"waiting","transitions":{"waiting":["active"]}}`, `transition waiting → active`},
+		{"not json", `initial`, "parse session states"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "states.json")
+			if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Load error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "read session states") {
+		t.Errorf("Load(missing) error = %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Режимы изоляции арендаторов (TENANCY_MODE).
+const (
+	ModeNone   = "none"   // один общий набор индексов без tenant_id
+	ModeIndex  = "index"  // отдельные индексы на арендатора: tickets-<tenant>
+	ModeFilter = "filter" // общие индексы, обязательный фильтр по tenant_id
+)
+
+// Header — ключ gRPC metadata / HTTP-заголовок / заголовок Kafka-сообщения с идентификатором арендатора.
+const Header = "x-tenant-id"
+
+// Field — поле документа с идентификатором арендатора (режим filter).
+const Field = "tenant_id"
+
+// idPattern — допустимый идентификатор: безопасен как суффикс имени индекса ES.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type ctxKey struct{}
+
+// WithTenant кладёт идентификатор арендатора в контекст.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор арендатора из контекста (пусто, если не задан).
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Validate проверяет формат идентификатора арендатора.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid tenant id %q: want lowercase letters, digits, '-' or '_' (max 63)", id)
+	}
+	return nil
+}
+
+// ValidMode сообщает, известен ли режим изоляции.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeNone, ModeIndex, ModeFilter:
+		return true
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package validator
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -62,6 +63,14 @@ func (v *Validator) ValidateSearchLimit(limit int) error {
 	return nil
 }
 
+// ValidateTimeRange validates a [from, to) date filter; zero bounds are open
+func (v *Validator) ValidateTimeRange(name string, from, to time.Time) error {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return errors.New("validation: " + name + ".from must be before " + name + ".to")
+	}
+	return nil
+}
+
 // ValidateSearchOffset validates offset parameter for pagination
 func (v *Validator) ValidateSearchOffset(offset int) error {
 	if offset < 0 {
@@ -10,6 +10,7 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -23,15 +24,31 @@ const (
 )
 
 type SearchTicketsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                           // опционально: фильтр по status
-	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`    // опционально: фильтр по session_id
-	ClientId      string                 `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`       // опционально: фильтр по client_id
-	OperatorId    string                 `protobuf:"bytes,4,opt,name=operator_id,json=operatorId,proto3" json:"operator_id,omitempty"` // опционально: фильтр по operator_id
-	Limit         int32                  `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`                            // опционально: лимит результатов (по умолчанию 20, максимум 100)
-	Offset        int32                  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`                          // опционально: смещение для пагинации (по умолчанию 0)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Status     string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                           // опционально: фильтр по status
+	SessionId  string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`    // опционально: фильтр по session_id
+	ClientId   string                 `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`       // опционально: фильтр по client_id
+	OperatorId string                 `protobuf:"bytes,4,opt,name=operator_id,json=operatorId,proto3" json:"operator_id,omitempty"` // опционально: фильтр по operator_id
+	Limit      int32                  `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`                            // опционально: лимит результатов (по умолчанию 20, максимум 100)
+	Offset     int32                  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`                          // опционально: смещение для пагинации (по умолчанию 0)
+	Created    *TimeRange             `protobuf:"bytes,7,opt,name=created,proto3" json:"created,omitempty"`                         // опционально: фильтр по created_at
+	Updated    *TimeRange             `protobuf:"bytes,8,opt,name=updated,proto3" json:"updated,omitempty"`                         // опционально: фильтр по updated_at
+	// Множественные фильтры: совпадение с любым из значений (OR); одиночное поле выше — алиас одного значения.
+	// В query-параметрах HTTP значения повторяются: statuses=open&statuses=in_progress
+	Statuses      []string `protobuf:"bytes,9,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	ExcludeStatus []string `protobuf:"bytes,10,rep,name=exclude_status,json=excludeStatus,proto3" json:"exclude_status,omitempty"` // исключить тикеты с этими статусами
+	ClientIds     []string `protobuf:"bytes,11,rep,name=client_ids,json=clientIds,proto3" json:"client_ids,omitempty"`
+	OperatorIds   []string `protobuf:"bytes,12,rep,name=operator_ids,json=operatorIds,proto3" json:"operator_ids,omitempty"`
+	// опционально: строка поиска, например status:open operator:me subject:"card blocked" -status:closed created:>2026-09-01
+	// поля: status (-status исключает), operator (me — вызывающий), operator_region, client, session, subject, text, created, updated
+	Query string `protobuf:"bytes,13,opt,name=query,proto3" json:"query,omitempty"`
+	// опционально: полнотекстовый поиск по subject, notes и комментариям (все слова);
+	// для совпавшего комментария в хите заполняются snippet и matched_comment_id
+	Text            string   `protobuf:"bytes,14,opt,name=text,proto3" json:"text,omitempty"`
+	OperatorRegion  string   `protobuf:"bytes,15,opt,name=operator_region,json=operatorRegion,proto3" json:"operator_region,omitempty"`    // опционально: фильтр по региону оператора тикета
+	OperatorRegions []string `protobuf:"bytes,16,rep,name=operator_regions,json=operatorRegions,proto3" json:"operator_regions,omitempty"` // опционально: регион оператора — любой из
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *SearchTicketsRequest) Reset() {
@@ -106,13 +123,94 @@ func (x *SearchTicketsRequest) GetOffset() int32 {
 	return 0
 }
 
+func (x *SearchTicketsRequest) GetCreated() *TimeRange {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetUpdated() *TimeRange {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetExcludeStatus() []string {
+	if x != nil {
+		return x.ExcludeStatus
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetClientIds() []string {
+	if x != nil {
+		return x.ClientIds
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetOperatorIds() []string {
+	if x != nil {
+		return x.OperatorIds
+	}
+	return nil
+}
+
+func (x *SearchTicketsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchTicketsRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SearchTicketsRequest) GetOperatorRegion() string {
+	if x != nil {
+		return x.OperatorRegion
+	}
+	return ""
+}
+
+func (x *SearchTicketsRequest) GetOperatorRegions() []string {
+	if x != nil {
+		return x.OperatorRegions
+	}
+	return nil
+}
+
 type SearchSessionsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                     // опционально: фильтр по status (waiting, active, finished)
-	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"` // опционально: фильтр по client_id
-	Pin           string                 `protobuf:"bytes,3,opt,name=pin,proto3" json:"pin,omitempty"`                           // опционально: фильтр по pin
-	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                      // опционально: лимит результатов (по умолчанию 20, максимум 100)
-	Offset        int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`                    // опционально: смещение для пагинации (по умолчанию 0)
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Status   string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                     // опционально: фильтр по status (waiting, active, finished)
+	ClientId string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"` // опционально: фильтр по client_id
+	Pin      string                 `protobuf:"bytes,3,opt,name=pin,proto3" json:"pin,omitempty"`                           // опционально: фильтр по pin
+	Limit    int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                      // опционально: лимит результатов (по умолчанию 20, максимум 100)
+	Offset   int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`                    // опционально: смещение для пагинации (по умолчанию 0)
+	Created  *TimeRange             `protobuf:"bytes,6,opt,name=created,proto3" json:"created,omitempty"`                   // опционально: фильтр по created_at
+	Updated  *TimeRange             `protobuf:"bytes,7,opt,name=updated,proto3" json:"updated,omitempty"`                   // опционально: фильтр по updated_at
+	Ended    *TimeRange             `protobuf:"bytes,8,opt,name=ended,proto3" json:"ended,omitempty"`                       // опционально: фильтр по ended_at (завершённые сессии)
+	// Множественные фильтры: совпадение с любым из значений (OR); одиночное поле выше — алиас одного значения.
+	Statuses      []string `protobuf:"bytes,9,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	ExcludeStatus []string `protobuf:"bytes,10,rep,name=exclude_status,json=excludeStatus,proto3" json:"exclude_status,omitempty"` // исключить сессии с этими статусами
+	ClientIds     []string `protobuf:"bytes,11,rep,name=client_ids,json=clientIds,proto3" json:"client_ids,omitempty"`
+	// опционально: строка поиска (поля: status, -status, client, pin, operator (me — вызывающий), created, updated, ended)
+	Query         string   `protobuf:"bytes,12,opt,name=query,proto3" json:"query,omitempty"`
+	OperatorId    string   `protobuf:"bytes,13,opt,name=operator_id,json=operatorId,proto3" json:"operator_id,omitempty"`    // опционально: сессии, к которым подключался оператор
+	OperatorIds   []string `protobuf:"bytes,14,rep,name=operator_ids,json=operatorIds,proto3" json:"operator_ids,omitempty"` // опционально: подключался любой из операторов
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -182,13 +280,83 @@ func (x *SearchSessionsRequest) GetOffset() int32 {
 	return 0
 }
 
+func (x *SearchSessionsRequest) GetCreated() *TimeRange {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetUpdated() *TimeRange {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetEnded() *TimeRange {
+	if x != nil {
+		return x.Ended
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetExcludeStatus() []string {
+	if x != nil {
+		return x.ExcludeStatus
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetClientIds() []string {
+	if x != nil {
+		return x.ClientIds
+	}
+	return nil
+}
+
+func (x *SearchSessionsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchSessionsRequest) GetOperatorId() string {
+	if x != nil {
+		return x.OperatorId
+	}
+	return ""
+}
+
+func (x *SearchSessionsRequest) GetOperatorIds() []string {
+	if x != nil {
+		return x.OperatorIds
+	}
+	return nil
+}
+
 type SearchOperatorsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Region        string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`                              // опционально: фильтр по region
-	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`                                  // опционально: фильтр по role (operator, supervisor, admin)
-	DisplayName   string                 `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"` // опционально: фильтр по display_name (точное совпадение)
-	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                               // опционально: лимит результатов (по умолчанию 20, максимум 100)
-	Offset        int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`                             // опционально: смещение для пагинации (по умолчанию 0)
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Region      string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`                              // опционально: фильтр по region
+	Role        string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`                                  // опционально: фильтр по role (operator, supervisor, admin)
+	DisplayName string                 `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"` // опционально: фильтр по display_name (точное совпадение)
+	Limit       int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                               // опционально: лимит результатов (по умолчанию 20, максимум 100)
+	Offset      int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`                             // опционально: смещение для пагинации (по умолчанию 0)
+	Created     *TimeRange             `protobuf:"bytes,6,opt,name=created,proto3" json:"created,omitempty"`                            // опционально: фильтр по created_at
+	Updated     *TimeRange             `protobuf:"bytes,7,opt,name=updated,proto3" json:"updated,omitempty"`                            // опционально: фильтр по updated_at
+	// Множественные фильтры: совпадение с любым из значений (OR); одиночное поле выше — алиас одного значения.
+	Regions []string `protobuf:"bytes,8,rep,name=regions,proto3" json:"regions,omitempty"`
+	Roles   []string `protobuf:"bytes,9,rep,name=roles,proto3" json:"roles,omitempty"`
+	// опционально: строка поиска (поля: region, role, name, created, updated)
+	Query         string `protobuf:"bytes,10,opt,name=query,proto3" json:"query,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -258,6 +426,95 @@ func (x *SearchOperatorsRequest) GetOffset() int32 {
 	return 0
 }
 
+func (x *SearchOperatorsRequest) GetCreated() *TimeRange {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *SearchOperatorsRequest) GetUpdated() *TimeRange {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *SearchOperatorsRequest) GetRegions() []string {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *SearchOperatorsRequest) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *SearchOperatorsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+// TimeRange — диапазон дат [from, to); незаданная граница не ограничивает.
+// В query-параметрах HTTP: created.from=2024-05-01T00:00:00Z&created.to=2024-05-08T00:00:00Z
+type TimeRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"` // включительно
+	To            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`     // не включительно
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeRange) Reset() {
+	*x = TimeRange{}
+	mi := &file_search_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeRange) ProtoMessage() {}
+
+func (x *TimeRange) ProtoReflect() protoreflect.Message {
+	mi := &file_search_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeRange.ProtoReflect.Descriptor instead.
+func (*TimeRange) Descriptor() ([]byte, []int) {
+	return file_search_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TimeRange) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *TimeRange) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
 type IndexTicketRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TicketId      int64                  `protobuf:"varint,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
@@ -267,13 +524,15 @@ type IndexTicketRequest struct {
 	Subject       string                 `protobuf:"bytes,5,opt,name=subject,proto3" json:"subject,omitempty"`
 	Notes         string                 `protobuf:"bytes,6,opt,name=notes,proto3" json:"notes,omitempty"`
 	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`  // опционально: по умолчанию updated_at
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // опционально: по умолчанию время индексации
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IndexTicketRequest) Reset() {
 	*x = IndexTicketRequest{}
-	mi := &file_search_proto_msgTypes[3]
+	mi := &file_search_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -285,7 +544,7 @@ func (x *IndexTicketRequest) String() string {
 func (*IndexTicketRequest) ProtoMessage() {}
 
 func (x *IndexTicketRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[3]
+	mi := &file_search_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -298,7 +557,7 @@ func (x *IndexTicketRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IndexTicketRequest.ProtoReflect.Descriptor instead.
 func (*IndexTicketRequest) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{3}
+	return file_search_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *IndexTicketRequest) GetTicketId() int64 {
@@ -350,19 +609,36 @@ func (x *IndexTicketRequest) GetStatus() string {
 	return ""
 }
 
+func (x *IndexTicketRequest) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *IndexTicketRequest) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
 type IndexSessionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
 	Pin           string                 `protobuf:"bytes,3,opt,name=pin,proto3" json:"pin,omitempty"`
 	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // опционально: по умолчанию updated_at
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // опционально: по умолчанию время индексации
+	EndedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`       // опционально: время завершения сессии
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IndexSessionRequest) Reset() {
 	*x = IndexSessionRequest{}
-	mi := &file_search_proto_msgTypes[4]
+	mi := &file_search_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -374,7 +650,7 @@ func (x *IndexSessionRequest) String() string {
 func (*IndexSessionRequest) ProtoMessage() {}
 
 func (x *IndexSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[4]
+	mi := &file_search_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -387,7 +663,7 @@ func (x *IndexSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IndexSessionRequest.ProtoReflect.Descriptor instead.
 func (*IndexSessionRequest) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{4}
+	return file_search_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *IndexSessionRequest) GetSessionId() string {
@@ -418,19 +694,42 @@ func (x *IndexSessionRequest) GetStatus() string {
 	return ""
 }
 
+func (x *IndexSessionRequest) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *IndexSessionRequest) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *IndexSessionRequest) GetEndedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndedAt
+	}
+	return nil
+}
+
 type IndexOperatorRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	DisplayName   string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
 	Region        string                 `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty"`
 	Role          string                 `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // опционально: по умолчанию updated_at
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // опционально: по умолчанию время индексации
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IndexOperatorRequest) Reset() {
 	*x = IndexOperatorRequest{}
-	mi := &file_search_proto_msgTypes[5]
+	mi := &file_search_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -442,7 +741,7 @@ func (x *IndexOperatorRequest) String() string {
 func (*IndexOperatorRequest) ProtoMessage() {}
 
 func (x *IndexOperatorRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[5]
+	mi := &file_search_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -455,7 +754,7 @@ func (x *IndexOperatorRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IndexOperatorRequest.ProtoReflect.Descriptor instead.
 func (*IndexOperatorRequest) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{5}
+	return file_search_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *IndexOperatorRequest) GetUserId() string {
@@ -486,6 +785,20 @@ func (x *IndexOperatorRequest) GetRole() string {
 	return ""
 }
 
+func (x *IndexOperatorRequest) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *IndexOperatorRequest) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
 type SearchTicketsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Tickets       []*TicketHit           `protobuf:"bytes,1,rep,name=tickets,proto3" json:"tickets,omitempty"`
@@ -497,7 +810,7 @@ type SearchTicketsResponse struct {
 
 func (x *SearchTicketsResponse) Reset() {
 	*x = SearchTicketsResponse{}
-	mi := &file_search_proto_msgTypes[6]
+	mi := &file_search_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -509,7 +822,7 @@ func (x *SearchTicketsResponse) String() string {
 func (*SearchTicketsResponse) ProtoMessage() {}
 
 func (x *SearchTicketsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[6]
+	mi := &file_search_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -522,7 +835,7 @@ func (x *SearchTicketsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SearchTicketsResponse.ProtoReflect.Descriptor instead.
 func (*SearchTicketsResponse) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{6}
+	return file_search_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *SearchTicketsResponse) GetTickets() []*TicketHit {
@@ -557,7 +870,7 @@ type SearchSessionsResponse struct {
 
 func (x *SearchSessionsResponse) Reset() {
 	*x = SearchSessionsResponse{}
-	mi := &file_search_proto_msgTypes[7]
+	mi := &file_search_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -569,7 +882,7 @@ func (x *SearchSessionsResponse) String() string {
 func (*SearchSessionsResponse) ProtoMessage() {}
 
 func (x *SearchSessionsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[7]
+	mi := &file_search_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -582,7 +895,7 @@ func (x *SearchSessionsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SearchSessionsResponse.ProtoReflect.Descriptor instead.
 func (*SearchSessionsResponse) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{7}
+	return file_search_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *SearchSessionsResponse) GetSessions() []*SessionHit {
@@ -617,7 +930,7 @@ type SearchOperatorsResponse struct {
 
 func (x *SearchOperatorsResponse) Reset() {
 	*x = SearchOperatorsResponse{}
-	mi := &file_search_proto_msgTypes[8]
+	mi := &file_search_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -629,7 +942,7 @@ func (x *SearchOperatorsResponse) String() string {
 func (*SearchOperatorsResponse) ProtoMessage() {}
 
 func (x *SearchOperatorsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[8]
+	mi := &file_search_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -642,7 +955,7 @@ func (x *SearchOperatorsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SearchOperatorsResponse.ProtoReflect.Descriptor instead.
 func (*SearchOperatorsResponse) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{8}
+	return file_search_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SearchOperatorsResponse) GetOperators() []*OperatorHit {
@@ -667,18 +980,23 @@ func (x *SearchOperatorsResponse) GetHasMore() bool {
 }
 
 type TicketHit struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TicketId      int64                  `protobuf:"varint,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
-	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Subject       string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
-	Snippet       string                 `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TicketId         int64                  `protobuf:"varint,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
+	SessionId        string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Subject          string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Snippet          string                 `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	MatchedCommentId string                 `protobuf:"bytes,5,opt,name=matched_comment_id,json=matchedCommentId,proto3" json:"matched_comment_id,omitempty"` // комментарий, совпавший с text
+	// копии атрибутов оператора и сессии на момент индексации (обновляются при их изменении)
+	OperatorDisplayName string `protobuf:"bytes,6,opt,name=operator_display_name,json=operatorDisplayName,proto3" json:"operator_display_name,omitempty"`
+	OperatorRegion      string `protobuf:"bytes,7,opt,name=operator_region,json=operatorRegion,proto3" json:"operator_region,omitempty"`
+	SessionStatus       string `protobuf:"bytes,8,opt,name=session_status,json=sessionStatus,proto3" json:"session_status,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *TicketHit) Reset() {
 	*x = TicketHit{}
-	mi := &file_search_proto_msgTypes[9]
+	mi := &file_search_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -690,7 +1008,7 @@ func (x *TicketHit) String() string {
 func (*TicketHit) ProtoMessage() {}
 
 func (x *TicketHit) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[9]
+	mi := &file_search_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -703,7 +1021,7 @@ func (x *TicketHit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TicketHit.ProtoReflect.Descriptor instead.
 func (*TicketHit) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{9}
+	return file_search_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *TicketHit) GetTicketId() int64 {
@@ -734,19 +1052,49 @@ func (x *TicketHit) GetSnippet() string {
 	return ""
 }
 
+func (x *TicketHit) GetMatchedCommentId() string {
+	if x != nil {
+		return x.MatchedCommentId
+	}
+	return ""
+}
+
+func (x *TicketHit) GetOperatorDisplayName() string {
+	if x != nil {
+		return x.OperatorDisplayName
+	}
+	return ""
+}
+
+func (x *TicketHit) GetOperatorRegion() string {
+	if x != nil {
+		return x.OperatorRegion
+	}
+	return ""
+}
+
+func (x *TicketHit) GetSessionStatus() string {
+	if x != nil {
+		return x.SessionStatus
+	}
+	return ""
+}
+
 type SessionHit struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Pin           string                 `protobuf:"bytes,2,opt,name=pin,proto3" json:"pin,omitempty"`
-	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	Snippet       string                 `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SessionId        string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Pin              string                 `protobuf:"bytes,2,opt,name=pin,proto3" json:"pin,omitempty"`
+	Status           string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Snippet          string                 `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	OperatorIds      []string               `protobuf:"bytes,5,rep,name=operator_ids,json=operatorIds,proto3" json:"operator_ids,omitempty"`                 // операторы в порядке первого подключения
+	ParticipantCount int32                  `protobuf:"varint,6,opt,name=participant_count,json=participantCount,proto3" json:"participant_count,omitempty"` // число операторов, подключавшихся к сессии
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *SessionHit) Reset() {
 	*x = SessionHit{}
-	mi := &file_search_proto_msgTypes[10]
+	mi := &file_search_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -758,7 +1106,7 @@ func (x *SessionHit) String() string {
 func (*SessionHit) ProtoMessage() {}
 
 func (x *SessionHit) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[10]
+	mi := &file_search_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -771,7 +1119,7 @@ func (x *SessionHit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionHit.ProtoReflect.Descriptor instead.
 func (*SessionHit) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{10}
+	return file_search_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *SessionHit) GetSessionId() string {
@@ -802,6 +1150,20 @@ func (x *SessionHit) GetSnippet() string {
 	return ""
 }
 
+func (x *SessionHit) GetOperatorIds() []string {
+	if x != nil {
+		return x.OperatorIds
+	}
+	return nil
+}
+
+func (x *SessionHit) GetParticipantCount() int32 {
+	if x != nil {
+		return x.ParticipantCount
+	}
+	return 0
+}
+
 type OperatorHit struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -814,7 +1176,7 @@ type OperatorHit struct {
 
 func (x *OperatorHit) Reset() {
 	*x = OperatorHit{}
-	mi := &file_search_proto_msgTypes[11]
+	mi := &file_search_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -826,7 +1188,7 @@ func (x *OperatorHit) String() string {
 func (*OperatorHit) ProtoMessage() {}
 
 func (x *OperatorHit) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[11]
+	mi := &file_search_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -839,7 +1201,7 @@ func (x *OperatorHit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OperatorHit.ProtoReflect.Descriptor instead.
 func (*OperatorHit) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{11}
+	return file_search_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *OperatorHit) GetUserId() string {
@@ -879,7 +1241,7 @@ type IndexResponse struct {
 
 func (x *IndexResponse) Reset() {
 	*x = IndexResponse{}
-	mi := &file_search_proto_msgTypes[12]
+	mi := &file_search_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -891,7 +1253,7 @@ func (x *IndexResponse) String() string {
 func (*IndexResponse) ProtoMessage() {}
 
 func (x *IndexResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_search_proto_msgTypes[12]
+	mi := &file_search_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -904,7 +1266,7 @@ func (x *IndexResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IndexResponse.ProtoReflect.Descriptor instead.
 func (*IndexResponse) Descriptor() ([]byte, []int) {
-	return file_search_proto_rawDescGZIP(), []int{12}
+	return file_search_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *IndexResponse) GetOk() bool {
@@ -914,11 +1276,253 @@ func (x *IndexResponse) GetOk() bool {
 	return false
 }
 
+type ExplainRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Search:
+	//
+	//	*ExplainRequest_Tickets
+	//	*ExplainRequest_Sessions
+	//	*ExplainRequest_Operators
+	Search        isExplainRequest_Search `protobuf_oneof:"search"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainRequest) Reset() {
+	*x = ExplainRequest{}
+	mi := &file_search_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainRequest) ProtoMessage() {}
+
+func (x *ExplainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_search_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainRequest.ProtoReflect.Descriptor instead.
+func (*ExplainRequest) Descriptor() ([]byte, []int) {
+	return file_search_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ExplainRequest) GetSearch() isExplainRequest_Search {
+	if x != nil {
+		return x.Search
+	}
+	return nil
+}
+
+func (x *ExplainRequest) GetTickets() *SearchTicketsRequest {
+	if x != nil {
+		if x, ok := x.Search.(*ExplainRequest_Tickets); ok {
+			return x.Tickets
+		}
+	}
+	return nil
+}
+
+func (x *ExplainRequest) GetSessions() *SearchSessionsRequest {
+	if x != nil {
+		if x, ok := x.Search.(*ExplainRequest_Sessions); ok {
+			return x.Sessions
+		}
+	}
+	return nil
+}
+
+func (x *ExplainRequest) GetOperators() *SearchOperatorsRequest {
+	if x != nil {
+		if x, ok := x.Search.(*ExplainRequest_Operators); ok {
+			return x.Operators
+		}
+	}
+	return nil
+}
+
+type isExplainRequest_Search interface {
+	isExplainRequest_Search()
+}
+
+type ExplainRequest_Tickets struct {
+	Tickets *SearchTicketsRequest `protobuf:"bytes,1,opt,name=tickets,proto3,oneof"`
+}
+
+type ExplainRequest_Sessions struct {
+	Sessions *SearchSessionsRequest `protobuf:"bytes,2,opt,name=sessions,proto3,oneof"`
+}
+
+type ExplainRequest_Operators struct {
+	Operators *SearchOperatorsRequest `protobuf:"bytes,3,opt,name=operators,proto3,oneof"`
+}
+
+func (*ExplainRequest_Tickets) isExplainRequest_Search() {}
+
+func (*ExplainRequest_Sessions) isExplainRequest_Search() {}
+
+func (*ExplainRequest_Operators) isExplainRequest_Search() {}
+
+type ExplainResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`                  // индекс, по которому выполнен поиск
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`                  // тело запроса к ES (JSON), включая обязательные фильтры
+	Profile       string                 `protobuf:"bytes,3,opt,name=profile,proto3" json:"profile,omitempty"`              // profile из ответа ES (JSON)
+	TookMs        int64                  `protobuf:"varint,4,opt,name=took_ms,json=tookMs,proto3" json:"took_ms,omitempty"` // время выполнения запроса в ES
+	Total         int64                  `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"`                 // общее количество результатов
+	Hits          []*HitExplanation      `protobuf:"bytes,6,rep,name=hits,proto3" json:"hits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainResponse) Reset() {
+	*x = ExplainResponse{}
+	mi := &file_search_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainResponse) ProtoMessage() {}
+
+func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_search_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainResponse.ProtoReflect.Descriptor instead.
+func (*ExplainResponse) Descriptor() ([]byte, []int) {
+	return file_search_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ExplainResponse) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetTookMs() int64 {
+	if x != nil {
+		return x.TookMs
+	}
+	return 0
+}
+
+func (x *ExplainResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ExplainResponse) GetHits() []*HitExplanation {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+type HitExplanation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Score         float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	Explanation   string                 `protobuf:"bytes,3,opt,name=explanation,proto3" json:"explanation,omitempty"` // _explanation из ответа ES (JSON)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HitExplanation) Reset() {
+	*x = HitExplanation{}
+	mi := &file_search_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HitExplanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HitExplanation) ProtoMessage() {}
+
+func (x *HitExplanation) ProtoReflect() protoreflect.Message {
+	mi := &file_search_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HitExplanation.ProtoReflect.Descriptor instead.
+func (*HitExplanation) Descriptor() ([]byte, []int) {
+	return file_search_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *HitExplanation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *HitExplanation) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *HitExplanation) GetExplanation() string {
+	if x != nil {
+		return x.Explanation
+	}
+	return ""
+}
+
 var File_search_proto protoreflect.FileDescriptor
 
 const file_search_proto_rawDesc = "" +
 	"\n" +
-	"\fsearch.proto\x12\x0esearch_service\x1a\x1cgoogle/api/annotations.proto\"\xb9\x01\n" +
+	"\fsearch.proto\x12\x0esearch_service\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa6\x04\n" +
 	"\x14SearchTicketsRequest\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1d\n" +
 	"\n" +
@@ -927,19 +1531,52 @@ const file_search_proto_rawDesc = "" +
 	"\voperator_id\x18\x04 \x01(\tR\n" +
 	"operatorId\x12\x14\n" +
 	"\x05limit\x18\x05 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\x06 \x01(\x05R\x06offset\"\x8c\x01\n" +
+	"\x06offset\x18\x06 \x01(\x05R\x06offset\x123\n" +
+	"\acreated\x18\a \x01(\v2\x19.search_service.TimeRangeR\acreated\x123\n" +
+	"\aupdated\x18\b \x01(\v2\x19.search_service.TimeRangeR\aupdated\x12\x1a\n" +
+	"\bstatuses\x18\t \x03(\tR\bstatuses\x12%\n" +
+	"\x0eexclude_status\x18\n" +
+	" \x03(\tR\rexcludeStatus\x12\x1d\n" +
+	"\n" +
+	"client_ids\x18\v \x03(\tR\tclientIds\x12!\n" +
+	"\foperator_ids\x18\f \x03(\tR\voperatorIds\x12\x14\n" +
+	"\x05query\x18\r \x01(\tR\x05query\x12\x12\n" +
+	"\x04text\x18\x0e \x01(\tR\x04text\x12'\n" +
+	"\x0foperator_region\x18\x0f \x01(\tR\x0eoperatorRegion\x12)\n" +
+	"\x10operator_regions\x18\x10 \x03(\tR\x0foperatorRegions\"\xe3\x03\n" +
 	"\x15SearchSessionsRequest\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1b\n" +
 	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x10\n" +
 	"\x03pin\x18\x03 \x01(\tR\x03pin\x12\x14\n" +
 	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\x05 \x01(\x05R\x06offset\"\x95\x01\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\x123\n" +
+	"\acreated\x18\x06 \x01(\v2\x19.search_service.TimeRangeR\acreated\x123\n" +
+	"\aupdated\x18\a \x01(\v2\x19.search_service.TimeRangeR\aupdated\x12/\n" +
+	"\x05ended\x18\b \x01(\v2\x19.search_service.TimeRangeR\x05ended\x12\x1a\n" +
+	"\bstatuses\x18\t \x03(\tR\bstatuses\x12%\n" +
+	"\x0eexclude_status\x18\n" +
+	" \x03(\tR\rexcludeStatus\x12\x1d\n" +
+	"\n" +
+	"client_ids\x18\v \x03(\tR\tclientIds\x12\x14\n" +
+	"\x05query\x18\f \x01(\tR\x05query\x12\x1f\n" +
+	"\voperator_id\x18\r \x01(\tR\n" +
+	"operatorId\x12!\n" +
+	"\foperator_ids\x18\x0e \x03(\tR\voperatorIds\"\xc5\x02\n" +
 	"\x16SearchOperatorsRequest\x12\x16\n" +
 	"\x06region\x18\x01 \x01(\tR\x06region\x12\x12\n" +
 	"\x04role\x18\x02 \x01(\tR\x04role\x12!\n" +
 	"\fdisplay_name\x18\x03 \x01(\tR\vdisplayName\x12\x14\n" +
 	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\x05 \x01(\x05R\x06offset\"\xd6\x01\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\x123\n" +
+	"\acreated\x18\x06 \x01(\v2\x19.search_service.TimeRangeR\acreated\x123\n" +
+	"\aupdated\x18\a \x01(\v2\x19.search_service.TimeRangeR\aupdated\x12\x18\n" +
+	"\aregions\x18\b \x03(\tR\aregions\x12\x14\n" +
+	"\x05roles\x18\t \x03(\tR\x05roles\x12\x14\n" +
+	"\x05query\x18\n" +
+	" \x01(\tR\x05query\"g\n" +
+	"\tTimeRange\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\"\xda\x02\n" +
 	"\x12IndexTicketRequest\x12\x1b\n" +
 	"\tticket_id\x18\x01 \x01(\x03R\bticketId\x12\x1d\n" +
 	"\n" +
@@ -949,18 +1586,32 @@ const file_search_proto_rawDesc = "" +
 	"operatorId\x12\x18\n" +
 	"\asubject\x18\x05 \x01(\tR\asubject\x12\x14\n" +
 	"\x05notes\x18\x06 \x01(\tR\x05notes\x12\x16\n" +
-	"\x06status\x18\a \x01(\tR\x06status\"{\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtJ\x04\b\b\x10\tR\x06region\"\xa8\x02\n" +
 	"\x13IndexSessionRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1b\n" +
 	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x10\n" +
 	"\x03pin\x18\x03 \x01(\tR\x03pin\x12\x16\n" +
-	"\x06status\x18\x04 \x01(\tR\x06status\"~\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x125\n" +
+	"\bended_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aendedAt\"\xf4\x01\n" +
 	"\x14IndexOperatorRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
 	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12\x16\n" +
 	"\x06region\x18\x03 \x01(\tR\x06region\x12\x12\n" +
-	"\x04role\x18\x04 \x01(\tR\x04role\"}\n" +
+	"\x04role\x18\x04 \x01(\tR\x04role\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"}\n" +
 	"\x15SearchTicketsResponse\x123\n" +
 	"\atickets\x18\x01 \x03(\v2\x19.search_service.TicketHitR\atickets\x12\x14\n" +
 	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x19\n" +
@@ -972,34 +1623,57 @@ const file_search_proto_rawDesc = "" +
 	"\x17SearchOperatorsResponse\x129\n" +
 	"\toperators\x18\x01 \x03(\v2\x1b.search_service.OperatorHitR\toperators\x12\x14\n" +
 	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x19\n" +
-	"\bhas_more\x18\x03 \x01(\bR\ahasMore\"{\n" +
+	"\bhas_more\x18\x03 \x01(\bR\ahasMore\"\xad\x02\n" +
 	"\tTicketHit\x12\x1b\n" +
 	"\tticket_id\x18\x01 \x01(\x03R\bticketId\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x18\n" +
 	"\asubject\x18\x03 \x01(\tR\asubject\x12\x18\n" +
-	"\asnippet\x18\x04 \x01(\tR\asnippet\"o\n" +
+	"\asnippet\x18\x04 \x01(\tR\asnippet\x12,\n" +
+	"\x12matched_comment_id\x18\x05 \x01(\tR\x10matchedCommentId\x122\n" +
+	"\x15operator_display_name\x18\x06 \x01(\tR\x13operatorDisplayName\x12'\n" +
+	"\x0foperator_region\x18\a \x01(\tR\x0eoperatorRegion\x12%\n" +
+	"\x0esession_status\x18\b \x01(\tR\rsessionStatus\"\xbf\x01\n" +
 	"\n" +
 	"SessionHit\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x10\n" +
 	"\x03pin\x18\x02 \x01(\tR\x03pin\x12\x16\n" +
 	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
-	"\asnippet\x18\x04 \x01(\tR\asnippet\"{\n" +
+	"\asnippet\x18\x04 \x01(\tR\asnippet\x12!\n" +
+	"\foperator_ids\x18\x05 \x03(\tR\voperatorIds\x12+\n" +
+	"\x11participant_count\x18\x06 \x01(\x05R\x10participantCount\"{\n" +
 	"\vOperatorHit\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
 	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12\x16\n" +
 	"\x06region\x18\x03 \x01(\tR\x06region\x12\x18\n" +
 	"\asnippet\x18\x04 \x01(\tR\asnippet\"\x1f\n" +
 	"\rIndexResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok2\xe2\x05\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\xe9\x01\n" +
+	"\x0eExplainRequest\x12@\n" +
+	"\atickets\x18\x01 \x01(\v2$.search_service.SearchTicketsRequestH\x00R\atickets\x12C\n" +
+	"\bsessions\x18\x02 \x01(\v2%.search_service.SearchSessionsRequestH\x00R\bsessions\x12F\n" +
+	"\toperators\x18\x03 \x01(\v2&.search_service.SearchOperatorsRequestH\x00R\toperatorsB\b\n" +
+	"\x06search\"\xba\x01\n" +
+	"\x0fExplainResponse\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05index\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x18\n" +
+	"\aprofile\x18\x03 \x01(\tR\aprofile\x12\x17\n" +
+	"\atook_ms\x18\x04 \x01(\x03R\x06tookMs\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x03R\x05total\x122\n" +
+	"\x04hits\x18\x06 \x03(\v2\x1e.search_service.HitExplanationR\x04hits\"X\n" +
+	"\x0eHitExplanation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12 \n" +
+	"\vexplanation\x18\x03 \x01(\tR\vexplanation2\xca\x06\n" +
 	"\rSearchService\x12u\n" +
 	"\rSearchTickets\x12$.search_service.SearchTicketsRequest\x1a%.search_service.SearchTicketsResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/search/tickets\x12y\n" +
 	"\x0eSearchSessions\x12%.search_service.SearchSessionsRequest\x1a&.search_service.SearchSessionsResponse\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/search/sessions\x12}\n" +
 	"\x0fSearchOperators\x12&.search_service.SearchOperatorsRequest\x1a'.search_service.SearchOperatorsResponse\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/search/operators\x12q\n" +
 	"\vIndexTicket\x12\".search_service.IndexTicketRequest\x1a\x1d.search_service.IndexResponse\"\x1f\x82\xd3\xe4\x93\x02\x19:\x01*\"\x14/search/index/ticket\x12t\n" +
 	"\fIndexSession\x12#.search_service.IndexSessionRequest\x1a\x1d.search_service.IndexResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/search/index/session\x12w\n" +
-	"\rIndexOperator\x12$.search_service.IndexOperatorRequest\x1a\x1d.search_service.IndexResponse\"!\x82\xd3\xe4\x93\x02\x1b:\x01*\"\x16/search/index/operatorBSZQgithub.com/psds-microservice/search-service/pkg/gen/search_service;search_serviceb\x06proto3"
+	"\rIndexOperator\x12$.search_service.IndexOperatorRequest\x1a\x1d.search_service.IndexResponse\"!\x82\xd3\xe4\x93\x02\x1b:\x01*\"\x16/search/index/operator\x12f\n" +
+	"\aExplain\x12\x1e.search_service.ExplainRequest\x1a\x1f.search_service.ExplainResponse\"\x1a\x82\xd3\xe4\x93\x02\x14:\x01*\"\x0f/search/explainBSZQgithub.com/psds-microservice/search-service/pkg/gen/search_service;search_serviceb\x06proto3"
 
 var (
 	file_search_proto_rawDescOnce sync.Once
@@ -1013,43 +1687,70 @@ func file_search_proto_rawDescGZIP() []byte {
 	return file_search_proto_rawDescData
 }
 
-var file_search_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_search_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_search_proto_goTypes = []any{
 	(*SearchTicketsRequest)(nil),    // 0: search_service.SearchTicketsRequest
 	(*SearchSessionsRequest)(nil),   // 1: search_service.SearchSessionsRequest
 	(*SearchOperatorsRequest)(nil),  // 2: search_service.SearchOperatorsRequest
-	(*IndexTicketRequest)(nil),      // 3: search_service.IndexTicketRequest
-	(*IndexSessionRequest)(nil),     // 4: search_service.IndexSessionRequest
-	(*IndexOperatorRequest)(nil),    // 5: search_service.IndexOperatorRequest
-	(*SearchTicketsResponse)(nil),   // 6: search_service.SearchTicketsResponse
-	(*SearchSessionsResponse)(nil),  // 7: search_service.SearchSessionsResponse
-	(*SearchOperatorsResponse)(nil), // 8: search_service.SearchOperatorsResponse
-	(*TicketHit)(nil),               // 9: search_service.TicketHit
-	(*SessionHit)(nil),              // 10: search_service.SessionHit
-	(*OperatorHit)(nil),             // 11: search_service.OperatorHit
-	(*IndexResponse)(nil),           // 12: search_service.IndexResponse
+	(*TimeRange)(nil),               // 3: search_service.TimeRange
+	(*IndexTicketRequest)(nil),      // 4: search_service.IndexTicketRequest
+	(*IndexSessionRequest)(nil),     // 5: search_service.IndexSessionRequest
+	(*IndexOperatorRequest)(nil),    // 6: search_service.IndexOperatorRequest
+	(*SearchTicketsResponse)(nil),   // 7: search_service.SearchTicketsResponse
+	(*SearchSessionsResponse)(nil),  // 8: search_service.SearchSessionsResponse
+	(*SearchOperatorsResponse)(nil), // 9: search_service.SearchOperatorsResponse
+	(*TicketHit)(nil),               // 10: search_service.TicketHit
+	(*SessionHit)(nil),              // 11: search_service.SessionHit
+	(*OperatorHit)(nil),             // 12: search_service.OperatorHit
+	(*IndexResponse)(nil),           // 13: search_service.IndexResponse
+	(*ExplainRequest)(nil),          // 14: search_service.ExplainRequest
+	(*ExplainResponse)(nil),         // 15: search_service.ExplainResponse
+	(*HitExplanation)(nil),          // 16: search_service.HitExplanation
+	(*timestamppb.Timestamp)(nil),   // 17: google.protobuf.Timestamp
 }
 var file_search_proto_depIdxs = []int32{
-	9,  // 0: search_service.SearchTicketsResponse.tickets:type_name -> search_service.TicketHit
-	10, // 1: search_service.SearchSessionsResponse.sessions:type_name -> search_service.SessionHit
-	11, // 2: search_service.SearchOperatorsResponse.operators:type_name -> search_service.OperatorHit
-	0,  // 3: search_service.SearchService.SearchTickets:input_type -> search_service.SearchTicketsRequest
-	1,  // 4: search_service.SearchService.SearchSessions:input_type -> search_service.SearchSessionsRequest
-	2,  // 5: search_service.SearchService.SearchOperators:input_type -> search_service.SearchOperatorsRequest
-	3,  // 6: search_service.SearchService.IndexTicket:input_type -> search_service.IndexTicketRequest
-	4,  // 7: search_service.SearchService.IndexSession:input_type -> search_service.IndexSessionRequest
-	5,  // 8: search_service.SearchService.IndexOperator:input_type -> search_service.IndexOperatorRequest
-	6,  // 9: search_service.SearchService.SearchTickets:output_type -> search_service.SearchTicketsResponse
-	7,  // 10: search_service.SearchService.SearchSessions:output_type -> search_service.SearchSessionsResponse
-	8,  // 11: search_service.SearchService.SearchOperators:output_type -> search_service.SearchOperatorsResponse
-	12, // 12: search_service.SearchService.IndexTicket:output_type -> search_service.IndexResponse
-	12, // 13: search_service.SearchService.IndexSession:output_type -> search_service.IndexResponse
-	12, // 14: search_service.SearchService.IndexOperator:output_type -> search_service.IndexResponse
-	9,  // [9:15] is the sub-list for method output_type
-	3,  // [3:9] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	3,  // 0: search_service.SearchTicketsRequest.created:type_name -> search_service.TimeRange
+	3,  // 1: search_service.SearchTicketsRequest.updated:type_name -> search_service.TimeRange
+	3,  // 2: search_service.SearchSessionsRequest.created:type_name -> search_service.TimeRange
+	3,  // 3: search_service.SearchSessionsRequest.updated:type_name -> search_service.TimeRange
+	3,  // 4: search_service.SearchSessionsRequest.ended:type_name -> search_service.TimeRange
+	3,  // 5: search_service.SearchOperatorsRequest.created:type_name -> search_service.TimeRange
+	3,  // 6: search_service.SearchOperatorsRequest.updated:type_name -> search_service.TimeRange
+	17, // 7: search_service.TimeRange.from:type_name -> google.protobuf.Timestamp
+	17, // 8: search_service.TimeRange.to:type_name -> google.protobuf.Timestamp
+	17, // 9: search_service.IndexTicketRequest.created_at:type_name -> google.protobuf.Timestamp
+	17, // 10: search_service.IndexTicketRequest.updated_at:type_name -> google.protobuf.Timestamp
+	17, // 11: search_service.IndexSessionRequest.created_at:type_name -> google.protobuf.Timestamp
+	17, // 12: search_service.IndexSessionRequest.updated_at:type_name -> google.protobuf.Timestamp
+	17, // 13: search_service.IndexSessionRequest.ended_at:type_name -> google.protobuf.Timestamp
+	17, // 14: search_service.IndexOperatorRequest.created_at:type_name -> google.protobuf.Timestamp
+	17, // 15: search_service.IndexOperatorRequest.updated_at:type_name -> google.protobuf.Timestamp
+	10, // 16: search_service.SearchTicketsResponse.tickets:type_name -> search_service.TicketHit
+	11, // 17: search_service.SearchSessionsResponse.sessions:type_name -> search_service.SessionHit
+	12, // 18: search_service.SearchOperatorsResponse.operators:type_name -> search_service.OperatorHit
+	0,  // 19: search_service.ExplainRequest.tickets:type_name -> search_service.SearchTicketsRequest
+	1,  // 20: search_service.ExplainRequest.sessions:type_name -> search_service.SearchSessionsRequest
+	2,  // 21: search_service.ExplainRequest.operators:type_name -> search_service.SearchOperatorsRequest
+	16, // 22: search_service.ExplainResponse.hits:type_name -> search_service.HitExplanation
+	0,  // 23: search_service.SearchService.SearchTickets:input_type -> search_service.SearchTicketsRequest
+	1,  // 24: search_service.SearchService.SearchSessions:input_type -> search_service.SearchSessionsRequest
+	2,  // 25: search_service.SearchService.SearchOperators:input_type -> search_service.SearchOperatorsRequest
+	4,  // 26: search_service.SearchService.IndexTicket:input_type -> search_service.IndexTicketRequest
+	5,  // 27: search_service.SearchService.IndexSession:input_type -> search_service.IndexSessionRequest
+	6,  // 28: search_service.SearchService.IndexOperator:input_type -> search_service.IndexOperatorRequest
+	14, // 29: search_service.SearchService.Explain:input_type -> search_service.ExplainRequest
+	7,  // 30: search_service.SearchService.SearchTickets:output_type -> search_service.SearchTicketsResponse
+	8,  // 31: search_service.SearchService.SearchSessions:output_type -> search_service.SearchSessionsResponse
+	9,  // 32: search_service.SearchService.SearchOperators:output_type -> search_service.SearchOperatorsResponse
+	13, // 33: search_service.SearchService.IndexTicket:output_type -> search_service.IndexResponse
+	13, // 34: search_service.SearchService.IndexSession:output_type -> search_service.IndexResponse
+	13, // 35: search_service.SearchService.IndexOperator:output_type -> search_service.IndexResponse
+	15, // 36: search_service.SearchService.Explain:output_type -> search_service.ExplainResponse
+	30, // [30:37] is the sub-list for method output_type
+	23, // [23:30] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_search_proto_init() }
@@ -1057,13 +1758,18 @@ func file_search_proto_init() {
 	if File_search_proto != nil {
 		return
 	}
+	file_search_proto_msgTypes[14].OneofWrappers = []any{
+		(*ExplainRequest_Tickets)(nil),
+		(*ExplainRequest_Sessions)(nil),
+		(*ExplainRequest_Operators)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_search_proto_rawDesc), len(file_search_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   13,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
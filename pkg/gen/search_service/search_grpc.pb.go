@@ -25,6 +25,7 @@ const (
 	SearchService_IndexTicket_FullMethodName     = "/search_service.SearchService/IndexTicket"
 	SearchService_IndexSession_FullMethodName    = "/search_service.SearchService/IndexSession"
 	SearchService_IndexOperator_FullMethodName   = "/search_service.SearchService/IndexOperator"
+	SearchService_Explain_FullMethodName         = "/search_service.SearchService/Explain"
 )
 
 // SearchServiceClient is the client API for SearchService service.
@@ -37,6 +38,8 @@ type SearchServiceClient interface {
 	IndexTicket(ctx context.Context, in *IndexTicketRequest, opts ...grpc.CallOption) (*IndexResponse, error)
 	IndexSession(ctx context.Context, in *IndexSessionRequest, opts ...grpc.CallOption) (*IndexResponse, error)
 	IndexOperator(ctx context.Context, in *IndexOperatorRequest, opts ...grpc.CallOption) (*IndexResponse, error)
+	// Explain — отладка поиска (только роль администратора): сгенерированный запрос ES, profile и объяснение score по хитам
+	Explain(ctx context.Context, in *ExplainRequest, opts ...grpc.CallOption) (*ExplainResponse, error)
 }
 
 type searchServiceClient struct {
@@ -107,6 +110,16 @@ func (c *searchServiceClient) IndexOperator(ctx context.Context, in *IndexOperat
 	return out, nil
 }
 
+func (c *searchServiceClient) Explain(ctx context.Context, in *ExplainRequest, opts ...grpc.CallOption) (*ExplainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExplainResponse)
+	err := c.cc.Invoke(ctx, SearchService_Explain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SearchServiceServer is the server API for SearchService service.
 // All implementations must embed UnimplementedSearchServiceServer
 // for forward compatibility.
@@ -117,6 +130,8 @@ type SearchServiceServer interface {
 	IndexTicket(context.Context, *IndexTicketRequest) (*IndexResponse, error)
 	IndexSession(context.Context, *IndexSessionRequest) (*IndexResponse, error)
 	IndexOperator(context.Context, *IndexOperatorRequest) (*IndexResponse, error)
+	// Explain — отладка поиска (только роль администратора): сгенерированный запрос ES, profile и объяснение score по хитам
+	Explain(context.Context, *ExplainRequest) (*ExplainResponse, error)
 	mustEmbedUnimplementedSearchServiceServer()
 }
 
@@ -145,6 +160,9 @@ func (UnimplementedSearchServiceServer) IndexSession(context.Context, *IndexSess
 func (UnimplementedSearchServiceServer) IndexOperator(context.Context, *IndexOperatorRequest) (*IndexResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method IndexOperator not implemented")
 }
+func (UnimplementedSearchServiceServer) Explain(context.Context, *ExplainRequest) (*ExplainResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Explain not implemented")
+}
 func (UnimplementedSearchServiceServer) mustEmbedUnimplementedSearchServiceServer() {}
 func (UnimplementedSearchServiceServer) testEmbeddedByValue()                       {}
 
@@ -274,6 +292,24 @@ func _SearchService_IndexOperator_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SearchService_Explain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).Explain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_Explain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).Explain(ctx, req.(*ExplainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SearchService_ServiceDesc is the grpc.ServiceDesc for SearchService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,6 +341,10 @@ var SearchService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IndexOperator",
 			Handler:    _SearchService_IndexOperator_Handler,
 		},
+		{
+			MethodName: "Explain",
+			Handler:    _SearchService_Explain_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "search.proto",